@@ -21,6 +21,12 @@ var QuotaPerUnit = 500 * 1000.0 // $0.002 / 1K tokens
 var DisplayInCurrencyEnabled = true
 var DisplayTokenStatEnabled = true
 
+// SubUserEnabled lets users create sub-users and allocate them part of their own quota
+var SubUserEnabled = false
+
+// SubUserGroups lists, comma separated, the groups users may put their sub-users in besides their own group
+var SubUserGroups = ""
+
 // Any options with "Secret", "Token" in its key won't be return by GetOptions
 
 var SessionSecret = uuid.New().String()
@@ -80,6 +86,36 @@ var GoogleOAuthEnabled = true
 var GoogleClientId = ""
 var GoogleClientSecret = ""
 var GoogleRedirectUri = ""
+
+// OpenID Connect login, claims are looked up by name and nested claims by a dotted path such as realm_access.roles
+var OidcEnabled = false
+var OidcDiscoveryUrl = "" // issuer URL, or the URL of its .well-known/openid-configuration document
+var OidcClientId = ""
+var OidcClientSecret = ""
+var OidcRedirectUri = ""
+var OidcScopes = "openid profile email"
+var OidcUsernameClaim = "preferred_username"
+var OidcEmailClaim = "email"
+var OidcGroupsClaim = "groups"
+var OidcRoleMapping = ""  // JSON object mapping the values of the groups claim to role names
+var OidcGroupMapping = "" // JSON object mapping the values of the groups claim to user groups
+
+// LDAP login binds as the user, %s in the user DN and the filter is replaced by the username.
+// Groups are matched by their DN or by the value of their first RDN, such as the CN.
+var LdapEnabled = false
+var LdapUrl = "" // ldap://host:389 or ldaps://host:636
+var LdapStartTlsEnabled = false
+var LdapUserDn = "" // uid=%s,ou=people,dc=example,dc=com, or %s@example.com for Active Directory
+var LdapBaseDn = "" // base of the search for the entry of the user
+var LdapUserFilter = "(uid=%s)"
+var LdapEmailAttribute = "mail"
+var LdapDisplayNameAttribute = "displayName"
+var LdapGroupAttribute = "memberOf"
+
+// LdapGroupShortNameEnabled also matches the groups by the value of their first RDN, e.g. admins for cn=admins,ou=groups,...
+var LdapGroupShortNameEnabled = false
+var LdapRoleMapping = ""  // JSON object mapping LDAP groups to role names
+var LdapGroupMapping = "" // JSON object mapping LDAP groups to user groups
 var StripeKey = ""
 
 var WeChatServerAddress = ""
@@ -117,6 +153,12 @@ var BatchUpdateInterval = env.Int("BATCH_UPDATE_INTERVAL", 5)
 
 var RelayTimeout = env.Int("RELAY_TIMEOUT", 0) // unit is second
 
+// ChannelModelSyncApplyEnabled makes the periodic model sync write the discovered models back to the channels
+var ChannelModelSyncApplyEnabled = env.Bool("CHANNEL_MODEL_SYNC_APPLY", false)
+
+// ChannelTestHistoryLimit is how many full test results are kept per channel, older ones are deleted
+var ChannelTestHistoryLimit = env.Int("CHANNEL_TEST_HISTORY_LIMIT", 1000)
+
 var GeminiSafetySetting = env.String("GEMINI_SAFETY_SETTING", "BLOCK_NONE")
 
 var Theme = env.String("THEME", "default")
@@ -154,3 +196,19 @@ var MetricSuccessChanSize = env.Int("METRIC_SUCCESS_CHAN_SIZE", 1024)
 var MetricFailChanSize = env.Int("METRIC_FAIL_CHAN_SIZE", 128)
 
 var InitialRootToken = os.Getenv("INITIAL_ROOT_TOKEN")
+
+// ConfigFile is a declarative config file reconciled into the database on startup and on SIGHUP
+var ConfigFile = env.String("CONFIG_FILE", "")
+
+// TrustedProxies lists the proxies, as IPs or CIDRs, whose RemoteIPHeaders are trusted to carry the client IP
+var TrustedProxies = env.String("TRUSTED_PROXIES", "")
+var RemoteIPHeaders = env.String("REMOTE_IP_HEADERS", "X-Forwarded-For,X-Real-IP")
+
+// TokenRotationGracePeriod is how long, in seconds, the previous key of a rotated token stays valid by default
+var TokenRotationGracePeriod = env.Int("TOKEN_ROTATION_GRACE_PERIOD", 86400)
+
+// TokenKeySecret keys the hash of the token keys stored in the database, changing it invalidates every token
+var TokenKeySecret = env.String("TOKEN_KEY_SECRET", "")
+
+// ChannelMasterKey encrypts the keys of the channels at rest, it is read from CHANNEL_MASTER_KEY or CHANNEL_MASTER_KEY_FILE
+var ChannelMasterKey string
@@ -0,0 +1,46 @@
+package cron_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/songquanpeng/one-api/common/cron"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMatch(t *testing.T) {
+	// 2024-03-04 is a monday
+	monday := func(hour, minute int) time.Time {
+		return time.Date(2024, 3, 4, hour, minute, 0, 0, time.UTC)
+	}
+	cases := []struct {
+		expr  string
+		time  time.Time
+		match bool
+	}{
+		{"* * * * *", monday(3, 7), true},
+		{"* 9-17 * * 1-5", monday(9, 0), true},
+		{"* 9-17 * * 1-5", monday(17, 59), true},
+		{"* 9-17 * * 1-5", monday(18, 0), false},
+		{"* 9-17 * * 6,0", monday(12, 0), false},
+		{"*/15 * * * *", monday(12, 30), true},
+		{"*/15 * * * *", monday(12, 31), false},
+		{"5/10 * * * *", monday(12, 25), true},
+		{"* * * * 7", time.Date(2024, 3, 3, 0, 0, 0, 0, time.UTC), true},
+		{"* * 1 * 1", monday(0, 0), true},
+		{"* * 1 * 2", monday(0, 0), false},
+		{"* * * 4 *", monday(0, 0), false},
+	}
+	for _, c := range cases {
+		schedule, err := cron.Parse(c.expr)
+		assert.NoError(t, err, c.expr)
+		assert.Equal(t, c.match, schedule.Match(c.time), "%s at %s", c.expr, c.time)
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	for _, expr := range []string{"", "* * * *", "60 * * * *", "* 5-2 * * *", "*/0 * * * *", "a * * * *", "* * 0 * *"} {
+		_, err := cron.Parse(expr)
+		assert.Error(t, err, expr)
+	}
+}
@@ -1,6 +1,17 @@
 package common
 
-import "golang.org/x/crypto/bcrypt"
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"strings"
+
+	"golang.org/x/crypto/bcrypt"
+	"golang.org/x/crypto/scrypt"
+)
 
 func Password2Hash(password string) (string, error) {
 	passwordBytes := []byte(password)
@@ -12,3 +23,161 @@ func ValidatePasswordAndHash(password string, hash string) bool {
 	err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
 	return err == nil
 }
+
+const passphraseSaltSize = 16
+
+func deriveKeyFromPassphrase(passphrase string, salt []byte) ([]byte, error) {
+	return scrypt.Key([]byte(passphrase), salt, 1<<15, 8, 1, 32)
+}
+
+// EncryptWithPassphrase seals plaintext with AES-256-GCM, the key is derived from passphrase with scrypt.
+// The result is base64(salt | nonce | ciphertext).
+func EncryptWithPassphrase(plaintext string, passphrase string) (string, error) {
+	if passphrase == "" {
+		return "", errors.New("passphrase is empty")
+	}
+	salt := make([]byte, passphraseSaltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	key, err := deriveKeyFromPassphrase(passphrase, salt)
+	if err != nil {
+		return "", err
+	}
+	sealed, err := sealAESGCM(key, []byte(plaintext))
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(append(salt, sealed...)), nil
+}
+
+func DecryptWithPassphrase(ciphertext string, passphrase string) (string, error) {
+	if passphrase == "" {
+		return "", errors.New("passphrase is empty")
+	}
+	data, err := base64.StdEncoding.DecodeString(ciphertext)
+	if err != nil {
+		return "", err
+	}
+	if len(data) < passphraseSaltSize {
+		return "", errors.New("ciphertext too short")
+	}
+	key, err := deriveKeyFromPassphrase(passphrase, data[:passphraseSaltSize])
+	if err != nil {
+		return "", err
+	}
+	plaintext, err := openAESGCM(key, data[passphraseSaltSize:])
+	if err != nil {
+		return "", errors.New("failed to decrypt, wrong passphrase or corrupted data")
+	}
+	return string(plaintext), nil
+}
+
+const envelopePrefix = "enc:v1:"
+
+// DeriveMasterKey turns the configured master key into an AES-256 key
+func DeriveMasterKey(secret string) []byte {
+	key := sha256.Sum256([]byte(secret))
+	return key[:]
+}
+
+func IsEnvelopeEncrypted(data string) bool {
+	return strings.HasPrefix(data, envelopePrefix)
+}
+
+// EnvelopeEncrypt seals plaintext with a random data key, and the data key with the master key,
+// so that rotating the master key only needs to seal the data keys again.
+// The result is "enc:v1:" + base64(sealed data key) + ":" + base64(sealed plaintext).
+func EnvelopeEncrypt(plaintext string, masterKey []byte) (string, error) {
+	dataKey := make([]byte, 32)
+	if _, err := rand.Read(dataKey); err != nil {
+		return "", err
+	}
+	sealedDataKey, err := sealAESGCM(masterKey, dataKey)
+	if err != nil {
+		return "", err
+	}
+	sealed, err := sealAESGCM(dataKey, []byte(plaintext))
+	if err != nil {
+		return "", err
+	}
+	return envelopePrefix + base64.StdEncoding.EncodeToString(sealedDataKey) + ":" + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+func splitEnvelope(ciphertext string) (sealedDataKey []byte, sealed []byte, err error) {
+	parts := strings.Split(strings.TrimPrefix(ciphertext, envelopePrefix), ":")
+	if !IsEnvelopeEncrypted(ciphertext) || len(parts) != 2 {
+		return nil, nil, errors.New("invalid envelope")
+	}
+	sealedDataKey, err = base64.StdEncoding.DecodeString(parts[0])
+	if err != nil {
+		return nil, nil, err
+	}
+	sealed, err = base64.StdEncoding.DecodeString(parts[1])
+	return sealedDataKey, sealed, err
+}
+
+func EnvelopeDecrypt(ciphertext string, masterKey []byte) (string, error) {
+	sealedDataKey, sealed, err := splitEnvelope(ciphertext)
+	if err != nil {
+		return "", err
+	}
+	dataKey, err := openAESGCM(masterKey, sealedDataKey)
+	if err != nil {
+		return "", errors.New("failed to decrypt, wrong master key or corrupted data")
+	}
+	plaintext, err := openAESGCM(dataKey, sealed)
+	if err != nil {
+		return "", errors.New("failed to decrypt, corrupted data")
+	}
+	return string(plaintext), nil
+}
+
+// EnvelopeRewrap seals the data key of the envelope with a new master key, the payload is left as it is
+func EnvelopeRewrap(ciphertext string, oldMasterKey []byte, newMasterKey []byte) (string, error) {
+	sealedDataKey, sealed, err := splitEnvelope(ciphertext)
+	if err != nil {
+		return "", err
+	}
+	dataKey, err := openAESGCM(oldMasterKey, sealedDataKey)
+	if err != nil {
+		return "", errors.New("failed to decrypt, wrong master key or corrupted data")
+	}
+	sealedDataKey, err = sealAESGCM(newMasterKey, dataKey)
+	if err != nil {
+		return "", err
+	}
+	return envelopePrefix + base64.StdEncoding.EncodeToString(sealedDataKey) + ":" + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+func sealAESGCM(key []byte, plaintext []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return gcm.Seal(nonce, nonce, plaintext, nil), nil
+}
+
+func openAESGCM(key []byte, data []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < gcm.NonceSize() {
+		return nil, errors.New("ciphertext too short")
+	}
+	nonce, ciphertext := data[:gcm.NonceSize()], data[gcm.NonceSize():]
+	return gcm.Open(nil, nonce, ciphertext, nil)
+}
@@ -0,0 +1,87 @@
+package common_test
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	"github.com/songquanpeng/one-api/common"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPassphraseRoundTrip(t *testing.T) {
+	ciphertext, err := common.EncryptWithPassphrase("sk-secret", "correct horse")
+	assert.NoError(t, err)
+	assert.NotContains(t, ciphertext, "sk-secret")
+	plaintext, err := common.DecryptWithPassphrase(ciphertext, "correct horse")
+	assert.NoError(t, err)
+	assert.Equal(t, "sk-secret", plaintext)
+	// every encryption uses a new salt and nonce
+	other, err := common.EncryptWithPassphrase("sk-secret", "correct horse")
+	assert.NoError(t, err)
+	assert.NotEqual(t, ciphertext, other)
+}
+
+func TestPassphraseWrongPassphrase(t *testing.T) {
+	ciphertext, err := common.EncryptWithPassphrase("sk-secret", "correct horse")
+	assert.NoError(t, err)
+	_, err = common.DecryptWithPassphrase(ciphertext, "battery staple")
+	assert.Error(t, err)
+	_, err = common.DecryptWithPassphrase(ciphertext, "")
+	assert.Error(t, err)
+	_, err = common.EncryptWithPassphrase("sk-secret", "")
+	assert.Error(t, err)
+}
+
+func TestEnvelopeRoundTrip(t *testing.T) {
+	masterKey := common.DeriveMasterKey("master")
+	ciphertext, err := common.EnvelopeEncrypt("sk-secret", masterKey)
+	assert.NoError(t, err)
+	assert.True(t, common.IsEnvelopeEncrypted(ciphertext))
+	assert.NotContains(t, ciphertext, "sk-secret")
+	plaintext, err := common.EnvelopeDecrypt(ciphertext, masterKey)
+	assert.NoError(t, err)
+	assert.Equal(t, "sk-secret", plaintext)
+}
+
+func TestEnvelopeWrongKey(t *testing.T) {
+	ciphertext, err := common.EnvelopeEncrypt("sk-secret", common.DeriveMasterKey("master"))
+	assert.NoError(t, err)
+	_, err = common.EnvelopeDecrypt(ciphertext, common.DeriveMasterKey("other"))
+	assert.Error(t, err)
+	_, err = common.EnvelopeRewrap(ciphertext, common.DeriveMasterKey("other"), common.DeriveMasterKey("new"))
+	assert.Error(t, err)
+}
+
+func TestEnvelopeTampered(t *testing.T) {
+	masterKey := common.DeriveMasterKey("master")
+	ciphertext, err := common.EnvelopeEncrypt("sk-secret", masterKey)
+	assert.NoError(t, err)
+	parts := strings.Split(ciphertext, ":")
+	payload, err := base64.StdEncoding.DecodeString(parts[len(parts)-1])
+	assert.NoError(t, err)
+	payload[len(payload)-1] ^= 1
+	parts[len(parts)-1] = base64.StdEncoding.EncodeToString(payload)
+	_, err = common.EnvelopeDecrypt(strings.Join(parts, ":"), masterKey)
+	assert.Error(t, err)
+	_, err = common.EnvelopeDecrypt(ciphertext+":extra", masterKey)
+	assert.Error(t, err)
+	_, err = common.EnvelopeDecrypt("sk-secret", masterKey)
+	assert.Error(t, err)
+}
+
+func TestEnvelopeRewrap(t *testing.T) {
+	oldKey := common.DeriveMasterKey("old")
+	newKey := common.DeriveMasterKey("new")
+	ciphertext, err := common.EnvelopeEncrypt("sk-secret", oldKey)
+	assert.NoError(t, err)
+	rewrapped, err := common.EnvelopeRewrap(ciphertext, oldKey, newKey)
+	assert.NoError(t, err)
+	plaintext, err := common.EnvelopeDecrypt(rewrapped, newKey)
+	assert.NoError(t, err)
+	assert.Equal(t, "sk-secret", plaintext)
+	_, err = common.EnvelopeDecrypt(rewrapped, oldKey)
+	assert.Error(t, err)
+	// only the data key is sealed again, the payload is unchanged
+	assert.Equal(t, ciphertext[strings.LastIndex(ciphertext, ":"):], rewrapped[strings.LastIndex(rewrapped, ":"):])
+}
@@ -10,4 +10,10 @@ const (
 	ConfigAK         = ConfigPrefix + "ak"
 	ConfigRegion     = ConfigPrefix + "region"
 	ConfigUserID     = ConfigPrefix + "user_id"
+	ConfigProxy      = ConfigPrefix + "proxy"
+
+	ConfigTLSCACert     = ConfigPrefix + "tls_ca_cert"
+	ConfigTLSClientCert = ConfigPrefix + "tls_client_cert"
+	ConfigTLSClientKey  = ConfigPrefix + "tls_client_key"
+	ConfigTLSServerName = ConfigPrefix + "tls_server_name"
 )
@@ -3,6 +3,7 @@ package env
 import (
 	"os"
 	"strconv"
+	"strings"
 )
 
 func Bool(env string, defaultValue bool) bool {
@@ -40,3 +41,19 @@ func String(env string, defaultValue string) string {
 	}
 	return os.Getenv(env)
 }
+
+// Secret reads a secret from the variable, or from the file named by the variable with a _FILE suffix
+func Secret(env string) (string, error) {
+	if os.Getenv(env) != "" {
+		return os.Getenv(env), nil
+	}
+	path := os.Getenv(env + "_FILE")
+	if path == "" {
+		return "", nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(data)), nil
+}
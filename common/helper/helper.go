@@ -197,6 +197,14 @@ func Max(a int, b int) int {
 	}
 }
 
+// MaskSecret keeps the first and last four characters of a secret and hides the rest
+func MaskSecret(secret string) string {
+	if len(secret) <= 8 {
+		return strings.Repeat("*", len(secret))
+	}
+	return secret[:4] + strings.Repeat("*", len(secret)-8) + secret[len(secret)-4:]
+}
+
 func AssignOrDefault(value string, defaultValue string) string {
 	if len(value) != 0 {
 		return value
@@ -215,3 +223,24 @@ func String2Int(str string) int {
 	}
 	return num
 }
+
+// MatchWildcard reports whether s matches the pattern, in which * matches any sequence of characters
+func MatchWildcard(pattern string, s string) bool {
+	parts := strings.Split(pattern, "*")
+	if len(parts) == 1 {
+		return pattern == s
+	}
+	if !strings.HasPrefix(s, parts[0]) {
+		return false
+	}
+	s = s[len(parts[0]):]
+	last := parts[len(parts)-1]
+	for _, part := range parts[1 : len(parts)-1] {
+		i := strings.Index(s, part)
+		if i < 0 {
+			return false
+		}
+		s = s[i+len(part):]
+	}
+	return len(s) >= len(last) && strings.HasSuffix(s, last)
+}
@@ -0,0 +1,31 @@
+package helper_test
+
+import (
+	"testing"
+
+	"github.com/songquanpeng/one-api/common/helper"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMatchWildcard(t *testing.T) {
+	cases := []struct {
+		pattern string
+		s       string
+		match   bool
+	}{
+		{"gpt-3.5-turbo", "gpt-3.5-turbo", true},
+		{"gpt-3.5-turbo", "gpt-3.5-turbo-16k", false},
+		{"gpt-3.5*", "gpt-3.5-turbo-16k", true},
+		{"gpt-3.5*", "gpt-4", false},
+		{"*", "anything", true},
+		{"*-mini", "gpt-4o-mini", true},
+		{"*-mini", "gpt-4o", false},
+		{"claude-*-haiku*", "claude-3-haiku-20240307", true},
+		{"claude-*-haiku*", "claude-3-sonnet", false},
+		{"meta-llama/*", "meta-llama/Llama-3-8b", true},
+		{"a*a", "a", false},
+	}
+	for _, c := range cases {
+		assert.Equal(t, c.match, helper.MatchWildcard(c.pattern, c.s), "%s %s", c.pattern, c.s)
+	}
+}
@@ -4,10 +4,12 @@ import (
 	"flag"
 	"fmt"
 	"github.com/songquanpeng/one-api/common/config"
+	"github.com/songquanpeng/one-api/common/env"
 	"github.com/songquanpeng/one-api/common/logger"
 	"log"
 	"os"
 	"path/filepath"
+	"strings"
 )
 
 var (
@@ -15,6 +17,12 @@ var (
 	PrintVersion = flag.Bool("version", false, "print version and exit")
 	PrintHelp    = flag.Bool("help", false, "print help and exit")
 	LogDir       = flag.String("log-dir", "./logs", "specify the log directory")
+
+	ExportChannelsPath = flag.String("export-channels", "", "export channels to the given json/yaml file and exit")
+	ImportChannelsPath = flag.String("import-channels", "", "import channels from the given json/yaml file and exit")
+	ChannelKeyMode     = flag.String("channel-key-mode", "mask", "how channel keys are exported: plain, mask or encrypt")
+	AllowUnknownModels = flag.Bool("allow-unknown-models", false, "do not validate imported channel models against the adaptor model list")
+	RotateMasterKey    = flag.Bool("rotate-master-key", false, "encrypt the channel keys with the master key read from CHANNEL_NEW_MASTER_KEY and exit")
 )
 
 func printHelp() {
@@ -22,9 +30,23 @@ func printHelp() {
 	fmt.Println("Copyright (C) 2023 JustSong. All rights reserved.")
 	fmt.Println("GitHub: https://github.com/songquanpeng/one-api")
 	fmt.Println("Usage: one-api [--port <port>] [--log-dir <log directory>] [--version] [--help]")
+	fmt.Println("       one-api --export-channels <file> [--channel-key-mode plain|mask|encrypt]")
+	fmt.Println("       one-api --import-channels <file> [--allow-unknown-models]")
+	fmt.Println("       one-api --rotate-master-key")
+	fmt.Println("The passphrase for encrypted channel keys is read from CHANNEL_TRANSFER_PASSPHRASE.")
+	fmt.Println("The master key is read from CHANNEL_MASTER_KEY and the new one from CHANNEL_NEW_MASTER_KEY, or from the files named by their _FILE variants.")
+}
+
+// isTestBinary reports whether the process is built by go test
+func isTestBinary() bool {
+	return strings.HasSuffix(strings.TrimSuffix(filepath.Base(os.Args[0]), ".exe"), ".test")
 }
 
 func init() {
+	if isTestBinary() {
+		// the -test.* flags are only registered once the tests start, and tests set the config they need
+		return
+	}
 	flag.Parse()
 
 	if *PrintVersion {
@@ -44,6 +66,15 @@ func init() {
 			config.SessionSecret = os.Getenv("SESSION_SECRET")
 		}
 	}
+	if config.TokenKeySecret == "" {
+		// not fatal, setting it later would invalidate the tokens of existing deployments
+		logger.SysError("TOKEN_KEY_SECRET is not set, the token keys are hashed without a secret, please set it to a random string.")
+	}
+	masterKey, err := env.Secret("CHANNEL_MASTER_KEY")
+	if err != nil {
+		log.Fatal("failed to read the channel master key: " + err.Error())
+	}
+	config.ChannelMasterKey = masterKey
 	if os.Getenv("SQLITE_PATH") != "" {
 		SQLitePath = os.Getenv("SQLITE_PATH")
 	}
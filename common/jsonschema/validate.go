@@ -0,0 +1,158 @@
+// Package jsonschema implements the subset of JSON Schema used by channel test assertions:
+// type, enum, const, properties, required, additionalProperties, items, minItems, maxItems,
+// minLength, maxLength, pattern, minimum and maximum.
+package jsonschema
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+)
+
+// Validate checks that the JSON document satisfies the schema
+func Validate(schema []byte, document []byte) error {
+	var schemaValue map[string]any
+	if err := json.Unmarshal(schema, &schemaValue); err != nil {
+		return fmt.Errorf("invalid schema: %w", err)
+	}
+	var value any
+	if err := json.Unmarshal(document, &value); err != nil {
+		return fmt.Errorf("invalid json: %w", err)
+	}
+	return validate(schemaValue, value, "$")
+}
+
+func typeOf(value any) string {
+	switch v := value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case float64:
+		if v == float64(int64(v)) {
+			return "integer"
+		}
+		return "number"
+	case string:
+		return "string"
+	case []any:
+		return "array"
+	case map[string]any:
+		return "object"
+	}
+	return "unknown"
+}
+
+func matchesType(expected string, value any) bool {
+	actual := typeOf(value)
+	return actual == expected || (expected == "number" && actual == "integer")
+}
+
+func validate(schema map[string]any, value any, path string) error {
+	if expected, ok := schema["type"]; ok {
+		matched := false
+		switch t := expected.(type) {
+		case string:
+			matched = matchesType(t, value)
+		case []any:
+			for _, item := range t {
+				if name, ok := item.(string); ok && matchesType(name, value) {
+					matched = true
+					break
+				}
+			}
+		}
+		if !matched {
+			return fmt.Errorf("%s: expected type %v, got %s", path, expected, typeOf(value))
+		}
+	}
+	if constant, ok := schema["const"]; ok && !equal(constant, value) {
+		return fmt.Errorf("%s: expected %v", path, constant)
+	}
+	if enum, ok := schema["enum"].([]any); ok {
+		found := false
+		for _, item := range enum {
+			if equal(item, value) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("%s: value is not one of %v", path, enum)
+		}
+	}
+	switch v := value.(type) {
+	case string:
+		if minLength, ok := schema["minLength"].(float64); ok && float64(len([]rune(v))) < minLength {
+			return fmt.Errorf("%s: shorter than %v", path, minLength)
+		}
+		if maxLength, ok := schema["maxLength"].(float64); ok && float64(len([]rune(v))) > maxLength {
+			return fmt.Errorf("%s: longer than %v", path, maxLength)
+		}
+		if pattern, ok := schema["pattern"].(string); ok {
+			re, err := regexp.Compile(pattern)
+			if err != nil {
+				return fmt.Errorf("%s: invalid pattern: %w", path, err)
+			}
+			if !re.MatchString(v) {
+				return fmt.Errorf("%s: does not match pattern %s", path, pattern)
+			}
+		}
+	case float64:
+		if minimum, ok := schema["minimum"].(float64); ok && v < minimum {
+			return fmt.Errorf("%s: less than %v", path, minimum)
+		}
+		if maximum, ok := schema["maximum"].(float64); ok && v > maximum {
+			return fmt.Errorf("%s: greater than %v", path, maximum)
+		}
+	case []any:
+		if minItems, ok := schema["minItems"].(float64); ok && float64(len(v)) < minItems {
+			return fmt.Errorf("%s: fewer than %v items", path, minItems)
+		}
+		if maxItems, ok := schema["maxItems"].(float64); ok && float64(len(v)) > maxItems {
+			return fmt.Errorf("%s: more than %v items", path, maxItems)
+		}
+		if items, ok := schema["items"].(map[string]any); ok {
+			for i, item := range v {
+				if err := validate(items, item, fmt.Sprintf("%s[%d]", path, i)); err != nil {
+					return err
+				}
+			}
+		}
+	case map[string]any:
+		if required, ok := schema["required"].([]any); ok {
+			for _, name := range required {
+				key, _ := name.(string)
+				if _, ok := v[key]; !ok {
+					return fmt.Errorf("%s: missing required property %s", path, key)
+				}
+			}
+		}
+		properties, _ := schema["properties"].(map[string]any)
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			propertySchema, ok := properties[key].(map[string]any)
+			if !ok {
+				if additional, ok := schema["additionalProperties"].(bool); ok && !additional {
+					return fmt.Errorf("%s: unexpected property %s", path, key)
+				}
+				continue
+			}
+			if err := validate(propertySchema, v[key], path+"."+key); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func equal(a any, b any) bool {
+	aJSON, _ := json.Marshal(a)
+	bJSON, _ := json.Marshal(b)
+	return string(aJSON) == string(bJSON)
+}
@@ -0,0 +1,28 @@
+package jsonschema_test
+
+import (
+	"testing"
+
+	"github.com/songquanpeng/one-api/common/jsonschema"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidate(t *testing.T) {
+	schema := []byte(`{
+		"type": "object",
+		"required": ["name", "tags"],
+		"properties": {
+			"name": {"type": "string", "minLength": 1},
+			"age": {"type": "integer", "minimum": 0},
+			"tags": {"type": "array", "items": {"type": "string", "enum": ["a", "b"]}}
+		},
+		"additionalProperties": false
+	}`)
+
+	assert.NoError(t, jsonschema.Validate(schema, []byte(`{"name": "x", "age": 3, "tags": ["a"]}`)))
+	assert.Error(t, jsonschema.Validate(schema, []byte(`{"name": "x"}`)))
+	assert.Error(t, jsonschema.Validate(schema, []byte(`{"name": "x", "age": 1.5, "tags": []}`)))
+	assert.Error(t, jsonschema.Validate(schema, []byte(`{"name": "x", "tags": ["c"]}`)))
+	assert.Error(t, jsonschema.Validate(schema, []byte(`{"name": "x", "tags": [], "extra": 1}`)))
+	assert.Error(t, jsonschema.Validate(schema, []byte(`not json`)))
+}
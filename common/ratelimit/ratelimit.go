@@ -0,0 +1,176 @@
+// Package ratelimit implements the RPM, TPM and concurrency limits of the relay on top of shared counters
+package ratelimit
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/songquanpeng/one-api/common/config"
+)
+
+// Counter keeps the counters of the limits, in Redis when enabled so that they are shared by all nodes
+type Counter interface {
+	IncrBy(key string, value int64, expiration time.Duration) (int64, error)
+	Get(key string) (int64, error)
+}
+
+type memoryCounterItem struct {
+	value    int64
+	expireAt time.Time
+}
+
+// MemoryCounter is the Counter of a single node, its zero value is ready to use
+type MemoryCounter struct {
+	store map[string]*memoryCounterItem
+	mutex sync.Mutex
+	once  sync.Once
+}
+
+func (m *MemoryCounter) init() {
+	m.once.Do(func() {
+		m.store = make(map[string]*memoryCounterItem)
+		go func() {
+			for {
+				time.Sleep(time.Minute)
+				m.mutex.Lock()
+				now := time.Now()
+				for key, item := range m.store {
+					if now.After(item.expireAt) {
+						delete(m.store, key)
+					}
+				}
+				m.mutex.Unlock()
+			}
+		}()
+	})
+}
+
+func (m *MemoryCounter) IncrBy(key string, value int64, expiration time.Duration) (int64, error) {
+	m.init()
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	item, ok := m.store[key]
+	if !ok || time.Now().After(item.expireAt) {
+		item = &memoryCounterItem{}
+		m.store[key] = item
+	}
+	item.value += value
+	item.expireAt = time.Now().Add(expiration)
+	return item.value, nil
+}
+
+func (m *MemoryCounter) Get(key string) (int64, error) {
+	m.init()
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	item, ok := m.store[key]
+	if !ok || time.Now().After(item.expireAt) {
+		return 0, nil
+	}
+	return item.value, nil
+}
+
+// Scope is a token, a user or a group with its limits, 0 means unlimited
+type Scope struct {
+	Name        string // token, user or group, used in keys and error messages
+	Id          string
+	RPM         int
+	TPM         int
+	Concurrency int
+}
+
+type Error struct {
+	Message    string
+	LimitType  string // requests or tokens
+	RetryAfter int64  // seconds
+}
+
+// Release undoes the counters taken by a request once it is finished, and counts the tokens it used
+type Release func(totalTokens int64)
+
+// Acquire checks every scope, the windows of RPM and TPM are aligned on minutes.
+// When a scope rejects the request, the counters already taken for the previous scopes are given back.
+func Acquire(counter Counter, scopes []Scope) (Release, *Error, error) {
+	now := time.Now()
+	window := now.Unix() / 60
+	retryAfter := 60 - now.Unix()%60
+	// releases run once the request is finished, rollbacks only when it is rejected
+	releases := make([]func(totalTokens int64), 0)
+	rollbacks := make([]func(), 0)
+	release := func(totalTokens int64) {
+		for _, r := range releases {
+			r(totalTokens)
+		}
+	}
+	reject := func() {
+		for _, r := range rollbacks {
+			r()
+		}
+		release(0)
+	}
+	for _, scope := range scopes {
+		prefix := fmt.Sprintf("relayRateLimit:%s:%s", scope.Name, scope.Id)
+		if scope.Concurrency > 0 {
+			key := prefix + ":concurrency"
+			value, err := counter.IncrBy(key, 1, config.RateLimitKeyExpirationDuration)
+			if err != nil {
+				reject()
+				return nil, nil, err
+			}
+			releases = append(releases, func(int64) {
+				_, _ = counter.IncrBy(key, -1, config.RateLimitKeyExpirationDuration)
+			})
+			if value > int64(scope.Concurrency) {
+				reject()
+				return nil, &Error{
+					Message:    fmt.Sprintf("Concurrency limit of the %s reached: %d requests in flight", scope.Name, scope.Concurrency),
+					LimitType:  "requests",
+					RetryAfter: 1,
+				}, nil
+			}
+		}
+		if scope.RPM > 0 {
+			key := fmt.Sprintf("%s:rpm:%d", prefix, window)
+			value, err := counter.IncrBy(key, 1, 2*time.Minute)
+			if err != nil {
+				reject()
+				return nil, nil, err
+			}
+			rollbacks = append(rollbacks, func() {
+				_, _ = counter.IncrBy(key, -1, 2*time.Minute)
+			})
+			if value > int64(scope.RPM) {
+				reject()
+				return nil, &Error{
+					Message:    fmt.Sprintf("Rate limit of the %s reached: %d requests per minute", scope.Name, scope.RPM),
+					LimitType:  "requests",
+					RetryAfter: retryAfter,
+				}, nil
+			}
+		}
+		if scope.TPM > 0 {
+			key := fmt.Sprintf("%s:tpm:%d", prefix, window)
+			value, err := counter.Get(key)
+			if err != nil {
+				reject()
+				return nil, nil, err
+			}
+			if value >= int64(scope.TPM) {
+				reject()
+				return nil, &Error{
+					Message:    fmt.Sprintf("Rate limit of the %s reached: %d tokens per minute", scope.Name, scope.TPM),
+					LimitType:  "tokens",
+					RetryAfter: retryAfter,
+				}, nil
+			}
+			// tokens are known once the request is done, they are counted in the window the request started in
+			releases = append(releases, func(totalTokens int64) {
+				if totalTokens > 0 {
+					_, _ = counter.IncrBy(key, totalTokens, 2*time.Minute)
+				}
+			})
+		}
+	}
+	return release, nil, nil
+}
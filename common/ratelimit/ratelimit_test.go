@@ -0,0 +1,94 @@
+package ratelimit_test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/songquanpeng/one-api/common/ratelimit"
+	"github.com/stretchr/testify/assert"
+)
+
+// waitForWindow avoids running a test across two minute windows, which would reset the RPM and TPM counters
+func waitForWindow() {
+	if second := time.Now().Unix() % 60; second >= 58 {
+		time.Sleep(time.Duration(61-second) * time.Second)
+	}
+}
+
+func rpmKey(scope ratelimit.Scope) string {
+	return fmt.Sprintf("relayRateLimit:%s:%s:rpm:%d", scope.Name, scope.Id, time.Now().Unix()/60)
+}
+
+func TestAcquireRPM(t *testing.T) {
+	waitForWindow()
+	counter := &ratelimit.MemoryCounter{}
+	scopes := []ratelimit.Scope{{Name: "user", Id: "1", RPM: 2}}
+	for i := 0; i < 2; i++ {
+		release, limitErr, err := ratelimit.Acquire(counter, scopes)
+		assert.NoError(t, err)
+		assert.Nil(t, limitErr)
+		release(0)
+	}
+	_, limitErr, err := ratelimit.Acquire(counter, scopes)
+	assert.NoError(t, err)
+	if assert.NotNil(t, limitErr) {
+		assert.Equal(t, "requests", limitErr.LimitType)
+		assert.True(t, limitErr.RetryAfter > 0 && limitErr.RetryAfter <= 60)
+	}
+	value, _ := counter.Get(rpmKey(scopes[0]))
+	assert.Equal(t, int64(2), value)
+}
+
+func TestAcquireRollback(t *testing.T) {
+	waitForWindow()
+	counter := &ratelimit.MemoryCounter{}
+	scopes := []ratelimit.Scope{
+		{Name: "token", Id: "1", RPM: 10, Concurrency: 10},
+		{Name: "user", Id: "1", RPM: 1},
+	}
+	release, limitErr, err := ratelimit.Acquire(counter, scopes)
+	assert.NoError(t, err)
+	assert.Nil(t, limitErr)
+	release(0)
+	// the user rejects the second request, the token must not count it
+	_, limitErr, err = ratelimit.Acquire(counter, scopes)
+	assert.NoError(t, err)
+	assert.NotNil(t, limitErr)
+	value, _ := counter.Get(rpmKey(scopes[0]))
+	assert.Equal(t, int64(1), value)
+	value, _ = counter.Get("relayRateLimit:token:1:concurrency")
+	assert.Equal(t, int64(0), value)
+}
+
+func TestAcquireConcurrency(t *testing.T) {
+	counter := &ratelimit.MemoryCounter{}
+	scopes := []ratelimit.Scope{{Name: "group", Id: "default:1", Concurrency: 1}}
+	release, limitErr, err := ratelimit.Acquire(counter, scopes)
+	assert.NoError(t, err)
+	assert.Nil(t, limitErr)
+	_, limitErr, err = ratelimit.Acquire(counter, scopes)
+	assert.NoError(t, err)
+	assert.NotNil(t, limitErr)
+	release(0)
+	release, limitErr, err = ratelimit.Acquire(counter, scopes)
+	assert.NoError(t, err)
+	assert.Nil(t, limitErr)
+	release(0)
+}
+
+func TestAcquireTPM(t *testing.T) {
+	waitForWindow()
+	counter := &ratelimit.MemoryCounter{}
+	scopes := []ratelimit.Scope{{Name: "token", Id: "1", TPM: 100}}
+	release, limitErr, err := ratelimit.Acquire(counter, scopes)
+	assert.NoError(t, err)
+	assert.Nil(t, limitErr)
+	// the tokens are counted when the request is finished
+	release(150)
+	_, limitErr, err = ratelimit.Acquire(counter, scopes)
+	assert.NoError(t, err)
+	if assert.NotNil(t, limitErr) {
+		assert.Equal(t, "tokens", limitErr.LimitType)
+	}
+}
@@ -0,0 +1,58 @@
+package totp_test
+
+import (
+	"encoding/base32"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/songquanpeng/one-api/common/totp"
+	"github.com/stretchr/testify/assert"
+)
+
+// the SHA1 test vectors of RFC 6238, truncated to 6 digits
+func TestGenerateCode(t *testing.T) {
+	secret := base32.StdEncoding.EncodeToString([]byte("12345678901234567890"))
+	cases := []struct {
+		unix int64
+		code string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+		{20000000000, "353130"},
+	}
+	for _, c := range cases {
+		code, err := totp.GenerateCode(secret, totp.Step(time.Unix(c.unix, 0)))
+		assert.NoError(t, err)
+		assert.Equal(t, c.code, code, "time %d", c.unix)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	secret, err := totp.GenerateSecret()
+	assert.NoError(t, err)
+	now := time.Unix(1700000000, 0)
+	code, err := totp.GenerateCode(secret, totp.Step(now))
+	assert.NoError(t, err)
+
+	step, ok := totp.Validate(secret, code, now)
+	assert.True(t, ok)
+	assert.Equal(t, totp.Step(now), step)
+
+	_, ok = totp.Validate(secret, code, now.Add(totp.Period*time.Second))
+	assert.True(t, ok, "the previous code is accepted for a skewed clock")
+	_, ok = totp.Validate(secret, code, now.Add(3*totp.Period*time.Second))
+	assert.False(t, ok)
+	_, ok = totp.Validate(secret, "12345", now)
+	assert.False(t, ok)
+}
+
+func TestURL(t *testing.T) {
+	url := totp.URL("One API", "root", "JBSWY3DPEHPK3PXP")
+	assert.True(t, strings.HasPrefix(url, "otpauth://totp/One%20API:root?"))
+	assert.Contains(t, url, "secret=JBSWY3DPEHPK3PXP")
+	assert.Contains(t, url, "issuer=One+API")
+}
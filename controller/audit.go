@@ -0,0 +1,142 @@
+package controller
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/songquanpeng/one-api/common/config"
+	"github.com/songquanpeng/one-api/model"
+)
+
+// at most this many records are exported at once, narrow the filter to export older ones
+const auditExportLimit = 10000
+
+// setAuditChange describes the change made by the handler, it is recorded by the audit middleware.
+// before is nil for creations and after is nil for deletions.
+func setAuditChange(c *gin.Context, action string, targetType string, targetId interface{}, before interface{}, after interface{}) {
+	c.Set(model.AuditChangeKey, &model.AuditChange{
+		Action:     action,
+		TargetType: targetType,
+		TargetId:   fmt.Sprint(targetId),
+		Before:     before,
+		After:      after,
+	})
+}
+
+func auditLogFilter(c *gin.Context) *model.AuditLogFilter {
+	filter := &model.AuditLogFilter{
+		ActorName:  c.Query("actor_name"),
+		Action:     c.Query("action"),
+		TargetType: c.Query("target_type"),
+		TargetId:   c.Query("target_id"),
+		Ip:         c.Query("ip"),
+		RequestId:  c.Query("request_id"),
+	}
+	filter.ActorId, _ = strconv.Atoi(c.Query("actor_id"))
+	filter.StartTimestamp, _ = strconv.ParseInt(c.Query("start_timestamp"), 10, 64)
+	filter.EndTimestamp, _ = strconv.ParseInt(c.Query("end_timestamp"), 10, 64)
+	if success, err := strconv.ParseBool(c.Query("success")); err == nil {
+		filter.Success = &success
+	}
+	return filter
+}
+
+func GetAuditLogs(c *gin.Context) {
+	page, _ := strconv.Atoi(c.Query("page"))
+	if page < 1 {
+		page = 1
+	}
+	pagesize, _ := strconv.Atoi(c.Query("pagesize"))
+	if pagesize <= 0 || pagesize > 100 {
+		pagesize = config.ItemsPerPage
+	}
+	auditLogs, total, err := model.GetAuditLogs(auditLogFilter(c), (page-1)*pagesize, pagesize)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+		"data": gin.H{
+			"list":        auditLogs,
+			"currentPage": page,
+			"pageSize":    pagesize,
+			"total":       total,
+		},
+	})
+}
+
+// csvRow escapes the cells which spreadsheet applications would evaluate as formulas
+func csvRow(cells ...string) []string {
+	for i, cell := range cells {
+		if cell != "" && strings.ContainsRune("=+-@\t\r", rune(cell[0])) {
+			cells[i] = "'" + cell
+		}
+	}
+	return cells
+}
+
+// ExportAuditLogs downloads the filtered audit logs as CSV, or as JSON with format=json
+func ExportAuditLogs(c *gin.Context) {
+	auditLogs, _, err := model.GetAuditLogs(auditLogFilter(c), 0, auditExportLimit)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+	fileName := "audit-" + time.Now().Format("20060102150405")
+	if c.Query("format") == "json" {
+		data, err := json.MarshalIndent(auditLogs, "", "  ")
+		if err != nil {
+			c.JSON(http.StatusOK, gin.H{
+				"success": false,
+				"message": err.Error(),
+			})
+			return
+		}
+		c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%s.json", fileName))
+		c.Data(http.StatusOK, "application/json", data)
+		return
+	}
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%s.csv", fileName))
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Status(http.StatusOK)
+	// the byte order mark lets spreadsheet applications detect UTF-8
+	_, _ = c.Writer.WriteString("\ufeff")
+	writer := csv.NewWriter(c.Writer)
+	_ = writer.Write([]string{"id", "time", "actor_id", "actor_name", "action", "target_type", "target_id",
+		"method", "path", "success", "message", "ip", "request_id", "before", "after", "diff"})
+	for _, auditLog := range auditLogs {
+		_ = writer.Write(csvRow(
+			strconv.Itoa(auditLog.Id),
+			time.Unix(auditLog.CreatedAt, 0).Format("2006-01-02 15:04:05"),
+			strconv.Itoa(auditLog.ActorId),
+			auditLog.ActorName,
+			auditLog.Action,
+			auditLog.TargetType,
+			auditLog.TargetId,
+			auditLog.Method,
+			auditLog.Path,
+			strconv.FormatBool(auditLog.Success),
+			auditLog.Message,
+			auditLog.Ip,
+			auditLog.RequestId,
+			auditLog.Before,
+			auditLog.After,
+			auditLog.Diff,
+		))
+	}
+	writer.Flush()
+}
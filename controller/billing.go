@@ -19,6 +19,14 @@ func GetSubscription(c *gin.Context) {
 		expiredTime = token.ExpiredTime
 		remainQuota = token.RemainQuota
 		usedQuota = token.UsedQuota
+	} else if organizationId := c.GetInt("organization_id"); organizationId != 0 {
+		// tokens of an organization spend its shared quota
+		var organization *model.Organization
+		organization, err = model.GetOrganizationById(organizationId)
+		if err == nil {
+			remainQuota = organization.Quota
+			usedQuota = organization.UsedQuota
+		}
 	} else {
 		userId := c.GetInt("id")
 		remainQuota, err = model.GetUserQuota(userId)
@@ -55,6 +63,14 @@ func GetSubscription(c *gin.Context) {
 		SystemHardLimitUSD: amount,
 		AccessUntil:        expiredTime,
 	}
+	budget := getTokenBudget(c, token)
+	if budget != nil && budget.HasBudget() {
+		subscription.BudgetPeriod = budget.BudgetPeriod
+		subscription.BudgetLimitUSD = quota2Amount(budget.BudgetQuota)
+		budgetRemain := quota2Amount(budget.BudgetRemain)
+		subscription.BudgetRemainUSD = &budgetRemain
+		subscription.BudgetResetAt = budget.BudgetResetTime
+	}
 	c.JSON(200, subscription)
 	return
 }
@@ -67,6 +83,12 @@ func GetUsage(c *gin.Context) {
 		tokenId := c.GetInt("token_id")
 		token, err = model.GetTokenById(tokenId)
 		quota = token.UsedQuota
+	} else if organizationId := c.GetInt("organization_id"); organizationId != 0 {
+		var organization *model.Organization
+		organization, err = model.GetOrganizationById(organizationId)
+		if err == nil {
+			quota = organization.UsedQuota
+		}
 	} else {
 		userId := c.GetInt("id")
 		quota, err = model.GetUserUsedQuota(userId)
@@ -89,6 +111,35 @@ func GetUsage(c *gin.Context) {
 		Object:     "list",
 		TotalUsage: amount * 100,
 	}
+	budget := getTokenBudget(c, token)
+	if budget != nil && budget.HasBudget() {
+		budgetUsage := quota2Amount(budget.BudgetUsed) * 100
+		usage.BudgetUsage = &budgetUsage
+		usage.BudgetResetAt = budget.BudgetResetTime
+	}
 	c.JSON(200, usage)
 	return
 }
+
+func quota2Amount(quota int64) float64 {
+	amount := float64(quota)
+	if config.DisplayInCurrencyEnabled {
+		amount /= config.QuotaPerUnit
+	}
+	return amount
+}
+
+// getTokenBudget returns the budget of the token of the request in its current period,
+// the budget is shown even when the token stats are not displayed
+func getTokenBudget(c *gin.Context, token *model.Token) *model.TokenBudget {
+	if token == nil {
+		var err error
+		token, err = model.GetTokenById(c.GetInt("token_id"))
+		if err != nil {
+			return nil
+		}
+	}
+	budget := token.TokenBudget
+	budget.FillBudgetRemain()
+	return &budget
+}
@@ -0,0 +1,219 @@
+package controller
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/songquanpeng/one-api/common"
+	"github.com/songquanpeng/one-api/model"
+)
+
+// https://openrouter.ai/docs/api-reference/get-credits
+type OpenRouterCreditsResponse struct {
+	Data struct {
+		TotalCredits float64 `json:"total_credits"`
+		TotalUsage   float64 `json:"total_usage"`
+	} `json:"data"`
+}
+
+// https://api-docs.deepseek.com/api/get-user-balance
+type DeepSeekBalanceResponse struct {
+	IsAvailable  bool `json:"is_available"`
+	BalanceInfos []struct {
+		Currency     string `json:"currency"`
+		TotalBalance string `json:"total_balance"`
+	} `json:"balance_infos"`
+}
+
+// https://docs.siliconflow.cn/api-reference/userinfo/get-user-info
+type SiliconFlowUserInfoResponse struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+	Status  bool   `json:"status"`
+	Data    struct {
+		TotalBalance string `json:"totalBalance"`
+	} `json:"data"`
+}
+
+// https://platform.moonshot.cn/docs/api/misc
+type MoonshotBalanceResponse struct {
+	Code   int  `json:"code"`
+	Status bool `json:"status"`
+	Data   struct {
+		AvailableBalance float64 `json:"available_balance"`
+	} `json:"data"`
+}
+
+// balanceURL returns the balance_url of the channel config, or the given default
+func balanceURL(channel *model.Channel, defaultURL string) string {
+	cfg, _ := channel.LoadConfig()
+	if url := cfg[model.ChannelConfigBalanceURL]; url != "" {
+		return url
+	}
+	return defaultURL
+}
+
+// rmbToUSD converts a balance reported in RMB, the balance of a channel is kept in USD
+func rmbToUSD(balance float64) float64 {
+	return balance / common.USD2RMB
+}
+
+func fetchOpenRouterBalance(channel *model.Channel) (float64, error) {
+	url := balanceURL(channel, "https://openrouter.ai/api/v1/credits")
+	body, err := GetResponseBody("GET", url, channel, GetAuthHeader(channel.Key))
+	if err != nil {
+		return 0, err
+	}
+	response := OpenRouterCreditsResponse{}
+	err = json.Unmarshal(body, &response)
+	if err != nil {
+		return 0, err
+	}
+	return response.Data.TotalCredits - response.Data.TotalUsage, nil
+}
+
+func fetchDeepSeekBalance(channel *model.Channel) (float64, error) {
+	url := balanceURL(channel, "https://api.deepseek.com/user/balance")
+	body, err := GetResponseBody("GET", url, channel, GetAuthHeader(channel.Key))
+	if err != nil {
+		return 0, err
+	}
+	response := DeepSeekBalanceResponse{}
+	err = json.Unmarshal(body, &response)
+	if err != nil {
+		return 0, err
+	}
+	balance := 0.0
+	for _, info := range response.BalanceInfos {
+		amount, err := strconv.ParseFloat(info.TotalBalance, 64)
+		if err != nil {
+			return 0, err
+		}
+		switch info.Currency {
+		case "USD":
+			balance += amount
+		case "CNY":
+			balance += rmbToUSD(amount)
+		default:
+			return 0, fmt.Errorf("unknown currency: %s", info.Currency)
+		}
+	}
+	return balance, nil
+}
+
+func fetchSiliconFlowBalance(channel *model.Channel) (float64, error) {
+	url := balanceURL(channel, "https://api.siliconflow.cn/v1/user/info")
+	body, err := GetResponseBody("GET", url, channel, GetAuthHeader(channel.Key))
+	if err != nil {
+		return 0, err
+	}
+	response := SiliconFlowUserInfoResponse{}
+	err = json.Unmarshal(body, &response)
+	if err != nil {
+		return 0, err
+	}
+	if !response.Status {
+		return 0, fmt.Errorf("code: %d, message: %s", response.Code, response.Message)
+	}
+	balance, err := strconv.ParseFloat(response.Data.TotalBalance, 64)
+	if err != nil {
+		return 0, err
+	}
+	return rmbToUSD(balance), nil
+}
+
+func fetchMoonshotBalance(channel *model.Channel) (float64, error) {
+	url := balanceURL(channel, "https://api.moonshot.cn/v1/users/me/balance")
+	body, err := GetResponseBody("GET", url, channel, GetAuthHeader(channel.Key))
+	if err != nil {
+		return 0, err
+	}
+	response := MoonshotBalanceResponse{}
+	err = json.Unmarshal(body, &response)
+	if err != nil {
+		return 0, err
+	}
+	if !response.Status {
+		return 0, fmt.Errorf("code: %d", response.Code)
+	}
+	return rmbToUSD(response.Data.AvailableBalance), nil
+}
+
+// fetchJSONBalance reads the balance from any JSON endpoint, configured by the channel config:
+// balance_url is requested with the channel key as bearer token, balance_path is the dot separated
+// path of the balance in the response, and balance_currency may be set to CNY to convert the result
+func fetchJSONBalance(channel *model.Channel) (float64, error) {
+	cfg, err := channel.LoadConfig()
+	if err != nil {
+		return 0, err
+	}
+	url := cfg[model.ChannelConfigBalanceURL]
+	if url == "" {
+		return 0, errors.New("balance_url is not configured")
+	}
+	path := cfg[model.ChannelConfigBalancePath]
+	if path == "" {
+		return 0, errors.New("balance_path is not configured")
+	}
+	headers := GetAuthHeader(channel.Key)
+	if header := cfg[model.ChannelConfigBalanceHeader]; header != "" {
+		headers = http.Header{}
+		headers.Add(header, channel.Key)
+	}
+	body, err := GetResponseBody("GET", url, channel, headers)
+	if err != nil {
+		return 0, err
+	}
+	balance, err := extractJSONNumber(body, path)
+	if err != nil {
+		return 0, err
+	}
+	switch strings.ToUpper(cfg[model.ChannelConfigBalanceCurrency]) {
+	case "", "USD":
+		return balance, nil
+	case "CNY", "RMB":
+		return rmbToUSD(balance), nil
+	default:
+		return 0, fmt.Errorf("unknown currency: %s", cfg[model.ChannelConfigBalanceCurrency])
+	}
+}
+
+// extractJSONNumber walks the dot separated path, array elements are addressed by their index,
+// numbers encoded as strings are accepted as well
+func extractJSONNumber(body []byte, path string) (float64, error) {
+	var value any
+	err := json.Unmarshal(body, &value)
+	if err != nil {
+		return 0, err
+	}
+	for _, key := range strings.Split(path, ".") {
+		switch v := value.(type) {
+		case map[string]any:
+			var ok bool
+			value, ok = v[key]
+			if !ok {
+				return 0, fmt.Errorf("%s not found in response", path)
+			}
+		case []any:
+			index, err := strconv.Atoi(key)
+			if err != nil || index < 0 || index >= len(v) {
+				return 0, fmt.Errorf("%s not found in response", path)
+			}
+			value = v[index]
+		default:
+			return 0, fmt.Errorf("%s not found in response", path)
+		}
+	}
+	switch v := value.(type) {
+	case float64:
+		return v, nil
+	case string:
+		return strconv.ParseFloat(v, 64)
+	default:
+		return 0, fmt.Errorf("%s is not a number", path)
+	}
+}
@@ -13,6 +13,7 @@ import (
 	"io"
 	"net/http"
 	"strconv"
+	"sync"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -27,6 +28,11 @@ type OpenAISubscriptionResponse struct {
 	HardLimitUSD       float64 `json:"hard_limit_usd"`
 	SystemHardLimitUSD float64 `json:"system_hard_limit_usd"`
 	AccessUntil        int64   `json:"access_until"`
+	// recurring budget of the token, if any
+	BudgetPeriod    string   `json:"budget_period,omitempty"`
+	BudgetLimitUSD  float64  `json:"budget_limit_usd,omitempty"`
+	BudgetRemainUSD *float64 `json:"budget_remain_usd,omitempty"`
+	BudgetResetAt   int64    `json:"budget_reset_at,omitempty"`
 }
 
 type OpenAIUsageDailyCost struct {
@@ -48,6 +54,9 @@ type OpenAIUsageResponse struct {
 	Object string `json:"object"`
 	//DailyCosts []OpenAIUsageDailyCost `json:"daily_costs"`
 	TotalUsage float64 `json:"total_usage"` // unit: 0.01 dollar
+	// usage in the current period of the recurring budget of the token, if any
+	BudgetUsage   *float64 `json:"budget_usage,omitempty"` // unit: 0.01 dollar
+	BudgetResetAt int64    `json:"budget_reset_at,omitempty"`
 }
 
 type OpenAISBUsageResponse struct {
@@ -96,7 +105,15 @@ func GetResponseBody(method, url string, channel *model.Channel, headers http.He
 	for k := range headers {
 		req.Header.Add(k, headers.Get(k))
 	}
-	res, err := util.HTTPClient.Do(req)
+	cfg, err := channel.LoadConfig()
+	if err != nil {
+		return nil, err
+	}
+	client, err := util.GetChannelHTTPClient(util.NewTransportOptions(cfg))
+	if err != nil {
+		return nil, err
+	}
+	res, err := client.Do(req)
 	if err != nil {
 		return nil, err
 	}
@@ -114,7 +131,7 @@ func GetResponseBody(method, url string, channel *model.Channel, headers http.He
 	return body, nil
 }
 
-func updateChannelCloseAIBalance(channel *model.Channel) (float64, error) {
+func fetchCloseAIBalance(channel *model.Channel) (float64, error) {
 	url := fmt.Sprintf("%s/dashboard/billing/credit_grants", channel.GetBaseURL())
 	body, err := GetResponseBody("GET", url, channel, GetAuthHeader(channel.Key))
 
@@ -126,11 +143,10 @@ func updateChannelCloseAIBalance(channel *model.Channel) (float64, error) {
 	if err != nil {
 		return 0, err
 	}
-	channel.UpdateBalance(response.TotalAvailable)
 	return response.TotalAvailable, nil
 }
 
-func updateChannelOpenAISBBalance(channel *model.Channel) (float64, error) {
+func fetchOpenAISBBalance(channel *model.Channel) (float64, error) {
 	url := fmt.Sprintf("https://api.openai-sb.com/sb-api/user/status?api_key=%s", channel.Key)
 	body, err := GetResponseBody("GET", url, channel, GetAuthHeader(channel.Key))
 	if err != nil {
@@ -148,11 +164,10 @@ func updateChannelOpenAISBBalance(channel *model.Channel) (float64, error) {
 	if err != nil {
 		return 0, err
 	}
-	channel.UpdateBalance(balance)
 	return balance, nil
 }
 
-func updateChannelAIProxyBalance(channel *model.Channel) (float64, error) {
+func fetchAIProxyBalance(channel *model.Channel) (float64, error) {
 	url := "https://aiproxy.io/api/report/getUserOverview"
 	headers := http.Header{}
 	headers.Add("Api-Key", channel.Key)
@@ -168,11 +183,10 @@ func updateChannelAIProxyBalance(channel *model.Channel) (float64, error) {
 	if !response.Success {
 		return 0, fmt.Errorf("code: %d, message: %s", response.ErrorCode, response.Message)
 	}
-	channel.UpdateBalance(response.Data.TotalPoints)
 	return response.Data.TotalPoints, nil
 }
 
-func updateChannelAPI2GPTBalance(channel *model.Channel) (float64, error) {
+func fetchAPI2GPTBalance(channel *model.Channel) (float64, error) {
 	url := "https://api.api2gpt.com/dashboard/billing/credit_grants"
 	body, err := GetResponseBody("GET", url, channel, GetAuthHeader(channel.Key))
 
@@ -184,11 +198,10 @@ func updateChannelAPI2GPTBalance(channel *model.Channel) (float64, error) {
 	if err != nil {
 		return 0, err
 	}
-	channel.UpdateBalance(response.TotalRemaining)
 	return response.TotalRemaining, nil
 }
 
-func updateChannelAIGC2DBalance(channel *model.Channel) (float64, error) {
+func fetchAIGC2DBalance(channel *model.Channel) (float64, error) {
 	url := "https://api.aigc2d.com/dashboard/billing/credit_grants"
 	body, err := GetResponseBody("GET", url, channel, GetAuthHeader(channel.Key))
 	if err != nil {
@@ -199,36 +212,13 @@ func updateChannelAIGC2DBalance(channel *model.Channel) (float64, error) {
 	if err != nil {
 		return 0, err
 	}
-	channel.UpdateBalance(response.TotalAvailable)
 	return response.TotalAvailable, nil
 }
 
-func updateChannelBalance(channel *model.Channel) (float64, error) {
-	baseURL := common.ChannelBaseURLs[channel.Type]
-	if channel.GetBaseURL() == "" {
-		channel.BaseURL = &baseURL
-	}
-	switch channel.Type {
-	case common.ChannelTypeOpenAI:
-		if channel.GetBaseURL() != "" {
-			baseURL = channel.GetBaseURL()
-		}
-	case common.ChannelTypeAzure:
-		return 0, errors.New("尚未实现")
-	case common.ChannelTypeCustom:
-		baseURL = channel.GetBaseURL()
-	case common.ChannelTypeCloseAI:
-		return updateChannelCloseAIBalance(channel)
-	case common.ChannelTypeOpenAISB:
-		return updateChannelOpenAISBBalance(channel)
-	case common.ChannelTypeAIProxy:
-		return updateChannelAIProxyBalance(channel)
-	case common.ChannelTypeAPI2GPT:
-		return updateChannelAPI2GPTBalance(channel)
-	case common.ChannelTypeAIGC2D:
-		return updateChannelAIGC2DBalance(channel)
-	default:
-		return 0, errors.New("尚未实现")
+func fetchOpenAIBalance(channel *model.Channel) (float64, error) {
+	baseURL := channel.GetBaseURL()
+	if baseURL == "" {
+		baseURL = common.ChannelBaseURLs[common.ChannelTypeOpenAI]
 	}
 	url := fmt.Sprintf("%s/v1/dashboard/billing/subscription", baseURL)
 
@@ -258,10 +248,111 @@ func updateChannelBalance(channel *model.Channel) (float64, error) {
 		return 0, err
 	}
 	balance := subscription.HardLimitUSD - usage.TotalUsage/100
+	return balance, nil
+}
+
+// BalanceProvider fetches the remaining balance of a channel from its upstream
+type BalanceProvider interface {
+	FetchBalance(channel *model.Channel) (float64, error)
+}
+
+type BalanceProviderFunc func(channel *model.Channel) (float64, error)
+
+func (f BalanceProviderFunc) FetchBalance(channel *model.Channel) (float64, error) {
+	return f(channel)
+}
+
+// balanceProviders can be selected explicitly with the balance_provider key of the channel config
+var balanceProviders = map[string]BalanceProvider{
+	"openai":      BalanceProviderFunc(fetchOpenAIBalance),
+	"closeai":     BalanceProviderFunc(fetchCloseAIBalance),
+	"openai-sb":   BalanceProviderFunc(fetchOpenAISBBalance),
+	"aiproxy":     BalanceProviderFunc(fetchAIProxyBalance),
+	"api2gpt":     BalanceProviderFunc(fetchAPI2GPTBalance),
+	"aigc2d":      BalanceProviderFunc(fetchAIGC2DBalance),
+	"openrouter":  BalanceProviderFunc(fetchOpenRouterBalance),
+	"deepseek":    BalanceProviderFunc(fetchDeepSeekBalance),
+	"siliconflow": BalanceProviderFunc(fetchSiliconFlowBalance),
+	"moonshot":    BalanceProviderFunc(fetchMoonshotBalance),
+	"json":        BalanceProviderFunc(fetchJSONBalance),
+}
+
+// providers used when the channel config does not name one
+var channelType2BalanceProvider = map[int]string{
+	common.ChannelTypeOpenAI:     "openai",
+	common.ChannelTypeCustom:     "openai",
+	common.ChannelTypeCloseAI:    "closeai",
+	common.ChannelTypeOpenAISB:   "openai-sb",
+	common.ChannelTypeAIProxy:    "aiproxy",
+	common.ChannelTypeAPI2GPT:    "api2gpt",
+	common.ChannelTypeAIGC2D:     "aigc2d",
+	common.ChannelTypeOpenRouter: "openrouter",
+	common.ChannelTypeMoonshot:   "moonshot",
+}
+
+func getBalanceProvider(channel *model.Channel) (BalanceProvider, error) {
+	cfg, err := channel.LoadConfig()
+	if err != nil {
+		return nil, err
+	}
+	name := cfg[model.ChannelConfigBalanceProvider]
+	if name == "" && cfg[model.ChannelConfigBalanceURL] != "" {
+		name = "json"
+	}
+	if name == "" {
+		name = channelType2BalanceProvider[channel.Type]
+	}
+	if name == "" {
+		return nil, errors.New("尚未实现")
+	}
+	provider, ok := balanceProviders[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown balance provider: %s", name)
+	}
+	return provider, nil
+}
+
+func updateChannelBalance(channel *model.Channel) (float64, error) {
+	provider, err := getBalanceProvider(channel)
+	if err != nil {
+		return 0, err
+	}
+	// the providers query the upstream with the plaintext key, on a copy so that it does not leak into the channel
+	key, err := channel.DecryptKey()
+	if err != nil {
+		return 0, err
+	}
+	plainChannel := *channel
+	plainChannel.Key = key
+	balance, err := provider.FetchBalance(&plainChannel)
+	if err != nil {
+		return 0, err
+	}
 	channel.UpdateBalance(balance)
+	checkChannelBalanceThreshold(channel, balance)
 	return balance, nil
 }
 
+// checkChannelBalanceThreshold notifies the root user when the balance drops below the threshold of the channel
+// and deprioritizes the channel if asked to, both are undone once the balance recovers
+func checkChannelBalanceThreshold(channel *model.Channel, balance float64) {
+	threshold := channel.GetBalanceThreshold()
+	low := threshold > 0 && balance < threshold
+	if low == channel.BalanceLow {
+		return
+	}
+	err := channel.UpdateBalanceLow(low)
+	if err != nil {
+		logger.SysError(fmt.Sprintf("failed to update balance state of channel #%d: %s", channel.Id, err.Error()))
+		return
+	}
+	if low {
+		monitor.NotifyLowBalance(channel.Id, channel.Name, balance, threshold, channel.GetBalanceDeprioritize())
+	} else if threshold > 0 {
+		monitor.NotifyBalanceRecovered(channel.Id, channel.Name, balance)
+	}
+}
+
 func UpdateChannelBalance(c *gin.Context) {
 	id, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
@@ -304,8 +395,7 @@ func updateAllChannelsBalance() error {
 		if channel.Status != common.ChannelStatusEnabled {
 			continue
 		}
-		// TODO: support Azure
-		if channel.Type != common.ChannelTypeOpenAI && channel.Type != common.ChannelTypeCustom {
+		if _, err := getBalanceProvider(channel); err != nil {
 			continue
 		}
 		balance, err := updateChannelBalance(channel)
@@ -322,15 +412,34 @@ func updateAllChannelsBalance() error {
 	return nil
 }
 
+var updateAllChannelsBalanceLock sync.Mutex
+var updateAllChannelsBalanceRunning bool = false
+
+// UpdateAllChannelsBalance starts the update in the background, it waits between channels and may disable some,
+// which is too long to be done within the request
 func UpdateAllChannelsBalance(c *gin.Context) {
-	//err := updateAllChannelsBalance()
-	//if err != nil {
-	//	c.JSON(http.StatusOK, gin.H{
-	//		"success": false,
-	//		"message": err.Error(),
-	//	})
-	//	return
-	//}
+	updateAllChannelsBalanceLock.Lock()
+	if updateAllChannelsBalanceRunning {
+		updateAllChannelsBalanceLock.Unlock()
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": "余额更新已在运行中",
+		})
+		return
+	}
+	updateAllChannelsBalanceRunning = true
+	updateAllChannelsBalanceLock.Unlock()
+	common.SafeGoroutine(func() {
+		defer func() {
+			updateAllChannelsBalanceLock.Lock()
+			updateAllChannelsBalanceRunning = false
+			updateAllChannelsBalanceLock.Unlock()
+		}()
+		err := updateAllChannelsBalance()
+		if err != nil {
+			logger.SysError("failed to update the balance of all channels: " + err.Error())
+		}
+	})
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
 		"message": "",
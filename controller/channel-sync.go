@@ -0,0 +1,216 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/songquanpeng/one-api/common"
+	"github.com/songquanpeng/one-api/common/config"
+	"github.com/songquanpeng/one-api/common/logger"
+	"github.com/songquanpeng/one-api/middleware"
+	"github.com/songquanpeng/one-api/model"
+	"github.com/songquanpeng/one-api/relay/channel"
+	"github.com/songquanpeng/one-api/relay/constant"
+	"github.com/songquanpeng/one-api/relay/helper"
+	"github.com/songquanpeng/one-api/relay/util"
+)
+
+type ChannelModelSyncResult struct {
+	ChannelId   int      `json:"channel_id"`
+	ChannelName string   `json:"channel_name"`
+	Upstream    []string `json:"upstream"`
+	Added       []string `json:"added"`
+	Removed     []string `json:"removed"`
+	Models      string   `json:"models"` // the models of the channel after applying the diff
+	Applied     bool     `json:"applied"`
+	Error       string   `json:"error,omitempty"`
+}
+
+// fetchUpstreamModels asks the upstream of the channel which models it serves
+func fetchUpstreamModels(ch *model.Channel) ([]string, error) {
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = &http.Request{
+		Method: "GET",
+		URL:    &url.URL{Path: "/v1/models"},
+		Header: make(http.Header),
+	}
+	if err := middleware.SetupContextForSelectedChannel(c, ch, ""); err != nil {
+		return nil, err
+	}
+	meta := util.GetRelayMeta(c)
+	adaptor := helper.GetAdaptor(constant.ChannelType2APIType(ch.Type))
+	if adaptor == nil {
+		return nil, fmt.Errorf("invalid channel type: %d", ch.Type)
+	}
+	adaptor.Init(meta)
+	fetcher, ok := adaptor.(channel.ModelFetcher)
+	if !ok {
+		return nil, fmt.Errorf("model discovery is not supported for %s", adaptor.GetChannelName())
+	}
+	return fetcher.FetchModelList(meta)
+}
+
+// diffChannelModels compares the models of the channel with the upstream ones,
+// models renamed by the model mapping are compared by their upstream name
+func diffChannelModels(ch *model.Channel, upstream []string) *ChannelModelSyncResult {
+	result := &ChannelModelSyncResult{
+		ChannelId:   ch.Id,
+		ChannelName: ch.Name,
+		Upstream:    upstream,
+		Added:       make([]string, 0),
+		Removed:     make([]string, 0),
+	}
+	upstreamSet := make(map[string]bool, len(upstream))
+	for _, modelName := range upstream {
+		upstreamSet[modelName] = true
+	}
+	modelMapping := ch.GetModelMapping()
+	known := make(map[string]bool)
+	kept := make([]string, 0)
+	for _, modelName := range strings.Split(ch.Models, ",") {
+		if modelName == "" {
+			continue
+		}
+		actualModelName := modelName
+		if mapped, ok := modelMapping[modelName]; ok {
+			actualModelName = mapped
+		}
+		known[modelName] = true
+		known[actualModelName] = true
+		if upstreamSet[actualModelName] {
+			kept = append(kept, modelName)
+		} else {
+			result.Removed = append(result.Removed, modelName)
+		}
+	}
+	for _, modelName := range upstream {
+		if !known[modelName] {
+			result.Added = append(result.Added, modelName)
+			known[modelName] = true
+		}
+	}
+	sort.Strings(result.Added)
+	result.Models = strings.Join(append(kept, result.Added...), ",")
+	return result
+}
+
+func syncChannelModels(ch *model.Channel, apply bool) (*ChannelModelSyncResult, error) {
+	upstream, err := fetchUpstreamModels(ch)
+	if err != nil {
+		return nil, err
+	}
+	result := diffChannelModels(ch, upstream)
+	if !apply || (len(result.Added) == 0 && len(result.Removed) == 0) {
+		return result, nil
+	}
+	if len(upstream) == 0 {
+		return nil, errors.New("upstream returned no models, refusing to clear the channel models")
+	}
+	if err := model.CheckChannelsNotManaged([]int{ch.Id}); err != nil {
+		return nil, err
+	}
+	if err := model.UpdateChannelModels(ch.Id, result.Models); err != nil {
+		return nil, err
+	}
+	result.Applied = true
+	return result, nil
+}
+
+func syncAllChannelsModels(apply bool) []*ChannelModelSyncResult {
+	results := make([]*ChannelModelSyncResult, 0)
+	channels, err := model.GetAllChannels(0, 0, "all")
+	if err != nil {
+		logger.SysError("failed to get channels: " + err.Error())
+		return results
+	}
+	for _, ch := range channels {
+		if ch.Status != common.ChannelStatusEnabled {
+			continue
+		}
+		result, err := syncChannelModels(ch, apply)
+		if err != nil {
+			result = &ChannelModelSyncResult{ChannelId: ch.Id, ChannelName: ch.Name, Error: err.Error()}
+		}
+		results = append(results, result)
+		time.Sleep(config.RequestInterval)
+	}
+	if apply && config.MemoryCacheEnabled {
+		model.InitChannelCache()
+	}
+	return results
+}
+
+// SyncChannelModels previews the model diff of a channel on GET and applies it on POST
+func SyncChannelModels(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+	ch, err := model.GetChannelById(id, true)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+	apply := c.Request.Method == http.MethodPost
+	result, err := syncChannelModels(ch, apply)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+	if result.Applied && config.MemoryCacheEnabled {
+		model.InitChannelCache()
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+		"data":    result,
+	})
+	return
+}
+
+func SyncAllChannelsModels(c *gin.Context) {
+	apply := c.Request.Method == http.MethodPost
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+		"data":    syncAllChannelsModels(apply),
+	})
+	return
+}
+
+func AutomaticallySyncChannelModels(frequency int) {
+	for {
+		time.Sleep(time.Duration(frequency) * time.Minute)
+		logger.SysLog("syncing models of all channels")
+		results := syncAllChannelsModels(config.ChannelModelSyncApplyEnabled)
+		for _, result := range results {
+			if result.Error != "" {
+				logger.SysError(fmt.Sprintf("failed to sync models of channel #%d: %s", result.ChannelId, result.Error))
+				continue
+			}
+			if len(result.Added) != 0 || len(result.Removed) != 0 {
+				logger.SysLog(fmt.Sprintf("channel #%d models changed upstream, added: %s, removed: %s, applied: %t",
+					result.ChannelId, strings.Join(result.Added, ","), strings.Join(result.Removed, ","), result.Applied))
+			}
+		}
+		logger.SysLog("channel model sync finished")
+	}
+}
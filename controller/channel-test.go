@@ -16,11 +16,14 @@ import (
 
 	"github.com/songquanpeng/one-api/common"
 	"github.com/songquanpeng/one-api/common/config"
+	helperpkg "github.com/songquanpeng/one-api/common/helper"
+	"github.com/songquanpeng/one-api/common/jsonschema"
 	"github.com/songquanpeng/one-api/common/logger"
 	"github.com/songquanpeng/one-api/common/message"
 	"github.com/songquanpeng/one-api/middleware"
 	"github.com/songquanpeng/one-api/model"
 	"github.com/songquanpeng/one-api/monitor"
+	"github.com/songquanpeng/one-api/relay/channel/openai"
 	"github.com/songquanpeng/one-api/relay/constant"
 	"github.com/songquanpeng/one-api/relay/helper"
 	relaymodel "github.com/songquanpeng/one-api/relay/model"
@@ -43,8 +46,48 @@ func buildTestRequest() *relaymodel.GeneralOpenAIRequest {
 	return testRequest
 }
 
+// testResponseRecorder records when the first byte of the response body is written,
+// which is the time to first token in stream mode
+type testResponseRecorder struct {
+	*httptest.ResponseRecorder
+	start          time.Time
+	firstTokenTime time.Duration
+}
+
+func newTestResponseRecorder() *testResponseRecorder {
+	return &testResponseRecorder{ResponseRecorder: httptest.NewRecorder(), start: time.Now()}
+}
+
+func (r *testResponseRecorder) Write(b []byte) (int, error) {
+	if r.firstTokenTime == 0 && len(b) > 0 {
+		r.firstTokenTime = time.Since(r.start)
+	}
+	return r.ResponseRecorder.Write(b)
+}
+
+func (r *testResponseRecorder) WriteString(str string) (int, error) {
+	return r.Write([]byte(str))
+}
+
+func (r *testResponseRecorder) CloseNotify() <-chan bool {
+	return make(chan bool)
+}
+
+type channelTestResult struct {
+	Output         string
+	Usage          *relaymodel.Usage
+	ResponseTime   time.Duration
+	FirstTokenTime time.Duration
+}
+
 func testChannel(channel *model.Channel) (err error, openaiErr *relaymodel.Error) {
-	w := httptest.NewRecorder()
+	_, err, openaiErr = doChannelTest(channel, "", buildTestRequest())
+	return err, openaiErr
+}
+
+// doChannelTest sends the request to the channel, an empty modelName selects the first model of the channel
+func doChannelTest(channel *model.Channel, modelName string, request *relaymodel.GeneralOpenAIRequest) (result *channelTestResult, err error, openaiErr *relaymodel.Error) {
+	w := newTestResponseRecorder()
 	c, _ := gin.CreateTestContext(w)
 	c.Request = &http.Request{
 		Method: "POST",
@@ -52,61 +95,199 @@ func testChannel(channel *model.Channel) (err error, openaiErr *relaymodel.Error
 		Body:   nil,
 		Header: make(http.Header),
 	}
-	c.Request.Header.Set("Authorization", "Bearer "+channel.Key)
 	c.Request.Header.Set("Content-Type", "application/json")
 	c.Set("channel", channel.Type)
 	c.Set("base_url", channel.GetBaseURL())
-	middleware.SetupContextForSelectedChannel(c, channel, "")
+	if err := middleware.SetupContextForSelectedChannel(c, channel, ""); err != nil {
+		return nil, err, nil
+	}
 	meta := util.GetRelayMeta(c)
 	apiType := constant.ChannelType2APIType(channel.Type)
 	adaptor := helper.GetAdaptor(apiType)
 	if adaptor == nil {
-		return fmt.Errorf("invalid api type: %d, adaptor is nil", apiType), nil
+		return nil, fmt.Errorf("invalid api type: %d, adaptor is nil", apiType), nil
 	}
 	adaptor.Init(meta)
-	modelName := adaptor.GetModelList()[0]
-	if !strings.Contains(channel.Models, modelName) {
-		modelNames := strings.Split(channel.Models, ",")
-		if len(modelNames) > 0 {
-			modelName = modelNames[0]
+	if modelName == "" {
+		modelName = adaptor.GetModelList()[0]
+		if !strings.Contains(channel.Models, modelName) {
+			modelNames := strings.Split(channel.Models, ",")
+			if len(modelNames) > 0 {
+				modelName = modelNames[0]
+			}
 		}
 	}
-	request := buildTestRequest()
 	request.Model = modelName
 	meta.OriginModelName, meta.ActualModelName = modelName, modelName
+	if mapped, ok := channel.GetModelMapping()[modelName]; ok {
+		meta.ActualModelName = mapped
+		request.Model = mapped
+	}
+	meta.IsStream = request.Stream
 	convertedRequest, err := adaptor.ConvertRequest(c, constant.RelayModeChatCompletions, request)
 	if err != nil {
-		return err, nil
+		return nil, err, nil
 	}
 	jsonData, err := json.Marshal(convertedRequest)
 	if err != nil {
-		return err, nil
+		return nil, err, nil
 	}
 	requestBody := bytes.NewBuffer(jsonData)
 	c.Request.Body = io.NopCloser(requestBody)
+	w.start = time.Now()
 	resp, err := adaptor.DoRequest(c, meta, requestBody)
 	if err != nil {
-		return err, nil
+		return nil, err, nil
 	}
 	if resp.StatusCode != http.StatusOK {
 		err := util.RelayErrorHandler(resp)
-		return fmt.Errorf("status code %d: %s", resp.StatusCode, err.Error.Message), &err.Error
+		return nil, fmt.Errorf("status code %d: %s", resp.StatusCode, err.Error.Message), &err.Error
 	}
 	usage, respErr := adaptor.DoResponse(c, resp, meta)
 	if respErr != nil {
-		return fmt.Errorf("%s", respErr.Error.Message), &respErr.Error
+		return nil, fmt.Errorf("%s", respErr.Error.Message), &respErr.Error
 	}
 	if usage == nil {
-		return errors.New("usage is nil"), nil
+		return nil, errors.New("usage is nil"), nil
 	}
-	result := w.Result()
-	// print result.Body
-	respBody, err := io.ReadAll(result.Body)
+	result = &channelTestResult{
+		Usage:          usage,
+		ResponseTime:   time.Since(w.start),
+		FirstTokenTime: w.firstTokenTime,
+	}
+	respBody, err := io.ReadAll(w.Result().Body)
 	if err != nil {
-		return err, nil
+		return nil, err, nil
+	}
+	if request.Stream {
+		result.Output = parseStreamOutput(respBody)
+	} else {
+		result.Output = parseResponseOutput(respBody)
+		result.FirstTokenTime = result.ResponseTime
 	}
 	logger.SysLog(fmt.Sprintf("testing channel #%d, response: \n%s", channel.Id, string(respBody)))
-	return nil, nil
+	return result, nil, nil
+}
+
+func parseResponseOutput(body []byte) string {
+	var response openai.TextResponse
+	if err := json.Unmarshal(body, &response); err != nil || len(response.Choices) == 0 {
+		return ""
+	}
+	return response.Choices[0].StringContent()
+}
+
+func parseStreamOutput(body []byte) string {
+	var output strings.Builder
+	for _, line := range strings.Split(string(body), "\n") {
+		line = strings.TrimSpace(line)
+		if !strings.HasPrefix(line, "data:") {
+			continue
+		}
+		data := strings.TrimSpace(strings.TrimPrefix(line, "data:"))
+		if data == "[DONE]" {
+			continue
+		}
+		var streamResponse openai.ChatCompletionsStreamResponse
+		if err := json.Unmarshal([]byte(data), &streamResponse); err != nil {
+			continue
+		}
+		for _, choice := range streamResponse.Choices {
+			output.WriteString(choice.Delta.StringContent())
+		}
+	}
+	return output.String()
+}
+
+// checkChannelTestAssertions verifies the output against the expectations of the test case
+func checkChannelTestAssertions(testCase *model.ChannelTestCase, output string) error {
+	if testCase.ExpectContains != "" && !strings.Contains(output, testCase.ExpectContains) {
+		return fmt.Errorf("output does not contain %q", testCase.ExpectContains)
+	}
+	if len(testCase.ExpectJSONSchema) != 0 && string(testCase.ExpectJSONSchema) != "null" {
+		document := strings.TrimSpace(output)
+		// models like to wrap json in a markdown code block
+		document = strings.TrimPrefix(document, "```json")
+		document = strings.TrimPrefix(document, "```")
+		document = strings.TrimSuffix(document, "```")
+		if err := jsonschema.Validate(testCase.ExpectJSONSchema, []byte(document)); err != nil {
+			return fmt.Errorf("output does not match the json schema: %s", err.Error())
+		}
+	}
+	return nil
+}
+
+// chat completion tests make no sense for these models
+var nonChatModelKeywords = []string{"embedding", "whisper", "tts", "dall-e", "moderation", "mj_", "swap_face", "midjourney"}
+
+func isChatModel(modelName string) bool {
+	for _, keyword := range nonChatModelKeywords {
+		if strings.Contains(modelName, keyword) {
+			return false
+		}
+	}
+	return true
+}
+
+// runFullChannelTest tests every chat model of the channel with every test case, in both stream and non-stream mode,
+// each result is recorded under runId as soon as it is known
+func runFullChannelTest(channel *model.Channel, runId string) {
+	testCases := model.GetChannelTestCases()
+	for _, modelName := range strings.Split(channel.Models, ",") {
+		if modelName == "" || !isChatModel(modelName) {
+			continue
+		}
+		for i := range testCases {
+			testCase := &testCases[i]
+			for _, stream := range []bool{false, true} {
+				request := &relaymodel.GeneralOpenAIRequest{
+					MaxTokens: testCase.MaxTokens,
+					Stream:    stream,
+					Messages: []relaymodel.Message{{
+						Role:    "user",
+						Content: testCase.Prompt,
+					}},
+				}
+				test := &model.ChannelTest{
+					RunId:     runId,
+					ChannelId: channel.Id,
+					ModelName: modelName,
+					CaseName:  testCase.Name,
+					Stream:    stream,
+					CreatedAt: helperpkg.GetTimestamp(),
+				}
+				result, err, _ := doChannelTest(channel, modelName, request)
+				if err == nil {
+					test.Output = result.Output
+					test.ResponseTime = result.ResponseTime.Milliseconds()
+					test.FirstTokenTime = result.FirstTokenTime.Milliseconds()
+					test.PromptTokens = result.Usage.PromptTokens
+					test.CompletionTokens = result.Usage.CompletionTokens
+					generationTime := (result.ResponseTime - result.FirstTokenTime).Seconds()
+					if !stream || generationTime <= 0 {
+						generationTime = result.ResponseTime.Seconds()
+					}
+					if generationTime > 0 {
+						test.TokensPerSecond = float64(result.Usage.CompletionTokens) / generationTime
+					}
+					err = checkChannelTestAssertions(testCase, result.Output)
+				}
+				test.Success = err == nil
+				if err != nil {
+					test.Message = err.Error()
+				}
+				err = model.RecordChannelTests([]*model.ChannelTest{test})
+				if err != nil {
+					logger.SysError(fmt.Sprintf("failed to record tests of channel #%d: %s", channel.Id, err.Error()))
+				}
+				time.Sleep(config.RequestInterval)
+			}
+		}
+	}
+	err := model.PruneChannelTests(channel.Id)
+	if err != nil {
+		logger.SysError(fmt.Sprintf("failed to prune tests of channel #%d: %s", channel.Id, err.Error()))
+	}
 }
 
 func TestChannel(c *gin.Context) {
@@ -151,7 +332,7 @@ func TestChannel(c *gin.Context) {
 var testAllChannelsLock sync.Mutex
 var testAllChannelsRunning bool = false
 
-func testChannels(notify bool, scope string) error {
+func testChannels(notify bool, scope string, tag string) error {
 	if config.RootUserEmail == "" {
 		config.RootUserEmail = model.GetRootUserEmail()
 	}
@@ -162,8 +343,17 @@ func testChannels(notify bool, scope string) error {
 	}
 	testAllChannelsRunning = true
 	testAllChannelsLock.Unlock()
-	channels, err := model.GetAllChannels(0, 0, scope)
+	var channels []*model.Channel
+	var err error
+	if tag != "" {
+		channels, err = model.GetChannelsByTag(tag, true)
+	} else {
+		channels, err = model.GetAllChannels(0, 0, scope)
+	}
 	if err != nil {
+		testAllChannelsLock.Lock()
+		testAllChannelsRunning = false
+		testAllChannelsLock.Unlock()
 		return err
 	}
 	var disableThreshold = int64(config.ChannelDisableThreshold * 1000)
@@ -212,7 +402,8 @@ func TestChannels(c *gin.Context) {
 	if scope == "" {
 		scope = "all"
 	}
-	err := testChannels(true, scope)
+	tag := strings.TrimSpace(c.Query("tag"))
+	err := testChannels(true, scope, tag)
 	if err != nil {
 		c.JSON(http.StatusOK, gin.H{
 			"success": false,
@@ -231,7 +422,78 @@ func AutomaticallyTestChannels(frequency int) {
 	for {
 		time.Sleep(time.Duration(frequency) * time.Minute)
 		logger.SysLog("testing all channels")
-		_ = testChannels(false, "all")
+		_ = testChannels(false, "all", "")
 		logger.SysLog("channel test finished")
 	}
 }
+
+// TestChannelFully starts the full test suite against a channel and returns its run id at once,
+// the results are listed by GetChannelTestHistory with the run_id query as they come in
+func TestChannelFully(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+	channel, err := model.GetChannelById(id, true)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+	runId := helperpkg.GetUUID()
+	common.SafeGoroutine(func() {
+		runFullChannelTest(channel, runId)
+	})
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+		"data": gin.H{
+			"run_id": runId,
+		},
+	})
+	return
+}
+
+func GetChannelTestHistory(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+	page, _ := strconv.Atoi(c.Query("page"))
+	if page < 1 {
+		page = 1
+	}
+	pageSize, _ := strconv.Atoi(c.Query("pagesize"))
+	if pageSize <= 0 {
+		pageSize = config.ItemsPerPage
+	}
+	tests, total, err := model.GetChannelTestsAndCount(id, c.Query("run_id"), page, pageSize)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+		"data": gin.H{
+			"list":        tests,
+			"currentPage": page,
+			"pageSize":    pageSize,
+			"total":       total,
+		},
+	})
+	return
+}
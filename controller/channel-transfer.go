@@ -0,0 +1,441 @@
+package controller
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/songquanpeng/one-api/common"
+	"github.com/songquanpeng/one-api/common/helper"
+	"github.com/songquanpeng/one-api/common/logger"
+	"github.com/songquanpeng/one-api/model"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	ChannelKeyModePlain   = "plain"
+	ChannelKeyModeMask    = "mask"
+	ChannelKeyModeEncrypt = "encrypt"
+)
+
+const channelTransferVersion = 1
+
+type ChannelTransferItem struct {
+	Name         string `json:"name" yaml:"name"`
+	Type         int    `json:"type" yaml:"type"`
+	Key          string `json:"key" yaml:"key"`
+	Status       int    `json:"status" yaml:"status"`
+	Weight       uint   `json:"weight" yaml:"weight"`
+	Priority     int64  `json:"priority" yaml:"priority"`
+	BaseURL      string `json:"base_url,omitempty" yaml:"base_url,omitempty"`
+	Other        string `json:"other,omitempty" yaml:"other,omitempty"`
+	Models       string `json:"models" yaml:"models"`
+	Group        string `json:"group" yaml:"group"`
+	ModelMapping string `json:"model_mapping,omitempty" yaml:"model_mapping,omitempty"`
+	Config       string `json:"config,omitempty" yaml:"config,omitempty"`
+	Tags         string `json:"tags,omitempty" yaml:"tags,omitempty"`
+}
+
+type ChannelTransferFile struct {
+	Version  int                   `json:"version" yaml:"version"`
+	KeyMode  string                `json:"key_mode" yaml:"key_mode"`
+	Channels []ChannelTransferItem `json:"channels" yaml:"channels"`
+}
+
+type ChannelImportOptions struct {
+	Passphrase         string
+	AllowUnknownModels bool
+	DryRun             bool
+}
+
+type ChannelImportResult struct {
+	Created int `json:"created"`
+	Updated int `json:"updated"`
+	Total   int `json:"total"`
+}
+
+func isYAMLFormat(format string) bool {
+	format = strings.ToLower(format)
+	return format == "yaml" || format == "yml"
+}
+
+func channelTransferFormatFromPath(path string) string {
+	ext := strings.TrimPrefix(filepath.Ext(path), ".")
+	if isYAMLFormat(ext) {
+		return "yaml"
+	}
+	return "json"
+}
+
+func channel2TransferItem(channel *model.Channel, keyMode string, passphrase string) (*ChannelTransferItem, error) {
+	item := &ChannelTransferItem{
+		Name:     channel.Name,
+		Type:     channel.Type,
+		Status:   channel.Status,
+		Weight:   *channel.GetWeight(),
+		Priority: channel.GetPriority(),
+		BaseURL:  channel.GetBaseURL(),
+		Other:    channel.Other,
+		Models:   channel.Models,
+		Group:    channel.Group,
+		Config:   channel.Config,
+		Tags:     channel.Tags,
+	}
+	if channel.ModelMapping != nil {
+		item.ModelMapping = *channel.ModelMapping
+	}
+	plainKey, err := channel.DecryptKey()
+	if err != nil {
+		return nil, fmt.Errorf("channel %s: %w", channel.Name, err)
+	}
+	plainConfig, err := channel.DecryptConfig()
+	if err != nil {
+		return nil, fmt.Errorf("channel %s: %w", channel.Name, err)
+	}
+	// the secret config entries are exported like the key
+	switch keyMode {
+	case ChannelKeyModePlain:
+		item.Key = plainKey
+		item.Config = plainConfig
+	case ChannelKeyModeMask:
+		item.Key = helper.MaskSecret(plainKey)
+		item.Config, _ = model.MapChannelConfigSecrets(plainConfig, func(string) (string, error) {
+			return model.MaskedChannelKey, nil
+		})
+	case ChannelKeyModeEncrypt:
+		key, err := common.EncryptWithPassphrase(plainKey, passphrase)
+		if err != nil {
+			return nil, err
+		}
+		item.Key = key
+		item.Config, err = model.MapChannelConfigSecrets(plainConfig, func(value string) (string, error) {
+			return common.EncryptWithPassphrase(value, passphrase)
+		})
+		if err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("invalid key mode: %s", keyMode)
+	}
+	return item, nil
+}
+
+func transferItem2Channel(item *ChannelTransferItem, keyMode string, passphrase string) (*model.Channel, error) {
+	weight := item.Weight
+	priority := item.Priority
+	baseURL := item.BaseURL
+	modelMapping := item.ModelMapping
+	channel := &model.Channel{
+		Name:         item.Name,
+		Type:         item.Type,
+		Status:       item.Status,
+		Weight:       &weight,
+		Priority:     &priority,
+		BaseURL:      &baseURL,
+		Other:        item.Other,
+		Models:       trimModelNames(item.Models),
+		Group:        item.Group,
+		ModelMapping: &modelMapping,
+		Config:       item.Config,
+		Tags:         strings.Join(model.NormalizeChannelTags(item.Tags), ","),
+	}
+	if channel.Status == 0 {
+		channel.Status = common.ChannelStatusEnabled
+	}
+	if channel.Group == "" {
+		channel.Group = "default"
+	}
+	switch keyMode {
+	case ChannelKeyModePlain:
+		channel.Key = item.Key
+	case ChannelKeyModeMask:
+		// masked keys are useless, existing channels keep their stored key and secret config entries
+		channel.Key = ""
+	case ChannelKeyModeEncrypt:
+		if item.Key != "" {
+			key, err := common.DecryptWithPassphrase(item.Key, passphrase)
+			if err != nil {
+				return nil, fmt.Errorf("channel %s: %s", item.Name, err.Error())
+			}
+			channel.Key = key
+		}
+		channelConfig, err := model.MapChannelConfigSecrets(item.Config, func(value string) (string, error) {
+			return common.DecryptWithPassphrase(value, passphrase)
+		})
+		if err != nil {
+			return nil, fmt.Errorf("channel %s: %s", item.Name, err.Error())
+		}
+		channel.Config = channelConfig
+	default:
+		return nil, fmt.Errorf("invalid key mode: %s", keyMode)
+	}
+	return channel, nil
+}
+
+// trimModelNames drops the spaces and empty entries of a comma separated model list, abilities are keyed by the exact names
+func trimModelNames(models string) string {
+	names := make([]string, 0)
+	for _, modelName := range strings.Split(models, ",") {
+		modelName = strings.TrimSpace(modelName)
+		if modelName != "" {
+			names = append(names, modelName)
+		}
+	}
+	return strings.Join(names, ",")
+}
+
+// validateChannelModels checks the models of a channel against the model list of its adaptor,
+// channel types whose adaptor does not declare any model are not checked
+func validateChannelModels(channel *model.Channel) error {
+	supportedModels := channelId2Models[channel.Type]
+	if len(supportedModels) == 0 {
+		return nil
+	}
+	supported := make(map[string]bool, len(supportedModels))
+	for _, modelName := range supportedModels {
+		supported[modelName] = true
+	}
+	modelMapping := channel.GetModelMapping()
+	unknownModels := make([]string, 0)
+	for _, modelName := range strings.Split(channel.Models, ",") {
+		modelName = strings.TrimSpace(modelName)
+		if modelName == "" {
+			continue
+		}
+		actualModelName := modelName
+		if mapped, ok := modelMapping[modelName]; ok {
+			actualModelName = mapped
+		}
+		if !supported[actualModelName] {
+			unknownModels = append(unknownModels, modelName)
+		}
+	}
+	if len(unknownModels) != 0 {
+		return fmt.Errorf("channel %s: models not supported by channel type %d: %s", channel.Name, channel.Type, strings.Join(unknownModels, ","))
+	}
+	return nil
+}
+
+func ExportChannels(channels []*model.Channel, format string, keyMode string, passphrase string) ([]byte, error) {
+	if keyMode == ChannelKeyModeEncrypt && passphrase == "" {
+		return nil, errors.New("passphrase is required to encrypt keys")
+	}
+	file := ChannelTransferFile{
+		Version:  channelTransferVersion,
+		KeyMode:  keyMode,
+		Channels: make([]ChannelTransferItem, 0, len(channels)),
+	}
+	for _, channel := range channels {
+		item, err := channel2TransferItem(channel, keyMode, passphrase)
+		if err != nil {
+			return nil, err
+		}
+		file.Channels = append(file.Channels, *item)
+	}
+	if isYAMLFormat(format) {
+		return yaml.Marshal(&file)
+	}
+	return json.MarshalIndent(&file, "", "  ")
+}
+
+func ImportChannels(data []byte, format string, options ChannelImportOptions) (*ChannelImportResult, error) {
+	var file ChannelTransferFile
+	var err error
+	if isYAMLFormat(format) {
+		err = yaml.Unmarshal(data, &file)
+	} else {
+		err = json.Unmarshal(data, &file)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if file.Version > channelTransferVersion {
+		return nil, fmt.Errorf("unsupported file version: %d", file.Version)
+	}
+	if file.KeyMode == "" {
+		file.KeyMode = ChannelKeyModePlain
+	}
+	if file.KeyMode == ChannelKeyModeEncrypt && options.Passphrase == "" {
+		return nil, errors.New("passphrase is required to decrypt keys")
+	}
+	names := make(map[string]bool)
+	channels := make([]*model.Channel, 0, len(file.Channels))
+	for i := range file.Channels {
+		item := &file.Channels[i]
+		if item.Name == "" {
+			return nil, fmt.Errorf("channel #%d has no name", i+1)
+		}
+		if names[item.Name] {
+			return nil, fmt.Errorf("duplicated channel name: %s", item.Name)
+		}
+		names[item.Name] = true
+		if item.Type <= common.ChannelTypeUnknown || item.Type >= common.ChannelTypeDummy {
+			return nil, fmt.Errorf("channel %s: invalid type %d", item.Name, item.Type)
+		}
+		if strings.TrimSpace(item.Models) == "" {
+			return nil, fmt.Errorf("channel %s: models cannot be empty", item.Name)
+		}
+		channel, err := transferItem2Channel(item, file.KeyMode, options.Passphrase)
+		if err != nil {
+			return nil, err
+		}
+		if !options.AllowUnknownModels {
+			if err := validateChannelModels(channel); err != nil {
+				return nil, err
+			}
+		}
+		channels = append(channels, channel)
+	}
+	result := &ChannelImportResult{Total: len(channels)}
+	if options.DryRun {
+		return result, nil
+	}
+	result.Created, result.Updated, err = model.UpsertChannelsByName(channels)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func ExportChannelsHandler(c *gin.Context) {
+	var request struct {
+		Format     string `json:"format"`
+		KeyMode    string `json:"key_mode"`
+		Passphrase string `json:"passphrase"`
+		Tag        string `json:"tag"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+	if request.KeyMode == "" {
+		request.KeyMode = ChannelKeyModeMask
+	}
+	setAuditChange(c, "channel.export", "channel", request.Tag, nil, map[string]interface{}{
+		"format":   request.Format,
+		"key_mode": request.KeyMode,
+		"tag":      request.Tag,
+	})
+	if request.KeyMode == ChannelKeyModePlain && !model.UserHasPermission(c.GetInt("id"), model.PermissionChannelKeyExport) {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": fmt.Sprintf("exporting plain keys requires the %s permission", model.PermissionChannelKeyExport),
+		})
+		return
+	}
+	var channels []*model.Channel
+	var err error
+	if request.Tag != "" {
+		channels, err = model.GetChannelsByTag(request.Tag, true)
+	} else {
+		channels, err = model.GetAllChannels(0, 0, "all")
+	}
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+	data, err := ExportChannels(channels, request.Format, request.KeyMode, request.Passphrase)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+	fileName := "channels.json"
+	contentType := "application/json"
+	if isYAMLFormat(request.Format) {
+		fileName = "channels.yaml"
+		contentType = "application/yaml"
+	}
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%s", fileName))
+	c.Data(http.StatusOK, contentType, data)
+}
+
+func ImportChannelsHandler(c *gin.Context) {
+	var request struct {
+		Format             string `json:"format"`
+		Data               string `json:"data"`
+		Passphrase         string `json:"passphrase"`
+		AllowUnknownModels bool   `json:"allow_unknown_models"`
+		DryRun             bool   `json:"dry_run"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+	// the imported data holds the channel keys, only the options are recorded
+	auditAfter := map[string]interface{}{
+		"format":               request.Format,
+		"allow_unknown_models": request.AllowUnknownModels,
+		"dry_run":              request.DryRun,
+	}
+	setAuditChange(c, "channel.import", "channel", "", nil, auditAfter)
+	result, err := ImportChannels([]byte(request.Data), request.Format, ChannelImportOptions{
+		Passphrase:         request.Passphrase,
+		AllowUnknownModels: request.AllowUnknownModels,
+		DryRun:             request.DryRun,
+	})
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+	if !request.DryRun {
+		model.InitChannelCache()
+	}
+	auditAfter["created"], auditAfter["updated"], auditAfter["total"] = result.Created, result.Updated, result.Total
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+		"data":    result,
+	})
+}
+
+// ExportChannelsToFile is the command line counterpart of ExportChannelsHandler
+func ExportChannelsToFile(path string, keyMode string, passphrase string) error {
+	channels, err := model.GetAllChannels(0, 0, "all")
+	if err != nil {
+		return err
+	}
+	data, err := ExportChannels(channels, channelTransferFormatFromPath(path), keyMode, passphrase)
+	if err != nil {
+		return err
+	}
+	err = os.WriteFile(path, data, 0600)
+	if err != nil {
+		return err
+	}
+	logger.SysLog(fmt.Sprintf("exported %d channels to %s", len(channels), path))
+	return nil
+}
+
+// ImportChannelsFromFile is the command line counterpart of ImportChannelsHandler
+func ImportChannelsFromFile(path string, options ChannelImportOptions) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	result, err := ImportChannels(data, channelTransferFormatFromPath(path), options)
+	if err != nil {
+		return err
+	}
+	logger.SysLog(fmt.Sprintf("imported %d channels from %s, %d created, %d updated", result.Total, path, result.Created, result.Updated))
+	return nil
+}
@@ -1,13 +1,16 @@
 package controller
 
 import (
+	"fmt"
 	"net/http"
 	"strconv"
 	"strings"
 
 	"github.com/gin-gonic/gin"
+	"github.com/songquanpeng/one-api/common"
 	"github.com/songquanpeng/one-api/common/helper"
 	"github.com/songquanpeng/one-api/model"
+	"github.com/songquanpeng/one-api/relay/util"
 )
 
 func GetAllChannels(c *gin.Context) {
@@ -25,6 +28,9 @@ func GetAllChannels(c *gin.Context) {
 		})
 		return
 	}
+	for _, channel := range channels {
+		channel.MaskSecrets()
+	}
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
 		"message": "",
@@ -71,6 +77,9 @@ func SearchChannels(c *gin.Context) {
 		})
 		return
 	}
+	for _, channel := range channels {
+		channel.MaskSecrets()
+	}
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
 		"message": "",
@@ -93,7 +102,7 @@ func GetChannel(c *gin.Context) {
 		})
 		return
 	}
-	channel, err := model.GetChannelById(id, false)
+	channel, err := model.GetChannelById(id, true)
 	if err != nil {
 		c.JSON(http.StatusOK, gin.H{
 			"success": false,
@@ -101,6 +110,7 @@ func GetChannel(c *gin.Context) {
 		})
 		return
 	}
+	channel.MaskSecrets()
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
 		"message": "",
@@ -109,6 +119,21 @@ func GetChannel(c *gin.Context) {
 	return
 }
 
+// validateChannel checks the settings of the channel which are not validated by the database
+func validateChannel(channel *model.Channel) error {
+	if _, err := model.ParseChannelSchedule(channel.GetSchedule()); err != nil {
+		return err
+	}
+	cfg, err := channel.LoadConfig()
+	if err != nil {
+		return fmt.Errorf("invalid config: %w", err)
+	}
+	if _, err := util.NewTransport(util.NewTransportOptions(cfg)); err != nil {
+		return err
+	}
+	return nil
+}
+
 func AddChannel(c *gin.Context) {
 	channel := model.Channel{}
 	err := c.ShouldBindJSON(&channel)
@@ -119,7 +144,15 @@ func AddChannel(c *gin.Context) {
 		})
 		return
 	}
+	if err := validateChannel(&channel); err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
 	channel.CreatedTime = helper.GetTimestamp()
+	channel.Managed = false
 	keys := strings.Split(channel.Key, "\n")
 	channels := make([]model.Channel, 0, len(keys))
 	for _, key := range keys {
@@ -138,6 +171,16 @@ func AddChannel(c *gin.Context) {
 		})
 		return
 	}
+	ids := make([]string, 0, len(channels))
+	for _, created := range channels {
+		ids = append(ids, strconv.Itoa(created.Id))
+	}
+	if len(channels) > 0 {
+		// the channels only differ by their key, which is not recorded
+		created, _ := model.GetChannelById(channels[0].Id, false)
+		created.MaskSecrets()
+		setAuditChange(c, "channel.create", "channel", strings.Join(ids, ","), nil, created)
+	}
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
 		"message": "",
@@ -147,8 +190,19 @@ func AddChannel(c *gin.Context) {
 
 func DeleteChannel(c *gin.Context) {
 	id, _ := strconv.Atoi(c.Param("id"))
+	if err := model.CheckChannelsNotManaged([]int{id}); err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+	before, err := model.GetChannelById(id, false)
+	if err == nil {
+		before.MaskSecrets()
+	}
 	channel := model.Channel{Id: id}
-	err := channel.Delete()
+	err = channel.Delete()
 	if err != nil {
 		c.JSON(http.StatusOK, gin.H{
 			"success": false,
@@ -156,6 +210,7 @@ func DeleteChannel(c *gin.Context) {
 		})
 		return
 	}
+	setAuditChange(c, "channel.delete", "channel", id, before, nil)
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
 		"message": "",
@@ -182,6 +237,13 @@ func BatchDelteChannel(c *gin.Context) {
 		})
 		return
 	}
+	if err := model.CheckChannelsNotManaged(request.Ids); err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
 	err := model.BatchDeleteChannel(request.Ids)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
@@ -190,6 +252,8 @@ func BatchDelteChannel(c *gin.Context) {
 		})
 		return
 	}
+	setAuditChange(c, "channel.batch_delete", "channel", strings.Trim(fmt.Sprint(request.Ids), "[]"),
+		map[string]interface{}{"ids": request.Ids}, nil)
 	// 返回成功响应
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
@@ -206,6 +270,7 @@ func DeleteDisabledChannel(c *gin.Context) {
 		})
 		return
 	}
+	setAuditChange(c, "channel.delete_disabled", "channel", "", map[string]interface{}{"deleted": rows}, nil)
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
 		"message": "",
@@ -224,6 +289,28 @@ func UpdateChannel(c *gin.Context) {
 		})
 		return
 	}
+	if err := model.CheckChannelsNotManaged([]int{channel.Id}); err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+	// the masked secret config entries returned by GetChannel are kept unchanged
+	err = channel.RestoreMaskedConfig()
+	if err == nil {
+		err = validateChannel(&channel)
+	}
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+	channel.Managed = false
+	keyChanged := channel.Key != "" && channel.Key != model.MaskedChannelKey
+	beforeChannel, _ := model.GetChannelById(channel.Id, false)
 	err = channel.Update()
 	if err != nil {
 		c.JSON(http.StatusOK, gin.H{
@@ -232,6 +319,17 @@ func UpdateChannel(c *gin.Context) {
 		})
 		return
 	}
+	if afterChannel, err := model.GetChannelById(channel.Id, false); err == nil {
+		beforeChannel.MaskSecrets()
+		afterChannel.MaskSecrets()
+		before, after := model.AuditSnapshot(beforeChannel), model.AuditSnapshot(afterChannel)
+		if keyChanged {
+			// keys are not loaded, this only records that it changed, the values are redacted
+			before["key"], after["key"] = "old", "new"
+		}
+		setAuditChange(c, "channel.update", "channel", channel.Id, before, after)
+	}
+	channel.MaskSecrets()
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
 		"message": "",
@@ -239,3 +337,105 @@ func UpdateChannel(c *gin.Context) {
 	})
 	return
 }
+
+func GetChannelTags(c *gin.Context) {
+	tags, err := model.GetAllChannelTags()
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+		"data":    tags,
+	})
+	return
+}
+
+func GetChannelsByTag(c *gin.Context) {
+	tag := strings.TrimSpace(c.Query("tag"))
+	if tag == "" {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": "tag is required",
+		})
+		return
+	}
+	channels, err := model.GetChannelsByTag(tag, false)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+	for _, channel := range channels {
+		channel.MaskSecrets()
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+		"data":    channels,
+	})
+	return
+}
+
+func UpdateChannelsByTag(c *gin.Context) {
+	var request struct {
+		Tag string `json:"tag"`
+		model.ChannelTagUpdate
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+	request.Tag = strings.TrimSpace(request.Tag)
+	if request.Tag == "" {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": "tag is required",
+		})
+		return
+	}
+	if request.Models != nil && strings.TrimSpace(*request.Models) == "" {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": "models cannot be empty",
+		})
+		return
+	}
+	if request.Group != nil && strings.TrimSpace(*request.Group) == "" {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": "group cannot be empty",
+		})
+		return
+	}
+	if request.Status != nil && *request.Status != common.ChannelStatusEnabled && *request.Status != common.ChannelStatusManuallyDisabled {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": "invalid status",
+		})
+		return
+	}
+	rows, err := model.BatchUpdateChannelsByTag(request.Tag, &request.ChannelTagUpdate)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+		"data":    rows,
+	})
+	return
+}
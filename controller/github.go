@@ -121,8 +121,7 @@ func GithubOAuthCallback(c *gin.Context) {
 		})
 		return
 	}
-	username := session.Get("username")
-	if username != nil {
+	if isLoggedIn(c) {
 		GitHubBind(c)
 		return
 	}
@@ -62,8 +62,7 @@ func GoogleOAuthCallback(c *gin.Context) {
 		})
 		return
 	}
-	username := session.Get("username")
-	if username != nil {
+	if isLoggedIn(c) {
 		GoogleBind(c)
 		return
 	}
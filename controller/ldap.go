@@ -0,0 +1,220 @@
+package controller
+
+import (
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-ldap/ldap/v3"
+	"github.com/songquanpeng/one-api/common"
+	"github.com/songquanpeng/one-api/common/config"
+	"github.com/songquanpeng/one-api/common/logger"
+	"github.com/songquanpeng/one-api/model"
+)
+
+const ldapTimeout = 10 * time.Second
+
+// usernames are put into DNs and filters, only the characters found in account names are accepted
+var ldapUsernamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._@-]*$`)
+
+type LdapUser struct {
+	Username    string
+	DisplayName string
+	Email       string
+	Groups      []string // DNs of the groups and the values of their first RDN
+}
+
+func dialLdap() (*ldap.Conn, error) {
+	conn, err := ldap.DialURL(config.LdapUrl, ldap.DialWithDialer(&net.Dialer{Timeout: ldapTimeout}))
+	if err != nil {
+		return nil, err
+	}
+	conn.SetTimeout(ldapTimeout)
+	if config.LdapStartTlsEnabled {
+		serverName := ""
+		if u, err := url.Parse(config.LdapUrl); err == nil {
+			serverName = u.Hostname()
+		}
+		err = conn.StartTLS(&tls.Config{ServerName: serverName})
+		if err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+	return conn, nil
+}
+
+// ldapGroups returns the groups of the entry as DNs, and also as the value of their first RDN when enabled.
+// Short names are ambiguous, cn=admins,ou=a and cn=admins,ou=b are the same group once shortened.
+func ldapGroups(values []string) []string {
+	groups := make([]string, 0, len(values)*2)
+	for _, value := range values {
+		groups = append(groups, value)
+		if !config.LdapGroupShortNameEnabled {
+			continue
+		}
+		dn, err := ldap.ParseDN(value)
+		if err == nil && len(dn.RDNs) > 0 && len(dn.RDNs[0].Attributes) > 0 {
+			groups = append(groups, dn.RDNs[0].Attributes[0].Value)
+		}
+	}
+	return groups
+}
+
+// authenticateLdapUser binds as the user and reads the attributes of its entry
+func authenticateLdapUser(username string, password string) (*LdapUser, error) {
+	if !ldapUsernamePattern.MatchString(username) || password == "" {
+		return nil, errors.New("invalid username or password")
+	}
+	conn, err := dialLdap()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	userDn := strings.ReplaceAll(config.LdapUserDn, "%s", username)
+	err = conn.Bind(userDn, password)
+	if err != nil {
+		return nil, err
+	}
+	ldapUser := &LdapUser{Username: username}
+	var request *ldap.SearchRequest
+	attributes := []string{config.LdapEmailAttribute, config.LdapDisplayNameAttribute, config.LdapGroupAttribute}
+	if config.LdapBaseDn != "" {
+		filter := strings.ReplaceAll(config.LdapUserFilter, "%s", ldap.EscapeFilter(username))
+		request = ldap.NewSearchRequest(config.LdapBaseDn, ldap.ScopeWholeSubtree, ldap.NeverDerefAliases, 2, int(ldapTimeout.Seconds()), false, filter, attributes, nil)
+	} else if strings.Contains(userDn, "=") {
+		request = ldap.NewSearchRequest(userDn, ldap.ScopeBaseObject, ldap.NeverDerefAliases, 1, int(ldapTimeout.Seconds()), false, "(objectClass=*)", attributes, nil)
+	} else {
+		// without a base DN the entry of a user bound by its user principal name cannot be found
+		return ldapUser, nil
+	}
+	result, err := conn.Search(request)
+	if err != nil {
+		return nil, err
+	}
+	if len(result.Entries) != 1 {
+		return nil, fmt.Errorf("expected one entry for the user, found %d", len(result.Entries))
+	}
+	entry := result.Entries[0]
+	ldapUser.Email = entry.GetAttributeValue(config.LdapEmailAttribute)
+	ldapUser.DisplayName = entry.GetAttributeValue(config.LdapDisplayNameAttribute)
+	ldapUser.Groups = ldapGroups(entry.GetAttributeValues(config.LdapGroupAttribute))
+	return ldapUser, nil
+}
+
+// ldapUsername returns the LDAP username if it can be used as it is, or a generated one
+func ldapUsername(username string) string {
+	if len(username) <= 12 && !model.IsUsernameAlreadyTaken(username) {
+		return username
+	}
+	return "ldap" + strconv.Itoa(model.GetMaxUserId()+1)
+}
+
+// LdapLogin logs in with the LDAP credentials of the user, the user is created on its first login
+// and its email, display name, role and group are synced from LDAP on every login
+func LdapLogin(c *gin.Context) {
+	if !config.LdapEnabled {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": "管理员未开启通过 LDAP 登录",
+		})
+		return
+	}
+	var loginRequest LoginRequest
+	err := json.NewDecoder(c.Request.Body).Decode(&loginRequest)
+	if err != nil || loginRequest.Username == "" || loginRequest.Password == "" {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": "无效的参数",
+		})
+		return
+	}
+	ldapUser, err := authenticateLdapUser(loginRequest.Username, loginRequest.Password)
+	if err != nil {
+		logger.SysLog(fmt.Sprintf("LDAP login of %s failed: %s", loginRequest.Username, err.Error()))
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": "用户名或密码错误，或用户已被封禁",
+		})
+		return
+	}
+	user := model.User{
+		LdapId: strings.ToLower(ldapUser.Username),
+	}
+	if model.IsLdapIdAlreadyTaken(user.LdapId) {
+		err := user.FillUserByLdapId()
+		if err != nil {
+			c.JSON(http.StatusOK, gin.H{
+				"success": false,
+				"message": err.Error(),
+			})
+			return
+		}
+	} else {
+		if !config.RegisterEnabled {
+			c.JSON(http.StatusOK, gin.H{
+				"success": false,
+				"message": "管理员关闭了新用户注册",
+			})
+			return
+		}
+		user.Username = ldapUsername(ldapUser.Username)
+		user.DisplayName = user.Username
+		user.Role = common.RoleCommonUser
+		user.Status = common.UserStatusEnabled
+		if err := user.Insert(0); err != nil {
+			c.JSON(http.StatusOK, gin.H{
+				"success": false,
+				"message": err.Error(),
+			})
+			return
+		}
+	}
+	if user.Status != common.UserStatusEnabled {
+		c.JSON(http.StatusOK, gin.H{
+			"message": "用户已被封禁",
+			"success": false,
+		})
+		return
+	}
+	displayName := ldapUser.DisplayName
+	if utf8.RuneCountInString(displayName) > 20 {
+		displayName = ""
+	}
+	if (ldapUser.Email != "" && ldapUser.Email != user.Email) || (displayName != "" && displayName != user.DisplayName) {
+		updatedUser := model.User{
+			Id:          user.Id,
+			Email:       ldapUser.Email,
+			DisplayName: displayName,
+		}
+		err = updatedUser.Update(false)
+		if err != nil {
+			c.JSON(http.StatusOK, gin.H{
+				"success": false,
+				"message": err.Error(),
+			})
+			return
+		}
+		if ldapUser.Email != "" {
+			user.Email = ldapUser.Email
+		}
+		if displayName != "" {
+			user.DisplayName = displayName
+		}
+	}
+	err = user.SyncExternalRoleAndGroup(ldapUser.Groups, config.LdapRoleMapping, config.LdapGroupMapping)
+	if err != nil {
+		logger.SysError(fmt.Sprintf("failed to sync the role and group of user #%d from LDAP: %s", user.Id, err.Error()))
+	}
+	setupLogin(&user, c)
+}
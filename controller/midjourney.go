@@ -134,8 +134,21 @@ func UpdateMidjourneyTaskBulk() {
 			// 使用带有超时的 context 创建新的请求
 			req = req.WithContext(ctx)
 			req.Header.Set("Content-Type", "application/json")
-			req.Header.Set("mj-api-secret", midjourneyChannel.Key)
-			resp, err := util.GetHttpClient().Do(req)
+			key, err := midjourneyChannel.DecryptKey()
+			if err != nil {
+				logger.Error(ctx, fmt.Sprintf("Get Task channel key error: %v", err))
+				cancel()
+				continue
+			}
+			req.Header.Set("mj-api-secret", key)
+			cfg, _ := midjourneyChannel.LoadConfig()
+			client, err := util.GetChannelHTTPClient(util.NewTransportOptions(cfg))
+			if err != nil {
+				logger.Error(ctx, fmt.Sprintf("Get Task http client error: %v", err))
+				cancel()
+				continue
+			}
+			resp, err := client.Do(req)
 			if err != nil {
 				logger.Error(ctx, fmt.Sprintf("Get Task Do req error: %v", err))
 				continue
@@ -199,12 +212,20 @@ func UpdateMidjourneyTaskBulk() {
 					} else {
 						quota := task.Quota
 						if quota != 0 {
-							err = model.IncreaseUserQuota(task.UserId, quota)
-							if err != nil {
-								logger.Error(ctx, "fail to increase user quota: "+err.Error())
-							}
 							logContent := fmt.Sprintf("构图失败 %s，补偿 %s", task.MjId, common.LogQuota(quota))
-							model.RecordLog(task.UserId, model.LogTypeSystem, logContent)
+							if task.OrganizationId != 0 {
+								err = model.IncreaseOrganizationQuota(task.OrganizationId, quota)
+								if err != nil {
+									logger.Error(ctx, "fail to increase organization quota: "+err.Error())
+								}
+								model.RecordOrganizationLog(task.OrganizationId, task.UserId, model.LogTypeSystem, logContent)
+							} else {
+								err = model.IncreaseUserQuota(task.UserId, quota)
+								if err != nil {
+									logger.Error(ctx, "fail to increase user quota: "+err.Error())
+								}
+								model.RecordLog(task.UserId, model.LogTypeSystem, logContent)
+							}
 						}
 					}
 				}
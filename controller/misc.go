@@ -27,6 +27,8 @@ func GetStatus(c *gin.Context) {
 			"github_client_id":    config.GitHubClientId,
 			"google_client_id":    config.GoogleClientId,
 			"google_redirect_uri": config.GoogleRedirectUri,
+			"oidc_login":          config.OidcEnabled,
+			"ldap_login":          config.LdapEnabled,
 			"github_redirect_uri": config.GithubRedirectUri,
 			"system_name":         config.SystemName,
 			"logo":                config.Logo,
@@ -7,6 +7,7 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/songquanpeng/one-api/common"
+	dbmodel "github.com/songquanpeng/one-api/model"
 	"github.com/songquanpeng/one-api/relay/channel/openai"
 	"github.com/songquanpeng/one-api/relay/constant"
 	"github.com/songquanpeng/one-api/relay/helper"
@@ -133,14 +134,25 @@ func DashboardListModels(c *gin.Context) {
 }
 
 func ListModels(c *gin.Context) {
+	tokenModels, tokenDeniedModels := c.GetString("token_models"), c.GetString("token_denied_models")
+	models := make([]OpenAIModels, 0, len(openAIModels))
+	for _, model := range openAIModels {
+		if dbmodel.IsModelAllowedForToken(tokenModels, tokenDeniedModels, model.Id) {
+			models = append(models, model)
+		}
+	}
 	c.JSON(200, gin.H{
 		"object": "list",
-		"data":   openAIModels,
+		"data":   models,
 	})
 }
 func RetrieveModel(c *gin.Context) {
 	modelId := c.Param("model")
-	if model, ok := openAIModelsMap[modelId]; ok {
+	model, ok := openAIModelsMap[modelId]
+	if ok && !dbmodel.IsModelAllowedForToken(c.GetString("token_models"), c.GetString("token_denied_models"), modelId) {
+		ok = false
+	}
+	if ok {
 		c.JSON(200, model)
 	} else {
 		Error := relaymodel.Error{
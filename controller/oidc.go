@@ -0,0 +1,473 @@
+package controller
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-contrib/sessions"
+	"github.com/gin-gonic/gin"
+	"github.com/songquanpeng/one-api/common"
+	"github.com/songquanpeng/one-api/common/config"
+	"github.com/songquanpeng/one-api/common/helper"
+	"github.com/songquanpeng/one-api/common/logger"
+	"github.com/songquanpeng/one-api/model"
+)
+
+type OidcDiscovery struct {
+	Issuer                            string   `json:"issuer"`
+	AuthorizationEndpoint             string   `json:"authorization_endpoint"`
+	TokenEndpoint                     string   `json:"token_endpoint"`
+	UserinfoEndpoint                  string   `json:"userinfo_endpoint"`
+	TokenEndpointAuthMethodsSupported []string `json:"token_endpoint_auth_methods_supported"`
+}
+
+type OidcTokenResult struct {
+	AccessToken string `json:"access_token"`
+	IdToken     string `json:"id_token"`
+	TokenType   string `json:"token_type"`
+}
+
+// the discovery document is fetched again once it expired or the discovery URL changed
+const oidcDiscoveryTTL = time.Hour
+
+var oidcDiscoveryCache = struct {
+	sync.Mutex
+	url       string
+	discovery *OidcDiscovery
+	expireAt  time.Time
+}{}
+
+var oidcHttpClient = &http.Client{Timeout: 10 * time.Second}
+
+func oidcDiscoveryUrl() string {
+	discoveryUrl := strings.TrimSpace(config.OidcDiscoveryUrl)
+	if strings.Contains(discoveryUrl, "/.well-known/") {
+		return discoveryUrl
+	}
+	return strings.TrimRight(discoveryUrl, "/") + "/.well-known/openid-configuration"
+}
+
+func getOidcDiscovery() (*OidcDiscovery, error) {
+	discoveryUrl := oidcDiscoveryUrl()
+	oidcDiscoveryCache.Lock()
+	defer oidcDiscoveryCache.Unlock()
+	if oidcDiscoveryCache.discovery != nil && oidcDiscoveryCache.url == discoveryUrl && time.Now().Before(oidcDiscoveryCache.expireAt) {
+		return oidcDiscoveryCache.discovery, nil
+	}
+	response, err := oidcHttpClient.Get(discoveryUrl)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to get the OIDC discovery document: %d", response.StatusCode)
+	}
+	var discovery OidcDiscovery
+	err = json.NewDecoder(response.Body).Decode(&discovery)
+	if err != nil {
+		return nil, err
+	}
+	if discovery.AuthorizationEndpoint == "" || discovery.TokenEndpoint == "" {
+		return nil, errors.New("the OIDC discovery document has no authorization or token endpoint")
+	}
+	oidcDiscoveryCache.url = discoveryUrl
+	oidcDiscoveryCache.discovery = &discovery
+	oidcDiscoveryCache.expireAt = time.Now().Add(oidcDiscoveryTTL)
+	return &discovery, nil
+}
+
+func oidcRedirectUri() string {
+	if config.OidcRedirectUri != "" {
+		return config.OidcRedirectUri
+	}
+	return strings.TrimRight(config.ServerAddress, "/") + "/oauth/oidc"
+}
+
+// OidcOAuth redirects to the identity provider, the state comes from /api/oauth/state
+func OidcOAuth(c *gin.Context) {
+	if !config.OidcEnabled {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": "管理员未开启通过 OIDC 登录以及注册",
+		})
+		return
+	}
+	discovery, err := getOidcDiscovery()
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+	nonce := helper.GetRandomString(16)
+	session := sessions.Default(c)
+	session.Set("oidc_nonce", nonce)
+	err = session.Save()
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+	params := url.Values{}
+	params.Set("response_type", "code")
+	params.Set("client_id", config.OidcClientId)
+	params.Set("redirect_uri", oidcRedirectUri())
+	params.Set("scope", config.OidcScopes)
+	params.Set("state", c.Query("state"))
+	params.Set("nonce", nonce)
+	separator := "?"
+	if strings.Contains(discovery.AuthorizationEndpoint, "?") {
+		separator = "&"
+	}
+	c.Redirect(http.StatusFound, discovery.AuthorizationEndpoint+separator+params.Encode())
+}
+
+func getOidcTokenByCode(discovery *OidcDiscovery, code string) (*OidcTokenResult, error) {
+	data := url.Values{}
+	data.Set("grant_type", "authorization_code")
+	data.Set("code", code)
+	data.Set("redirect_uri", oidcRedirectUri())
+	// client_secret_basic is the default of the specification, client_secret_post is used only when it is the one supported
+	basicAuth := len(discovery.TokenEndpointAuthMethodsSupported) == 0
+	for _, method := range discovery.TokenEndpointAuthMethodsSupported {
+		if method == "client_secret_basic" {
+			basicAuth = true
+		}
+	}
+	if !basicAuth {
+		data.Set("client_id", config.OidcClientId)
+		data.Set("client_secret", config.OidcClientSecret)
+	}
+	req, err := http.NewRequest("POST", discovery.TokenEndpoint, strings.NewReader(data.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if basicAuth {
+		req.SetBasicAuth(url.QueryEscape(config.OidcClientId), url.QueryEscape(config.OidcClientSecret))
+	}
+	response, err := oidcHttpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(response.Body, 512))
+		return nil, fmt.Errorf("failed to get token: %d %s", response.StatusCode, string(body))
+	}
+	var tokenResult OidcTokenResult
+	err = json.NewDecoder(response.Body).Decode(&tokenResult)
+	if err != nil {
+		return nil, err
+	}
+	if tokenResult.IdToken == "" {
+		return nil, errors.New("the identity provider returned no id token, is the openid scope missing?")
+	}
+	return &tokenResult, nil
+}
+
+// parseOidcIdToken returns the claims of the id token after checking its issuer, audience, expiry and nonce.
+// The token comes straight from the token endpoint over TLS, which the specification accepts in place of checking its signature.
+func parseOidcIdToken(discovery *OidcDiscovery, idToken string, nonce string) (map[string]interface{}, error) {
+	parts := strings.Split(idToken, ".")
+	if len(parts) != 3 {
+		return nil, errors.New("malformed id token")
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(parts[1], "="))
+	if err != nil {
+		return nil, fmt.Errorf("malformed id token: %w", err)
+	}
+	var claims map[string]interface{}
+	err = json.Unmarshal(payload, &claims)
+	if err != nil {
+		return nil, fmt.Errorf("malformed id token: %w", err)
+	}
+	if discovery.Issuer != "" && claimString(claims, "iss") != discovery.Issuer {
+		return nil, errors.New("the issuer of the id token does not match")
+	}
+	audienceMatched := false
+	for _, audience := range claimStrings(claims, "aud") {
+		if audience == config.OidcClientId {
+			audienceMatched = true
+		}
+	}
+	if !audienceMatched {
+		return nil, errors.New("the id token was not issued for this client")
+	}
+	if exp, ok := claims["exp"].(float64); !ok || time.Now().Unix() > int64(exp) {
+		return nil, errors.New("the id token has expired")
+	}
+	if nonce == "" || claimString(claims, "nonce") != nonce {
+		return nil, errors.New("the nonce of the id token does not match")
+	}
+	if claimString(claims, "sub") == "" {
+		return nil, errors.New("the id token has no subject")
+	}
+	return claims, nil
+}
+
+// getOidcUserinfo adds the claims of the userinfo endpoint missing from the id token
+func getOidcUserinfo(discovery *OidcDiscovery, accessToken string, claims map[string]interface{}) error {
+	if discovery.UserinfoEndpoint == "" || accessToken == "" {
+		return nil
+	}
+	req, err := http.NewRequest("GET", discovery.UserinfoEndpoint, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+	req.Header.Set("Accept", "application/json")
+	response, err := oidcHttpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to get user info: %d", response.StatusCode)
+	}
+	var userinfo map[string]interface{}
+	err = json.NewDecoder(response.Body).Decode(&userinfo)
+	if err != nil {
+		return err
+	}
+	if claimString(userinfo, "sub") != claimString(claims, "sub") {
+		return errors.New("the subject of the user info does not match the id token")
+	}
+	for key, value := range userinfo {
+		if _, ok := claims[key]; !ok {
+			claims[key] = value
+		}
+	}
+	return nil
+}
+
+// getOidcClaims exchanges the authorization code and returns the claims of the user
+func getOidcClaims(c *gin.Context, code string) (map[string]interface{}, error) {
+	if code == "" {
+		return nil, errors.New("无效的参数")
+	}
+	discovery, err := getOidcDiscovery()
+	if err != nil {
+		return nil, err
+	}
+	tokenResult, err := getOidcTokenByCode(discovery, code)
+	if err != nil {
+		return nil, err
+	}
+	session := sessions.Default(c)
+	nonce, _ := session.Get("oidc_nonce").(string)
+	session.Delete("oidc_nonce")
+	_ = session.Save()
+	claims, err := parseOidcIdToken(discovery, tokenResult.IdToken, nonce)
+	if err != nil {
+		return nil, err
+	}
+	err = getOidcUserinfo(discovery, tokenResult.AccessToken, claims)
+	if err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+// lookupClaim returns the claim at the dotted path, such as realm_access.roles
+func lookupClaim(claims map[string]interface{}, path string) interface{} {
+	if value, ok := claims[path]; ok {
+		return value
+	}
+	var current interface{} = claims
+	for _, key := range strings.Split(path, ".") {
+		object, ok := current.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		current = object[key]
+	}
+	return current
+}
+
+func claimString(claims map[string]interface{}, path string) string {
+	switch value := lookupClaim(claims, path).(type) {
+	case string:
+		return value
+	case float64:
+		return strconv.FormatFloat(value, 'f', -1, 64)
+	}
+	return ""
+}
+
+// claimStrings returns a claim holding a list of strings, a single string is a list of one
+func claimStrings(claims map[string]interface{}, path string) []string {
+	switch value := lookupClaim(claims, path).(type) {
+	case string:
+		return []string{value}
+	case []interface{}:
+		result := make([]string, 0, len(value))
+		for _, item := range value {
+			if s, ok := item.(string); ok {
+				result = append(result, s)
+			}
+		}
+		return result
+	}
+	return nil
+}
+
+// oidcUsername returns the username claim if it can be used as it is, or a generated one
+func oidcUsername(claims map[string]interface{}) string {
+	username := claimString(claims, config.OidcUsernameClaim)
+	if username != "" && len(username) <= 12 && !model.IsUsernameAlreadyTaken(username) {
+		return username
+	}
+	return "oidc" + strconv.Itoa(model.GetMaxUserId()+1)
+}
+
+func OidcOAuthCallback(c *gin.Context) {
+	session := sessions.Default(c)
+	state := c.Query("state")
+	if state == "" || session.Get("oauth_state") == nil || state != session.Get("oauth_state").(string) {
+		c.JSON(http.StatusForbidden, gin.H{
+			"success": false,
+			"message": "state is empty or not same",
+		})
+		return
+	}
+	if isLoggedIn(c) {
+		OidcBind(c)
+		return
+	}
+	if !config.OidcEnabled {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": "管理员未开启通过 OIDC 登录以及注册",
+		})
+		return
+	}
+	claims, err := getOidcClaims(c, c.Query("code"))
+	if err != nil {
+		logger.SysError("OIDC login failed: " + err.Error())
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+	user := model.User{
+		OidcId: claimString(claims, "sub"),
+	}
+	email := claimString(claims, config.OidcEmailClaim)
+	if model.IsOidcIdAlreadyTaken(user.OidcId) {
+		err := user.FillUserByOidcId()
+		if err != nil {
+			c.JSON(http.StatusOK, gin.H{
+				"success": false,
+				"message": err.Error(),
+			})
+			return
+		}
+	} else {
+		if !config.RegisterEnabled {
+			c.JSON(http.StatusOK, gin.H{
+				"success": false,
+				"message": "管理员关闭了新用户注册",
+			})
+			return
+		}
+		user.Username = oidcUsername(claims)
+		user.DisplayName = claimString(claims, "name")
+		if user.DisplayName == "" || len(user.DisplayName) > 20 {
+			user.DisplayName = user.Username
+		}
+		user.Email = email
+		user.Role = common.RoleCommonUser
+		user.Status = common.UserStatusEnabled
+		if err := user.Insert(0); err != nil {
+			c.JSON(http.StatusOK, gin.H{
+				"success": false,
+				"message": err.Error(),
+			})
+			return
+		}
+	}
+	if user.Status != common.UserStatusEnabled {
+		c.JSON(http.StatusOK, gin.H{
+			"message": "用户已被封禁",
+			"success": false,
+		})
+		return
+	}
+	if email != "" && email != user.Email {
+		user.Email = email
+		err = user.Update(false)
+		if err != nil {
+			c.JSON(http.StatusOK, gin.H{
+				"success": false,
+				"message": err.Error(),
+			})
+			return
+		}
+	}
+	err = user.SyncExternalRoleAndGroup(claimStrings(claims, config.OidcGroupsClaim), config.OidcRoleMapping, config.OidcGroupMapping)
+	if err != nil {
+		logger.SysError(fmt.Sprintf("failed to sync the role and group of user #%d from OIDC: %s", user.Id, err.Error()))
+	}
+	setupLogin(&user, c)
+}
+
+func OidcBind(c *gin.Context) {
+	if !config.OidcEnabled {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": "管理员未开启通过 OIDC 登录以及注册",
+		})
+		return
+	}
+	claims, err := getOidcClaims(c, c.Query("code"))
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+	user := model.User{
+		OidcId: claimString(claims, "sub"),
+	}
+	if model.IsOidcIdAlreadyTaken(user.OidcId) {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": "该 OIDC 账户已被绑定",
+		})
+		return
+	}
+	session := sessions.Default(c)
+	user.Id = session.Get("id").(int)
+	err = user.FillUserById()
+	if err == nil {
+		user.OidcId = claimString(claims, "sub")
+		err = user.Update(false)
+	}
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "bind",
+	})
+}
@@ -43,7 +43,37 @@ func UpdateOption(c *gin.Context) {
 		})
 		return
 	}
+	// the change is described before validating, so that rejected updates of secret options don't record the value
+	config.OptionMapRWMutex.RLock()
+	oldValue := config.OptionMap[option.Key]
+	config.OptionMapRWMutex.RUnlock()
+	setAuditChange(c, "option.update", "option", option.Key,
+		map[string]interface{}{option.Key: oldValue}, map[string]interface{}{option.Key: option.Value})
+	if err := model.CheckManagedOption(option.Key, option.Value); err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
 	switch option.Key {
+	case "ChannelTestCases":
+		if _, err := model.ParseChannelTestCases(option.Value); err != nil {
+			c.JSON(http.StatusOK, gin.H{
+				"success": false,
+				"message": "无效的渠道测试用例：" + err.Error(),
+			})
+			return
+		}
+	case "GroupRateLimits":
+		var limits map[string]model.RateLimits
+		if err := json.Unmarshal([]byte(option.Value), &limits); err != nil {
+			c.JSON(http.StatusOK, gin.H{
+				"success": false,
+				"message": "无效的分组限流配置：" + err.Error(),
+			})
+			return
+		}
 	case "Theme":
 		if !config.ValidThemes[option.Value] {
 			c.JSON(http.StatusOK, gin.H{
@@ -69,6 +99,33 @@ func UpdateOption(c *gin.Context) {
 			return
 
 		}
+	case "OidcEnabled":
+		if option.Value == "true" && (config.OidcDiscoveryUrl == "" || config.OidcClientId == "") {
+			c.JSON(http.StatusOK, gin.H{
+				"success": false,
+				"message": "无法启用 OIDC 登录，请先填入 Discovery URL 以及 Client Id！",
+			})
+			return
+		}
+	case "LdapEnabled":
+		if option.Value == "true" && (config.LdapUrl == "" || config.LdapUserDn == "") {
+			c.JSON(http.StatusOK, gin.H{
+				"success": false,
+				"message": "无法启用 LDAP 登录，请先填入 LDAP 地址以及用户 DN！",
+			})
+			return
+		}
+	case "OidcRoleMapping", "OidcGroupMapping", "LdapRoleMapping", "LdapGroupMapping":
+		if option.Value != "" {
+			var mapping map[string]string
+			if err := json.Unmarshal([]byte(option.Value), &mapping); err != nil {
+				c.JSON(http.StatusOK, gin.H{
+					"success": false,
+					"message": "映射必须是 JSON 对象：" + err.Error(),
+				})
+				return
+			}
+		}
 	case "EmailDomainRestrictionEnabled":
 		if option.Value == "true" && len(config.EmailDomainWhitelist) == 0 {
 			c.JSON(http.StatusOK, gin.H{
@@ -124,3 +181,53 @@ func UpdateOption(c *gin.Context) {
 	})
 	return
 }
+
+func GetManagedConfigStatus(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+		"data":    model.GetManagedConfigStatus(),
+	})
+	return
+}
+
+func GetManagedConfigDrift(c *gin.Context) {
+	drifts, err := model.GetManagedConfigDrift()
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+		"data":    drifts,
+	})
+	return
+}
+
+func ReconcileManagedConfig(c *gin.Context) {
+	if config.ConfigFile == "" {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": "config file is not enabled",
+		})
+		return
+	}
+	err := model.ReconcileManagedConfig()
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+		"data":    model.GetManagedConfigStatus(),
+	})
+	return
+}
@@ -0,0 +1,503 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/songquanpeng/one-api/common"
+	"github.com/songquanpeng/one-api/model"
+	"gorm.io/gorm"
+)
+
+// getOrganizationRole returns the role of the current user in the organization, users allowed to manage organizations act as its owner
+func getOrganizationRole(c *gin.Context, organizationId int) (string, error) {
+	if model.UserHasPermission(c.GetInt("id"), model.PermissionOrganizationManage) {
+		return model.OrganizationRoleOwner, nil
+	}
+	role, err := model.GetOrganizationMemberRole(organizationId, c.GetInt("id"))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return "", errors.New("你不是该组织的成员")
+	}
+	return role, err
+}
+
+// checkOrganizationRole writes the error and returns false unless the current user has at least minRole in the organization
+func checkOrganizationRole(c *gin.Context, organizationId int, minRole string) (string, bool) {
+	role, err := getOrganizationRole(c, organizationId)
+	if err == nil && !model.IsOrganizationRoleAtLeast(role, minRole) {
+		err = errors.New("无权进行此操作")
+	}
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return "", false
+	}
+	return role, true
+}
+
+func GetUserOrganizations(c *gin.Context) {
+	organizations, err := model.GetUserOrganizations(c.GetInt("id"))
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+		"data":    organizations,
+	})
+}
+
+func GetAllOrganizations(c *gin.Context) {
+	page, err := strconv.Atoi(c.Query("page"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+	pagesize, err := strconv.Atoi(c.Query("pagesize"))
+	if err != nil || pagesize <= 0 {
+		pagesize = 10
+	}
+	organizations, total, err := model.GetOrganizationsAndCount(page, pagesize)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+		"data": gin.H{
+			"list":        organizations,
+			"currentPage": page,
+			"pageSize":    pagesize,
+			"total":       total,
+		},
+	})
+}
+
+func GetOrganization(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+	role, ok := checkOrganizationRole(c, id, model.OrganizationRoleMember)
+	if !ok {
+		return
+	}
+	organization, err := model.GetOrganizationById(id)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+	organization.Role = role
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+		"data":    organization,
+	})
+}
+
+func AddOrganization(c *gin.Context) {
+	organization := model.Organization{}
+	err := c.ShouldBindJSON(&organization)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+	if organization.Name == "" || len(organization.Name) > 64 {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": "组织名称长度必须在1-64之间",
+		})
+		return
+	}
+	cleanOrganization := model.Organization{
+		Name:   organization.Name,
+		Status: common.OrganizationStatusEnabled,
+	}
+	err = cleanOrganization.Insert(c.GetInt("id"))
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+	cleanOrganization.Role = model.OrganizationRoleOwner
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+		"data":    cleanOrganization,
+	})
+}
+
+// UpdateOrganization renames the organization, its status and quota can only be changed by administrators
+func UpdateOrganization(c *gin.Context) {
+	organization := model.Organization{}
+	err := c.ShouldBindJSON(&organization)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+	if _, ok := checkOrganizationRole(c, organization.Id, model.OrganizationRoleAdmin); !ok {
+		return
+	}
+	if organization.Name == "" || len(organization.Name) > 64 {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": "组织名称长度必须在1-64之间",
+		})
+		return
+	}
+	originOrganization, err := model.GetOrganizationById(organization.Id)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+	updatedOrganization := *originOrganization
+	updatedOrganization.Name = organization.Name
+	isAdmin := model.UserHasPermission(c.GetInt("id"), model.PermissionOrganizationManage)
+	if isAdmin {
+		if organization.Status != 0 {
+			updatedOrganization.Status = organization.Status
+		}
+		updatedOrganization.Quota = organization.Quota
+	}
+	err = updatedOrganization.Update()
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+	if originOrganization.Quota != updatedOrganization.Quota {
+		model.RecordOrganizationLog(organization.Id, c.GetInt("id"), model.LogTypeManage, fmt.Sprintf("管理员将组织额度从 %s修改为 %s", common.LogQuota(originOrganization.Quota), common.LogQuota(updatedOrganization.Quota)))
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+		"data":    updatedOrganization,
+	})
+}
+
+func DeleteOrganization(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+	if _, ok := checkOrganizationRole(c, id, model.OrganizationRoleOwner); !ok {
+		return
+	}
+	organization, err := model.GetOrganizationById(id)
+	if err == nil {
+		err = organization.Delete()
+	}
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+	})
+}
+
+func GetOrganizationMembers(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+	if _, ok := checkOrganizationRole(c, id, model.OrganizationRoleMember); !ok {
+		return
+	}
+	members, err := model.GetOrganizationMembers(id)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+		"data":    members,
+	})
+}
+
+type organizationMemberRequest struct {
+	UserId   int    `json:"user_id"`
+	Username string `json:"username"`
+	Role     string `json:"role"`
+}
+
+// checkOrganizationMemberRole checks that the current user may give the role, only the owner manages admins
+func checkOrganizationMemberRole(myRole string, role string) error {
+	if !model.IsValidOrganizationRole(role) || role == model.OrganizationRoleOwner {
+		return errors.New("无效的组织角色")
+	}
+	if role == model.OrganizationRoleAdmin && myRole != model.OrganizationRoleOwner {
+		return errors.New("只有组织所有者可以管理组织管理员")
+	}
+	return nil
+}
+
+func AddOrganizationMember(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+	myRole, ok := checkOrganizationRole(c, id, model.OrganizationRoleAdmin)
+	if !ok {
+		return
+	}
+	req := organizationMemberRequest{}
+	err = c.ShouldBindJSON(&req)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+	if req.Role == "" {
+		req.Role = model.OrganizationRoleMember
+	}
+	err = checkOrganizationMemberRole(myRole, req.Role)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+	user := model.User{Username: req.Username}
+	err = user.FillUserByUsername()
+	if err != nil || user.Id == 0 {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": "用户不存在",
+		})
+		return
+	}
+	if _, err = model.GetOrganizationMemberRole(id, user.Id); err == nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": "该用户已是组织成员",
+		})
+		return
+	}
+	err = model.AddOrganizationMember(id, user.Id, req.Role)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+	})
+}
+
+func UpdateOrganizationMember(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+	myRole, ok := checkOrganizationRole(c, id, model.OrganizationRoleAdmin)
+	if !ok {
+		return
+	}
+	req := organizationMemberRequest{}
+	err = c.ShouldBindJSON(&req)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+	role, err := model.GetOrganizationMemberRole(id, req.UserId)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": "该用户不是组织成员",
+		})
+		return
+	}
+	if role == model.OrganizationRoleOwner {
+		err = errors.New("无法修改组织所有者的角色")
+	} else {
+		// demoting an admin needs the same rights as promoting one
+		err = checkOrganizationMemberRole(myRole, role)
+		if err == nil {
+			err = checkOrganizationMemberRole(myRole, req.Role)
+		}
+	}
+	if err == nil {
+		err = model.UpdateOrganizationMemberRole(id, req.UserId, req.Role)
+	}
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+	})
+}
+
+// RemoveOrganizationMember removes a member from the organization, members may also leave it themselves
+func RemoveOrganizationMember(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+	userId, err := strconv.Atoi(c.Param("user_id"))
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+	minRole := model.OrganizationRoleAdmin
+	if userId == c.GetInt("id") {
+		minRole = model.OrganizationRoleMember
+	}
+	myRole, ok := checkOrganizationRole(c, id, minRole)
+	if !ok {
+		return
+	}
+	role, err := model.GetOrganizationMemberRole(id, userId)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": "该用户不是组织成员",
+		})
+		return
+	}
+	if role == model.OrganizationRoleOwner {
+		err = errors.New("无法移除组织所有者")
+	} else if userId != c.GetInt("id") {
+		err = checkOrganizationMemberRole(myRole, role)
+	}
+	if err == nil {
+		err = model.RemoveOrganizationMember(id, userId)
+	}
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+	})
+}
+
+// GetOrganizationLogs returns the consumption of the organization per member, members only see their own logs
+func GetOrganizationLogs(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+	role, ok := checkOrganizationRole(c, id, model.OrganizationRoleMember)
+	if !ok {
+		return
+	}
+	page, err := strconv.Atoi(c.Query("page"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+	pagesize, err := strconv.Atoi(c.Query("pagesize"))
+	if err != nil || pagesize <= 0 {
+		pagesize = 10
+	}
+	userId := 0
+	if !model.IsOrganizationRoleAtLeast(role, model.OrganizationRoleAdmin) {
+		userId = c.GetInt("id")
+	}
+	logType, _ := strconv.Atoi(c.Query("type"))
+	startTimestamp, _ := strconv.ParseInt(c.Query("start_timestamp"), 10, 64)
+	endTimestamp, _ := strconv.ParseInt(c.Query("end_timestamp"), 10, 64)
+	logs, total, err := model.GetOrganizationLogsAndCount(id, userId, logType, startTimestamp, endTimestamp, c.Query("model_name"), c.Query("username"), page, pagesize)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+		"data": gin.H{
+			"list":        logs,
+			"currentPage": page,
+			"pageSize":    pagesize,
+			"total":       total,
+		},
+	})
+}
@@ -3,6 +3,7 @@ package controller
 import (
 	"net/http"
 	"strconv"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 	"github.com/songquanpeng/one-api/common/helper"
@@ -140,6 +141,7 @@ func AddRedemption(c *gin.Context) {
 		return
 	}
 	var keys []string
+	var ids []string
 	for i := 0; i < redemption.Count; i++ {
 		key := helper.GetUUID()
 		cleanRedemption := model.Redemption{
@@ -159,7 +161,13 @@ func AddRedemption(c *gin.Context) {
 			return
 		}
 		keys = append(keys, key)
+		ids = append(ids, strconv.Itoa(cleanRedemption.Id))
 	}
+	setAuditChange(c, "redemption.create", "redemption", strings.Join(ids, ","), nil, map[string]interface{}{
+		"name":  redemption.Name,
+		"quota": redemption.Quota,
+		"count": redemption.Count,
+	})
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
 		"message": "",
@@ -44,6 +44,18 @@ func relayHelper(c *gin.Context, relayMode int) *model.ErrorWithStatusCode {
 func Relay(c *gin.Context) {
 	ctx := c.Request.Context()
 	relayMode := constant.Path2RelayMode(c.Request.URL.Path)
+	if !dbmodel.IsEndpointAllowedForToken(c.GetString("token_endpoints"), c.GetString("token_denied_endpoints"), constant.RelayModeName(relayMode)) {
+		err := model.Error{
+			Message: helper.MessageWithRequestId(fmt.Sprintf("This token is not allowed to use %s", c.Request.URL.Path), c.GetString(logger.RequestIdKey)),
+			Type:    "api_error",
+			Param:   "",
+			Code:    "endpoint_not_allowed",
+		}
+		c.JSON(http.StatusForbidden, gin.H{
+			"error": err,
+		})
+		return
+	}
 	if config.DebugEnabled {
 		requestBody, _ := common.GetRequestBody(c)
 		logger.Debugf(ctx, "request body: %s", string(requestBody))
@@ -79,7 +91,10 @@ func Relay(c *gin.Context) {
 		}
 		logger.Infof(ctx, "Using channel #%d to retry (remain times %d)", channel.Id, i)
 
-		middleware.SetupContextForSelectedChannel(c, channel, originalModel)
+		if err := middleware.SetupContextForSelectedChannel(c, channel, originalModel); err != nil {
+			lastFailedChannelId = channel.Id
+			continue
+		}
 		requestBody, err := common.GetRequestBody(c)
 		c.Request.Body = io.NopCloser(bytes.NewBuffer(requestBody))
 		bizErr = relayHelper(c, relayMode)
@@ -183,7 +198,9 @@ func RelayMidjourney(c *gin.Context) {
 				break
 			}
 			logger.Infof(ctx, "Using channel #%d to retry (remain times %d)", channel.Id, i)
-			middleware.SetupContextForSelectedChannel(c, channel, originalModel)
+			if err := middleware.SetupContextForSelectedChannel(c, channel, originalModel); err != nil {
+				continue
+			}
 
 			requestBody, err := common.GetRequestBody(c)
 			c.Request.Body = io.NopCloser(bytes.NewBuffer(requestBody))
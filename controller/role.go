@@ -0,0 +1,201 @@
+package controller
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/songquanpeng/one-api/model"
+)
+
+func GetAllRoles(c *gin.Context) {
+	roles, err := model.GetAllRoles()
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+		"data":    roles,
+	})
+}
+
+func GetAllPermissions(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+		"data":    model.AllPermissions,
+	})
+}
+
+// checkGrantablePermissions checks that the current user holds every permission it is about to grant,
+// so that managing roles cannot be used to gain more rights
+func checkGrantablePermissions(c *gin.Context, permissions string) error {
+	err := model.ValidatePermissions(permissions)
+	if err != nil {
+		return err
+	}
+	myPermissions, err := model.CacheGetUserPermissions(c.GetInt("id"))
+	if err != nil {
+		return err
+	}
+	granted := model.SplitPermissions(permissions)
+	for _, permission := range model.AllPermissions {
+		if model.HasPermission(granted, permission) && !model.HasPermission(myPermissions, permission) {
+			return errors.New("无权授予权限 " + permission)
+		}
+	}
+	return nil
+}
+
+func AddRole(c *gin.Context) {
+	role := model.Role{}
+	err := c.ShouldBindJSON(&role)
+	if err != nil || role.Name == "" {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": "无效的参数",
+		})
+		return
+	}
+	if len(role.Name) > 32 {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": "角色名称过长",
+		})
+		return
+	}
+	err = checkGrantablePermissions(c, role.Permissions)
+	if err == nil {
+		err = role.Insert()
+	}
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+		"data":    role,
+	})
+}
+
+// UpdateRole changes the role, built-in roles cannot be renamed and the root role keeps all the permissions
+func UpdateRole(c *gin.Context) {
+	role := model.Role{}
+	err := c.ShouldBindJSON(&role)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": "无效的参数",
+		})
+		return
+	}
+	originRole, err := model.GetRoleById(role.Id)
+	if err == nil && originRole.Builtin && role.Name != originRole.Name {
+		err = errors.New("无法修改内置角色的名称")
+	}
+	if err == nil && originRole.Name == model.RoleNameRoot && role.Permissions != originRole.Permissions {
+		err = errors.New("无法修改超级管理员角色的权限")
+	}
+	if err == nil && (role.Name == "" || len(role.Name) > 32) {
+		err = errors.New("无效的角色名称")
+	}
+	if err == nil {
+		err = checkGrantablePermissions(c, role.Permissions)
+	}
+	if err == nil {
+		originRole.Name = role.Name
+		originRole.Description = role.Description
+		originRole.Permissions = role.Permissions
+		originRole.TwoFactorRequired = role.TwoFactorRequired
+		err = originRole.Update()
+	}
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+		"data":    originRole,
+	})
+}
+
+func DeleteRole(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	role, err := model.GetRoleById(id)
+	if err == nil && role.Builtin {
+		err = errors.New("无法删除内置角色")
+	}
+	if err == nil {
+		err = role.Delete()
+	}
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+	})
+}
+
+type assignRoleRequest struct {
+	UserId int `json:"user_id"`
+	RoleId int `json:"role_id"`
+}
+
+// AssignUserRole gives a role to the user, a role id of 0 resets it to the built-in role of its role level
+func AssignUserRole(c *gin.Context) {
+	var req assignRoleRequest
+	err := c.ShouldBindJSON(&req)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": "无效的参数",
+		})
+		return
+	}
+	user, err := model.GetUserById(req.UserId, false)
+	if err == nil {
+		if !model.CanManageUser(c.GetInt("id"), user.Role, user.RoleId) {
+			err = errors.New("无权更新同权限等级或更高权限等级的用户信息")
+		}
+	}
+	if err == nil && req.RoleId != 0 {
+		var role *model.Role
+		role, err = model.GetRoleById(req.RoleId)
+		if err == nil {
+			err = checkGrantablePermissions(c, role.Permissions)
+		}
+	}
+	if err == nil {
+		err = model.AssignUserRole(user.Id, req.RoleId)
+	}
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+	})
+}
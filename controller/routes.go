@@ -3,9 +3,7 @@ package controller
 import (
 	"net/http"
 
-	"github.com/gin-contrib/sessions"
 	"github.com/gin-gonic/gin"
-	"github.com/songquanpeng/one-api/common"
 	"github.com/songquanpeng/one-api/model"
 )
 
@@ -14,84 +12,55 @@ type Pages struct {
 	Settings []string
 }
 
-var userPages = Pages{
-	Name:     []string{"dashboard", "token", "setting", "topup", "log"},
-	Settings: []string{"personalSettings"},
-}
-
-var adminPages = Pages{
-	Name:     []string{"dashboard", "token", "setting", "topup", "log", "channel", "user"},
-	Settings: []string{"personalSettings"},
-}
-
-var rootPages = Pages{
-	Name:     []string{"dashboard", "token", "setting", "topup", "log", "channel", "user"},
-	Settings: []string{"personalSettings", "operateSettings", "systemSettings"},
+// menuPermissions lists the pages shown only to the users granted the permission
+var menuPermissions = []struct {
+	Page       string
+	Setting    bool
+	Permission string
+}{
+	{Page: "channel", Permission: model.PermissionChannelRead},
+	{Page: "redemption", Permission: model.PermissionRedemptionRead},
+	{Page: "user", Permission: model.PermissionUserRead},
+	{Page: "audit", Permission: model.PermissionAuditRead},
+	{Page: "operateSettings", Setting: true, Permission: model.PermissionOptionWrite},
+	{Page: "systemSettings", Setting: true, Permission: model.PermissionOptionWrite},
 }
 
+// Getmenus returns the pages of the current user, generated from the permissions of its role,
+// together with the permissions themselves so that the web UI can hide what the user cannot do
 func Getmenus(c *gin.Context) {
-	session := sessions.Default(c)
-	username := session.Get("username")
-	role := session.Get("role")
-	status := session.Get("status")
-	if username == nil {
-		accessToken := c.Request.Header.Get("Authorization")
-		if accessToken == "" {
-			c.JSON(http.StatusUnauthorized, gin.H{
-				"success": false,
-				"message": "Not authorized for this operation, not logged in and no access token provided",
-			})
-			c.Abort()
-			return
-		}
-		user := model.ValidateAccessToken(accessToken)
-		if user != nil && user.Username != "" {
-			username = user.Username
-			role = user.Role
-			role = user.Role
-		} else {
-			c.JSON(http.StatusOK, gin.H{
-				"success": false,
-				"message": "无权进行此操作，access token 无效",
-			})
-			c.Abort()
-			return
-		}
-	}
-	if status.(int) == common.UserStatusDisabled {
+	permissions, err := model.CacheGetUserPermissions(c.GetInt("id"))
+	if err != nil {
 		c.JSON(http.StatusOK, gin.H{
 			"success": false,
-			"message": "用户已被封禁",
+			"message": err.Error(),
 		})
-		c.Abort()
 		return
 	}
-	if role == 100 {
-		c.JSON(http.StatusOK, gin.H{
-			"success": true,
-			"message": "",
-			"menus":   rootPages,
-		})
-		return
-	} else if role == 10 {
-		c.JSON(http.StatusOK, gin.H{
-			"success": true,
-			"message": "",
-			"menus":   adminPages,
-		})
-		return
-	} else if role == 1 {
-		c.JSON(http.StatusOK, gin.H{
-			"success": true,
-			"message": "",
-			"menus":   userPages,
-		})
-		return
-	} else {
-		c.JSON(http.StatusOK, gin.H{
-			"success": false,
-			"message": "",
-		})
-		return
+	pages := Pages{
+		Name:     []string{"dashboard", "token", "setting", "topup", "log"},
+		Settings: []string{"personalSettings"},
+	}
+	for _, menu := range menuPermissions {
+		if !model.HasPermission(permissions, menu.Permission) {
+			continue
+		}
+		if menu.Setting {
+			pages.Settings = append(pages.Settings, menu.Page)
+		} else {
+			pages.Name = append(pages.Name, menu.Page)
+		}
+	}
+	granted := make([]string, 0)
+	for _, permission := range model.AllPermissions {
+		if model.HasPermission(permissions, permission) {
+			granted = append(granted, permission)
+		}
 	}
+	c.JSON(http.StatusOK, gin.H{
+		"success":     true,
+		"message":     "",
+		"menus":       pages,
+		"permissions": granted,
+	})
 }
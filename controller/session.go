@@ -0,0 +1,187 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-contrib/sessions"
+	"github.com/gin-gonic/gin"
+	"github.com/songquanpeng/one-api/model"
+)
+
+type sessionResponse struct {
+	*model.Session
+	Current bool `json:"current"`
+}
+
+func sessionsResponse(c *gin.Context, userId int) {
+	sessions, err := model.GetUserSessions(userId)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+	currentId := c.GetInt("session_id")
+	data := make([]sessionResponse, 0, len(sessions))
+	for _, session := range sessions {
+		data = append(data, sessionResponse{
+			Session: session,
+			Current: session.UserId == c.GetInt("id") && session.Id == currentId,
+		})
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+		"data":    data,
+	})
+}
+
+// isLoggedIn reports whether the cookie belongs to an active session,
+// the OAuth callbacks bind the account to the logged in user instead of logging in
+func isLoggedIn(c *gin.Context) bool {
+	session := sessions.Default(c)
+	key, ok := session.Get("session_key").(string)
+	if !ok || session.Get("username") == nil {
+		return false
+	}
+	_, err := model.ValidateSession(key, c.ClientIP())
+	return err == nil
+}
+
+// getManagedUser returns the user of the id parameter if the administrator may manage it
+func getManagedUser(c *gin.Context) (*model.User, error) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return nil, err
+	}
+	user, err := model.GetUserById(id, false)
+	if err != nil {
+		return nil, err
+	}
+	if user.Id != c.GetInt("id") && !model.CanManageUser(c.GetInt("id"), user.Role, user.RoleId) {
+		return nil, errors.New("无权更新同权限等级或更高权限等级的用户信息")
+	}
+	return user, nil
+}
+
+// GetSelfSessions lists the active sessions of the user, the one of the request is marked as current
+func GetSelfSessions(c *gin.Context) {
+	sessionsResponse(c, c.GetInt("id"))
+}
+
+func RevokeSelfSession(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err == nil {
+		err = model.RevokeSession(c.GetInt("id"), id)
+	}
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+	})
+}
+
+// RevokeOtherSelfSessions logs the user out everywhere but in the session of the request
+func RevokeOtherSelfSessions(c *gin.Context) {
+	count, err := model.RevokeUserSessions(c.GetInt("id"), c.GetInt("session_id"))
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+		"data":    count,
+	})
+}
+
+func GetUserSessions(c *gin.Context) {
+	user, err := getManagedUser(c)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+	sessionsResponse(c, user.Id)
+}
+
+func RevokeUserSession(c *gin.Context) {
+	user, err := getManagedUser(c)
+	var sessionId int
+	if err == nil {
+		sessionId, err = strconv.Atoi(c.Param("session_id"))
+	}
+	if err == nil {
+		err = model.RevokeSession(user.Id, sessionId)
+	}
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+	model.RecordLog(user.Id, model.LogTypeSecurity, "管理员注销了一个登录会话")
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+	})
+}
+
+// RevokeAllUserSessions logs the user out everywhere
+func RevokeAllUserSessions(c *gin.Context) {
+	user, err := getManagedUser(c)
+	var count int64
+	if err == nil {
+		count, err = model.RevokeUserSessions(user.Id, 0)
+	}
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+	model.RecordLog(user.Id, model.LogTypeSecurity, fmt.Sprintf("管理员注销了全部 %d 个登录会话", count))
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+		"data":    count,
+	})
+}
+
+// RevokeUserAccessToken replaces the system access token of the user with one nobody knows,
+// the user has to generate a new one to use the management API again
+func RevokeUserAccessToken(c *gin.Context) {
+	user, err := getManagedUser(c)
+	if err == nil {
+		err = model.RevokeAccessToken(user.Id)
+	}
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+	model.RecordLog(user.Id, model.LogTypeSecurity, "管理员吊销了系统访问令牌")
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+	})
+}
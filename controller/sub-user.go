@@ -0,0 +1,278 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/songquanpeng/one-api/common"
+	"github.com/songquanpeng/one-api/common/config"
+	"github.com/songquanpeng/one-api/common/logger"
+	"github.com/songquanpeng/one-api/model"
+)
+
+func checkSubUserEnabled(c *gin.Context) bool {
+	if !config.SubUserEnabled {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": "管理员未开启子用户功能",
+		})
+		return false
+	}
+	return true
+}
+
+// checkSubUserGroup checks that the parent may put its sub-user in the group: its own group, or one of the
+// groups the admin listed in SubUserGroups whose ratio is not lower than its own, so that it cannot resell below its own price
+func checkSubUserGroup(parentId int, group string) error {
+	if _, ok := common.GroupRatio[group]; !ok {
+		return fmt.Errorf("分组 %s 不存在", group)
+	}
+	parentGroup, err := model.CacheGetUserGroup(parentId)
+	if err != nil {
+		return err
+	}
+	if group == parentGroup {
+		return nil
+	}
+	assignable := false
+	for _, g := range strings.Split(config.SubUserGroups, ",") {
+		if strings.TrimSpace(g) == group {
+			assignable = true
+			break
+		}
+	}
+	if !assignable {
+		return fmt.Errorf("不允许将子用户设置为分组 %s", group)
+	}
+	if common.GetGroupRatio(group) < common.GetGroupRatio(parentGroup) {
+		return fmt.Errorf("分组 %s 的倍率低于你所在的分组", group)
+	}
+	return nil
+}
+
+func GetSubUsers(c *gin.Context) {
+	if !checkSubUserEnabled(c) {
+		return
+	}
+	users, err := model.GetSubUsers(c.GetInt("id"))
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+		"data":    users,
+	})
+}
+
+func CreateSubUser(c *gin.Context) {
+	if !checkSubUserEnabled(c) {
+		return
+	}
+	var user model.User
+	err := c.ShouldBindJSON(&user)
+	if err != nil || user.Username == "" || user.Password == "" {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": "无效的参数",
+		})
+		return
+	}
+	if err := common.Validate.Struct(&user); err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": "输入不合法 " + err.Error(),
+		})
+		return
+	}
+	parentId := c.GetInt("id")
+	if user.Group == "" {
+		user.Group, err = model.CacheGetUserGroup(parentId)
+	} else {
+		err = checkSubUserGroup(parentId, user.Group)
+	}
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+	if user.DisplayName == "" {
+		user.DisplayName = user.Username
+	}
+	cleanUser := model.User{
+		Username:    user.Username,
+		Password:    user.Password,
+		DisplayName: user.DisplayName,
+		Group:       user.Group,
+	}
+	if err := cleanUser.InsertSubUser(parentId); err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+	})
+}
+
+// UpdateSubUser changes the display name, group and status of a sub-user
+func UpdateSubUser(c *gin.Context) {
+	if !checkSubUserEnabled(c) {
+		return
+	}
+	var user model.User
+	err := c.ShouldBindJSON(&user)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": "无效的参数",
+		})
+		return
+	}
+	parentId := c.GetInt("id")
+	originUser, err := model.GetSubUser(parentId, user.Id)
+	if err == nil && user.Group != "" && user.Group != originUser.Group {
+		err = checkSubUserGroup(parentId, user.Group)
+	}
+	if err == nil && user.Status != 0 && user.Status != common.UserStatusEnabled && user.Status != common.UserStatusDisabled {
+		err = errors.New("无效的用户状态")
+	}
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+	updatedUser := model.User{
+		Id:          originUser.Id,
+		DisplayName: user.DisplayName,
+		Group:       user.Group,
+		Status:      user.Status,
+	}
+	if err := updatedUser.Update(false); err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+	})
+}
+
+type subUserQuotaRequest struct {
+	Quota int64 `json:"quota"`
+}
+
+// TransferSubUserQuota allocates part of the quota of the current user to a sub-user, a negative quota takes it back
+func TransferSubUserQuota(c *gin.Context) {
+	if !checkSubUserEnabled(c) {
+		return
+	}
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+	var req subUserQuotaRequest
+	err = c.ShouldBindJSON(&req)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": "无效的参数",
+		})
+		return
+	}
+	parentId := c.GetInt("id")
+	_, err = model.GetSubUser(parentId, id)
+	if err == nil {
+		err = model.TransferQuotaToSubUser(parentId, id, req.Quota)
+	}
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+	for _, userId := range []int{parentId, id} {
+		err = model.CacheUpdateUserQuota2(userId)
+		if err != nil {
+			logger.SysError("failed to update user quota cache: " + err.Error())
+		}
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+	})
+}
+
+// GetSubUserLogs returns the logs of the sub-users of the current user, or of one of them
+func GetSubUserLogs(c *gin.Context) {
+	if !checkSubUserEnabled(c) {
+		return
+	}
+	page, err := strconv.Atoi(c.Query("page"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+	pagesize, err := strconv.Atoi(c.Query("pagesize"))
+	if err != nil || pagesize <= 0 {
+		pagesize = 10
+	}
+	parentId := c.GetInt("id")
+	var userIds []int
+	if userId, _ := strconv.Atoi(c.Query("user_id")); userId != 0 {
+		_, err = model.GetSubUser(parentId, userId)
+		userIds = []int{userId}
+	} else {
+		userIds, err = model.GetSubUserIds(parentId)
+	}
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+	logType, _ := strconv.Atoi(c.Query("type"))
+	startTimestamp, _ := strconv.ParseInt(c.Query("start_timestamp"), 10, 64)
+	endTimestamp, _ := strconv.ParseInt(c.Query("end_timestamp"), 10, 64)
+	logs, total, err := model.GetUsersLogsAndCount(userIds, logType, startTimestamp, endTimestamp, c.Query("model_name"), c.Query("token_name"), page, pagesize)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+		"data": gin.H{
+			"list":        logs,
+			"currentPage": page,
+			"pageSize":    pagesize,
+			"total":       total,
+		},
+	})
+}
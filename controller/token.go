@@ -6,9 +6,11 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/songquanpeng/one-api/common"
+	"github.com/songquanpeng/one-api/common/config"
 	"github.com/songquanpeng/one-api/common/helper"
 	"github.com/songquanpeng/one-api/common/logger"
 	"github.com/songquanpeng/one-api/model"
+	"github.com/songquanpeng/one-api/relay/constant"
 )
 
 func GetAllTokens(c *gin.Context) {
@@ -27,6 +29,9 @@ func GetAllTokens(c *gin.Context) {
 		})
 		return
 	}
+	for _, token := range tokens {
+		token.FillBudgetRemain()
+	}
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
 		"message": "",
@@ -75,6 +80,9 @@ func SearchTokens(c *gin.Context) {
 		})
 		return
 	}
+	for _, token := range tokens {
+		token.FillBudgetRemain()
+	}
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
 		"message": "",
@@ -106,6 +114,7 @@ func GetToken(c *gin.Context) {
 		})
 		return
 	}
+	token.FillBudgetRemain()
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
 		"message": "",
@@ -156,15 +165,55 @@ func AddToken(c *gin.Context) {
 		})
 		return
 	}
+	if err := model.ValidateTokenAllowIps(token.AllowIps); err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+	if err := validateTokenEndpoints(token.Endpoints, token.DeniedEndpoints); err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+	if err := model.ValidateTokenBudget(token.BudgetPeriod, token.BudgetQuota); err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+	if token.OrganizationId != 0 {
+		if _, ok := checkOrganizationRole(c, token.OrganizationId, model.OrganizationRoleMember); !ok {
+			return
+		}
+	}
 	cleanToken := model.Token{
-		UserId:         c.GetInt("id"),
-		Name:           token.Name,
-		Key:            helper.GenerateKey(),
-		CreatedTime:    helper.GetTimestamp(),
-		AccessedTime:   helper.GetTimestamp(),
-		ExpiredTime:    token.ExpiredTime,
-		RemainQuota:    token.RemainQuota,
-		UnlimitedQuota: token.UnlimitedQuota,
+		UserId:          c.GetInt("id"),
+		OrganizationId:  token.OrganizationId,
+		Name:            token.Name,
+		CreatedTime:     helper.GetTimestamp(),
+		AccessedTime:    helper.GetTimestamp(),
+		ExpiredTime:     token.ExpiredTime,
+		RemainQuota:     token.RemainQuota,
+		UnlimitedQuota:  token.UnlimitedQuota,
+		Models:          token.Models,
+		DeniedModels:    token.DeniedModels,
+		AllowIps:        token.AllowIps,
+		Endpoints:       token.Endpoints,
+		DeniedEndpoints: token.DeniedEndpoints,
+		RateLimits:      token.RateLimits,
+		TokenBudget: model.TokenBudget{
+			BudgetPeriod: token.BudgetPeriod,
+			BudgetQuota:  token.BudgetQuota,
+		},
+	}
+	cleanToken.SetKey(helper.GenerateKey())
+	if cleanToken.HasBudget() {
+		cleanToken.StartBudgetPeriod()
 	}
 	err = cleanToken.Insert()
 	if err != nil {
@@ -175,6 +224,7 @@ func AddToken(c *gin.Context) {
 		})
 		return
 	}
+	cleanToken.FillBudgetRemain()
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
 		"message": "",
@@ -238,6 +288,67 @@ func DeleteToken(c *gin.Context) {
 	return
 }
 
+// validateTokenEndpoints checks the endpoint scopes of a token against the names of the relay modes
+func validateTokenEndpoints(endpoints string, deniedEndpoints string) error {
+	err := model.ValidateTokenEndpoints(endpoints, constant.RelayModeNames())
+	if err != nil {
+		return err
+	}
+	return model.ValidateTokenEndpoints(deniedEndpoints, constant.RelayModeNames())
+}
+
+func RotateToken(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	userId := c.GetInt("id")
+	request := struct {
+		GracePeriod *int64 `json:"grace_period"` // seconds
+	}{}
+	if c.Request.ContentLength > 0 {
+		err := c.ShouldBindJSON(&request)
+		if err != nil {
+			c.JSON(http.StatusOK, gin.H{
+				"success": false,
+				"message": err.Error(),
+			})
+			return
+		}
+	}
+	gracePeriod := int64(config.TokenRotationGracePeriod)
+	if request.GracePeriod != nil {
+		gracePeriod = *request.GracePeriod
+	}
+	if gracePeriod < 0 {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": "宽限期不能为负数",
+		})
+		return
+	}
+	token, err := model.GetTokenByIds(id, userId)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+	err = token.RotateKey(gracePeriod)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+	token.FillBudgetRemain()
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+		"data":    token,
+	})
+	return
+}
+
 func UpdateToken(c *gin.Context) {
 	type TokenUpdate struct {
 		Id                   int    `json:"id"`
@@ -248,6 +359,14 @@ func UpdateToken(c *gin.Context) {
 		StatusOnly           *bool  `json:"status_only"`
 		Status               int    `json:"status"`
 		TokenRemindThreshold int64  `json:"token_remind_threshold"`
+		Models               string `json:"models"`
+		DeniedModels         string `json:"denied_models"`
+		AllowIps             string `json:"allow_ips"`
+		Endpoints            string `json:"endpoints"`
+		DeniedEndpoints      string `json:"denied_endpoints"`
+		BudgetPeriod         string `json:"budget_period"`
+		BudgetQuota          int64  `json:"budget_quota"`
+		model.RateLimits
 	}
 
 	var tokenupdate TokenUpdate
@@ -267,6 +386,27 @@ func UpdateToken(c *gin.Context) {
 		})
 		return
 	}
+	if err := model.ValidateTokenAllowIps(tokenupdate.AllowIps); err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+	if err := validateTokenEndpoints(tokenupdate.Endpoints, tokenupdate.DeniedEndpoints); err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+	if err := model.ValidateTokenBudget(tokenupdate.BudgetPeriod, tokenupdate.BudgetQuota); err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
 	cleanToken, err := model.GetTokenByIds(tokenupdate.Id, userId)
 	if err != nil {
 		c.JSON(http.StatusOK, gin.H{
@@ -275,6 +415,12 @@ func UpdateToken(c *gin.Context) {
 		})
 		return
 	}
+	// a member removed from the organization keeps its tokens disabled
+	if cleanToken.OrganizationId != 0 {
+		if _, ok := checkOrganizationRole(c, cleanToken.OrganizationId, model.OrganizationRoleMember); !ok {
+			return
+		}
+	}
 	if tokenupdate.Status == common.TokenStatusEnabled {
 		if cleanToken.Status == common.TokenStatusExpired && cleanToken.ExpiredTime <= helper.GetTimestamp() && cleanToken.ExpiredTime != -1 {
 			c.JSON(http.StatusOK, gin.H{
@@ -291,6 +437,7 @@ func UpdateToken(c *gin.Context) {
 			return
 		}
 	}
+	restartBudget := false
 	if tokenupdate.StatusOnly != nil && *tokenupdate.StatusOnly {
 		cleanToken.Status = tokenupdate.Status
 	} else {
@@ -300,8 +447,21 @@ func UpdateToken(c *gin.Context) {
 		cleanToken.RemainQuota = tokenupdate.RemainQuota
 		cleanToken.TokenRemindThreshold = tokenupdate.TokenRemindThreshold
 		cleanToken.UnlimitedQuota = tokenupdate.UnlimitedQuota
+		cleanToken.Models = tokenupdate.Models
+		cleanToken.DeniedModels = tokenupdate.DeniedModels
+		cleanToken.AllowIps = tokenupdate.AllowIps
+		cleanToken.Endpoints = tokenupdate.Endpoints
+		cleanToken.DeniedEndpoints = tokenupdate.DeniedEndpoints
+		cleanToken.RateLimits = tokenupdate.RateLimits
+		if cleanToken.BudgetPeriod != tokenupdate.BudgetPeriod {
+			// a new period starts with the whole budget
+			cleanToken.BudgetPeriod = tokenupdate.BudgetPeriod
+			cleanToken.StartBudgetPeriod()
+			restartBudget = true
+		}
+		cleanToken.BudgetQuota = tokenupdate.BudgetQuota
 	}
-	err = cleanToken.Update()
+	err = cleanToken.Update(restartBudget)
 	if err != nil {
 		c.JSON(http.StatusOK, gin.H{
 			"success": false,
@@ -309,6 +469,7 @@ func UpdateToken(c *gin.Context) {
 		})
 		return
 	}
+	cleanToken.FillBudgetRemain()
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
 		"message": "",
@@ -0,0 +1,322 @@
+package controller
+
+import (
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-contrib/sessions"
+	"github.com/gin-gonic/gin"
+	"github.com/skip2/go-qrcode"
+	"github.com/songquanpeng/one-api/common"
+	"github.com/songquanpeng/one-api/common/config"
+	"github.com/songquanpeng/one-api/common/totp"
+	"github.com/songquanpeng/one-api/model"
+)
+
+// session keys of a login waiting for the two-factor code
+const (
+	twoFactorSessionUserId = "two_factor_user_id"
+	twoFactorSessionTime   = "two_factor_time"
+)
+
+// a login has to be completed with the two-factor code within this time
+const twoFactorLoginTimeout = 5 * time.Minute
+
+type twoFactorCodeRequest struct {
+	Code string `json:"code"`
+}
+
+// startTwoFactorLogin defers the login of the user until the two-factor code is checked,
+// it returns false if the user can log in right away
+func startTwoFactorLogin(user *model.User, c *gin.Context) bool {
+	enabled := model.IsTwoFactorEnabled(user.Id)
+	if !enabled {
+		required, err := model.IsTwoFactorRequired(user.Id)
+		if err != nil {
+			c.JSON(http.StatusOK, gin.H{
+				"success": false,
+				"message": err.Error(),
+			})
+			return true
+		}
+		if !required {
+			return false
+		}
+	}
+	session := sessions.Default(c)
+	session.Set(twoFactorSessionUserId, user.Id)
+	session.Set(twoFactorSessionTime, time.Now().Unix())
+	err := session.Save()
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": "Unable to save session information, please try again",
+		})
+		return true
+	}
+	message := "请输入两步验证码"
+	if !enabled {
+		message = "你所在的角色要求启用两步验证，请先完成绑定"
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": message,
+		"data": gin.H{
+			"two_factor": true,
+			"setup":      !enabled,
+		},
+	})
+	return true
+}
+
+// getTwoFactorLoginUser returns the user of the login waiting for the two-factor code
+func getTwoFactorLoginUser(c *gin.Context) (*model.User, error) {
+	session := sessions.Default(c)
+	userId, ok := session.Get(twoFactorSessionUserId).(int)
+	startTime, _ := session.Get(twoFactorSessionTime).(int64)
+	if !ok || time.Since(time.Unix(startTime, 0)) > twoFactorLoginTimeout {
+		return nil, errors.New("登录已过期，请重新登录")
+	}
+	user, err := model.GetUserById(userId, false)
+	if err != nil {
+		return nil, err
+	}
+	if user.Status != common.UserStatusEnabled {
+		return nil, errors.New("用户已被封禁")
+	}
+	return user, nil
+}
+
+// twoFactorSetupResponse returns the secret with the QR code to scan in an authenticator app
+func twoFactorSetupResponse(c *gin.Context, user *model.User, secret string) {
+	url := totp.URL(config.SystemName, user.Username, secret)
+	png, err := qrcode.Encode(url, qrcode.Medium, 256)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+		"data": gin.H{
+			"secret":  secret,
+			"url":     url,
+			"qr_code": "data:image/png;base64," + base64.StdEncoding.EncodeToString(png),
+		},
+	})
+}
+
+// TwoFactorLoginSetup generates the secret of a user whose role requires two-factor authentication during the login
+func TwoFactorLoginSetup(c *gin.Context) {
+	user, err := getTwoFactorLoginUser(c)
+	var secret string
+	if err == nil {
+		secret, err = model.PrepareTwoFactor(user.Id)
+	}
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+	twoFactorSetupResponse(c, user, secret)
+}
+
+// TwoFactorLogin completes the login with the two-factor code, or a recovery code.
+// Users enrolling during the login get their recovery codes in the response.
+func TwoFactorLogin(c *gin.Context) {
+	var req twoFactorCodeRequest
+	err := c.ShouldBindJSON(&req)
+	if err != nil || req.Code == "" {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": "无效的参数",
+		})
+		return
+	}
+	user, err := getTwoFactorLoginUser(c)
+	var recoveryCodes []string
+	if err == nil {
+		if model.IsTwoFactorEnabled(user.Id) {
+			err = model.VerifyTwoFactor(user.Id, req.Code)
+		} else {
+			recoveryCodes, err = model.EnableTwoFactor(user.Id, req.Code)
+		}
+	}
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+	var extra gin.H
+	if recoveryCodes != nil {
+		extra = gin.H{"recovery_codes": recoveryCodes}
+	}
+	completeLogin(user, c, extra)
+}
+
+func GetTwoFactorStatus(c *gin.Context) {
+	id := c.GetInt("id")
+	required, err := model.IsTwoFactorRequired(id)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+		"data": gin.H{
+			"enabled":  model.IsTwoFactorEnabled(id),
+			"required": required,
+		},
+	})
+}
+
+func SetupTwoFactor(c *gin.Context) {
+	user, err := model.GetUserById(c.GetInt("id"), false)
+	var secret string
+	if err == nil {
+		secret, err = model.PrepareTwoFactor(user.Id)
+	}
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+	twoFactorSetupResponse(c, user, secret)
+}
+
+// EnableTwoFactor confirms the secret generated by SetupTwoFactor with a first code
+func EnableTwoFactor(c *gin.Context) {
+	var req twoFactorCodeRequest
+	err := c.ShouldBindJSON(&req)
+	if err != nil || req.Code == "" {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": "无效的参数",
+		})
+		return
+	}
+	recoveryCodes, err := model.EnableTwoFactor(c.GetInt("id"), req.Code)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+		"data":    recoveryCodes,
+	})
+}
+
+// DisableTwoFactor turns off two-factor authentication, a valid code is required unless the role requires it
+func DisableTwoFactor(c *gin.Context) {
+	var req twoFactorCodeRequest
+	err := c.ShouldBindJSON(&req)
+	if err != nil || req.Code == "" {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": "无效的参数",
+		})
+		return
+	}
+	id := c.GetInt("id")
+	required, err := model.IsTwoFactorRequired(id)
+	if err == nil && required {
+		err = errors.New("你所在的角色要求启用两步验证")
+	}
+	if err == nil {
+		err = model.VerifyTwoFactor(id, req.Code)
+	}
+	if err == nil {
+		err = model.DisableTwoFactor(id)
+	}
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+	})
+}
+
+// RegenerateRecoveryCodes replaces the recovery codes after checking a code
+func RegenerateRecoveryCodes(c *gin.Context) {
+	var req twoFactorCodeRequest
+	err := c.ShouldBindJSON(&req)
+	if err != nil || req.Code == "" {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": "无效的参数",
+		})
+		return
+	}
+	id := c.GetInt("id")
+	var recoveryCodes []string
+	err = model.VerifyTwoFactor(id, req.Code)
+	if err == nil {
+		recoveryCodes, err = model.RegenerateRecoveryCodes(id)
+	}
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+		"data":    recoveryCodes,
+	})
+}
+
+// ResetUserTwoFactor lets an administrator turn off two-factor authentication for a user who lost the device
+func ResetUserTwoFactor(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	var user *model.User
+	if err == nil {
+		user, err = model.GetUserById(id, false)
+	}
+	if err == nil {
+		if !model.CanManageUser(c.GetInt("id"), user.Role, user.RoleId) {
+			err = errors.New("无权更新同权限等级或更高权限等级的用户信息")
+		}
+	}
+	if err == nil {
+		err = model.DisableTwoFactor(id)
+	}
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+	model.RecordLog(id, model.LogTypeSecurity, "管理员重置了两步验证")
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+	})
+}
@@ -3,6 +3,7 @@ package controller
 import (
 	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
 	"strconv"
 
@@ -61,31 +62,38 @@ func Login(c *gin.Context) {
 	setupLogin(&user, c)
 }
 
-// only setup session & cookies
-func setLoginSession(user *model.User, c *gin.Context) {
+// only setup session & cookies, the session is recorded on the server so that it can be revoked
+func setLoginSession(user *model.User, c *gin.Context) error {
 	session := sessions.Default(c)
+	if key, ok := session.Get("session_key").(string); ok {
+		_ = model.RevokeSessionByKey(key)
+	}
+	key, err := model.CreateSession(user.Id, c.Request.UserAgent(), c.ClientIP())
+	if err != nil {
+		return err
+	}
+	session.Delete(twoFactorSessionUserId)
+	session.Delete(twoFactorSessionTime)
+	session.Set("session_key", key)
 	session.Set("id", user.Id)
 	session.Set("username", user.Username)
 	session.Set("role", user.Role)
 	session.Set("status", user.Status)
-	err := session.Save()
-	if err != nil {
-		c.JSON(http.StatusOK, gin.H{
-			"message": "Unable to save session information, please try again",
-			"success": false,
-		})
+	return session.Save()
+}
+
+// setup session & cookies and then return user info,
+// users with two-factor authentication have to send their code to /api/user/login/2fa first
+func setupLogin(user *model.User, c *gin.Context) {
+	if startTwoFactorLogin(user, c) {
 		return
 	}
+	completeLogin(user, c, nil)
 }
 
-// setup session & cookies and then return user info
-func setupLogin(user *model.User, c *gin.Context) {
-	session := sessions.Default(c)
-	session.Set("id", user.Id)
-	session.Set("username", user.Username)
-	session.Set("role", user.Role)
-	session.Set("status", user.Status)
-	err := session.Save()
+// completeLogin creates the session of the user, extra fields are added to the response
+func completeLogin(user *model.User, c *gin.Context, extra gin.H) {
+	err := setLoginSession(user, c)
 	if err != nil {
 		c.JSON(http.StatusOK, gin.H{
 			"message": "Unable to save session information, please try again",
@@ -100,15 +108,29 @@ func setupLogin(user *model.User, c *gin.Context) {
 		Role:        user.Role,
 		Status:      user.Status,
 	}
-	c.JSON(http.StatusOK, gin.H{
+	response := gin.H{
 		"message": "",
 		"success": true,
 		"data":    cleanUser,
-	})
+	}
+	for key, value := range extra {
+		response[key] = value
+	}
+	c.JSON(http.StatusOK, response)
 }
 
 func Logout(c *gin.Context) {
 	session := sessions.Default(c)
+	if key, ok := session.Get("session_key").(string); ok {
+		err := model.RevokeSessionByKey(key)
+		if err != nil {
+			c.JSON(http.StatusOK, gin.H{
+				"message": err.Error(),
+				"success": false,
+			})
+			return
+		}
+	}
 	session.Clear()
 	err := session.Save()
 	if err != nil {
@@ -287,8 +309,7 @@ func GetUser(c *gin.Context) {
 		})
 		return
 	}
-	myRole := c.GetInt("role")
-	if myRole <= user.Role && myRole != common.RoleRootUser {
+	if !model.CanManageUser(c.GetInt("id"), user.Role, user.RoleId) {
 		c.JSON(http.StatusOK, gin.H{
 			"success": false,
 			"message": "Do not have the right to obtain information about users of the same level or higher",
@@ -410,7 +431,10 @@ func GetSelf(c *gin.Context) {
 
 func UpdateUser(c *gin.Context) {
 	var updatedUser model.User
-	err := json.NewDecoder(c.Request.Body).Decode(&updatedUser)
+	body, err := io.ReadAll(c.Request.Body)
+	if err == nil {
+		err = json.Unmarshal(body, &updatedUser)
+	}
 	if err != nil || updatedUser.Id == 0 {
 		c.JSON(http.StatusOK, gin.H{
 			"success": false,
@@ -436,15 +460,15 @@ func UpdateUser(c *gin.Context) {
 		})
 		return
 	}
-	myRole := c.GetInt("role")
-	if myRole <= originUser.Role && myRole != common.RoleRootUser {
+	myId := c.GetInt("id")
+	if !model.CanManageUser(myId, originUser.Role, originUser.RoleId) {
 		c.JSON(http.StatusOK, gin.H{
 			"success": false,
 			"message": "无权更新同权限等级或更高权限等级的用户信息",
 		})
 		return
 	}
-	if myRole <= updatedUser.Role && myRole != common.RoleRootUser {
+	if !model.CanManageUser(myId, updatedUser.Role, originUser.RoleId) {
 		c.JSON(http.StatusOK, gin.H{
 			"success": false,
 			"message": "无权将其他用户权限等级提升到大于等于自己的权限等级",
@@ -462,6 +486,54 @@ func UpdateUser(c *gin.Context) {
 		})
 		return
 	}
+	if updatePassword {
+		// a new password logs the user out everywhere, the access token is revoked as well
+		err = model.RevokeAccessToken(updatedUser.Id)
+		if err == nil {
+			_, err = model.RevokeUserSessions(updatedUser.Id, 0)
+		}
+		if err != nil {
+			c.JSON(http.StatusOK, gin.H{
+				"success": false,
+				"message": err.Error(),
+			})
+			return
+		}
+	}
+	// rate limits are only written when sent, so that they can be cleared without being reset by other forms
+	var rateLimits struct {
+		RPM         *int `json:"rpm_limit"`
+		TPM         *int `json:"tpm_limit"`
+		Concurrency *int `json:"concurrency_limit"`
+	}
+	_ = json.Unmarshal(body, &rateLimits)
+	if rateLimits.RPM != nil || rateLimits.TPM != nil || rateLimits.Concurrency != nil {
+		limits := originUser.RateLimits
+		if rateLimits.RPM != nil {
+			limits.RPM = *rateLimits.RPM
+		}
+		if rateLimits.TPM != nil {
+			limits.TPM = *rateLimits.TPM
+		}
+		if rateLimits.Concurrency != nil {
+			limits.Concurrency = *rateLimits.Concurrency
+		}
+		if err := model.UpdateUserRateLimits(updatedUser.Id, limits); err != nil {
+			c.JSON(http.StatusOK, gin.H{
+				"success": false,
+				"message": err.Error(),
+			})
+			return
+		}
+	}
+	if afterUser, err := model.GetUserById(updatedUser.Id, false); err == nil {
+		before, after := model.AuditSnapshot(originUser), model.AuditSnapshot(afterUser)
+		if updatePassword {
+			// passwords are not loaded, this only records that it changed, the values are redacted
+			before["password"], after["password"] = "old", "new"
+		}
+		setAuditChange(c, "user.update", "user", updatedUser.Id, before, after)
+	}
 	if originUser.Quota != updatedUser.Quota {
 		model.RecordLog(originUser.Id, model.LogTypeManage, fmt.Sprintf("管理员将用户额度从 %s修改为 %s", common.LogQuota(originUser.Quota), common.LogQuota(updatedUser.Quota)))
 	}
@@ -515,6 +587,20 @@ func UpdateSelf(c *gin.Context) {
 		})
 		return
 	}
+	if updatePassword {
+		// a new password logs the user out everywhere else, the access token is revoked as well
+		err = model.RevokeAccessToken(cleanUser.Id)
+		if err == nil {
+			_, err = model.RevokeUserSessions(cleanUser.Id, c.GetInt("session_id"))
+		}
+		if err != nil {
+			c.JSON(http.StatusOK, gin.H{
+				"success": false,
+				"message": err.Error(),
+			})
+			return
+		}
+	}
 
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
@@ -542,7 +628,7 @@ func BatchDelteUser(c *gin.Context) {
 		})
 		return
 	}
-	myRole := c.GetInt("role")
+	myId := c.GetInt("id")
 
 	// 对于每个ID，检查当前用户是否有权限删除
 	for _, id := range request.Ids {
@@ -554,7 +640,7 @@ func BatchDelteUser(c *gin.Context) {
 			})
 			return
 		}
-		if myRole <= originUser.Role {
+		if originUser.Role >= common.RoleRootUser || !model.CanManageUser(myId, originUser.Role, originUser.RoleId) {
 			c.JSON(http.StatusOK, gin.H{
 				"success": false,
 				"message": "无权删除同权限等级或更高权限等级的用户",
@@ -596,8 +682,7 @@ func DeleteUser(c *gin.Context) {
 		})
 		return
 	}
-	myRole := c.GetInt("role")
-	if myRole <= originUser.Role {
+	if originUser.Role >= common.RoleRootUser || !model.CanManageUser(c.GetInt("id"), originUser.Role, originUser.RoleId) {
 		c.JSON(http.StatusOK, gin.H{
 			"success": false,
 			"message": "无权删除同权限等级或更高权限等级的用户",
@@ -666,8 +751,7 @@ func CreateUser(c *gin.Context) {
 	if user.DisplayName == "" {
 		user.DisplayName = user.Username
 	}
-	myRole := c.GetInt("role")
-	if user.Role >= myRole {
+	if !model.CanManageUser(c.GetInt("id"), user.Role, 0) {
 		c.JSON(http.StatusOK, gin.H{
 			"success": false,
 			"message": "无法创建权限大于等于自己的用户",
@@ -724,14 +808,15 @@ func ManageUser(c *gin.Context) {
 		})
 		return
 	}
-	myRole := c.GetInt("role")
-	if myRole <= user.Role && myRole != common.RoleRootUser {
+	myId := c.GetInt("id")
+	if !model.CanManageUser(myId, user.Role, user.RoleId) {
 		c.JSON(http.StatusOK, gin.H{
 			"success": false,
 			"message": "无权更新同权限等级或更高权限等级的用户信息",
 		})
 		return
 	}
+	before := user
 	switch req.Action {
 	case "disable":
 		user.Status = common.UserStatusDisabled
@@ -760,7 +845,7 @@ func ManageUser(c *gin.Context) {
 			return
 		}
 	case "promote":
-		if myRole != common.RoleRootUser {
+		if !model.CanManageUser(myId, common.RoleAdminUser, user.RoleId) {
 			c.JSON(http.StatusOK, gin.H{
 				"success": false,
 				"message": "普通管理员用户无法提升其他用户为管理员",
@@ -800,6 +885,16 @@ func ManageUser(c *gin.Context) {
 		})
 		return
 	}
+	if user.Status == common.UserStatusDisabled {
+		if _, err := model.RevokeUserSessions(user.Id, 0); err != nil {
+			c.JSON(http.StatusOK, gin.H{
+				"success": false,
+				"message": err.Error(),
+			})
+			return
+		}
+	}
+	setAuditChange(c, "user.manage."+req.Action, "user", user.Id, before, user)
 	clearUser := model.User{
 		Role:   user.Role,
 		Status: user.Status,
@@ -855,7 +950,8 @@ func EmailBind(c *gin.Context) {
 }
 
 type topUpRequest struct {
-	Key string `json:"key"`
+	Key            string `json:"key"`
+	OrganizationId int    `json:"organization_id"` // credits the organization instead of the user
 }
 
 func TopUp(c *gin.Context) {
@@ -869,7 +965,15 @@ func TopUp(c *gin.Context) {
 		return
 	}
 	id := c.GetInt("id")
-	quota, err := model.Redeem(req.Key, id)
+	var quota int64
+	if req.OrganizationId != 0 {
+		if _, ok := checkOrganizationRole(c, req.OrganizationId, model.OrganizationRoleAdmin); !ok {
+			return
+		}
+		quota, err = model.RedeemForOrganization(req.Key, id, req.OrganizationId)
+	} else {
+		quota, err = model.Redeem(req.Key, id)
+	}
 	if err != nil {
 		c.JSON(http.StatusOK, gin.H{
 			"success": false,
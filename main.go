@@ -5,12 +5,14 @@ import (
 	"fmt"
 	"os"
 	"strconv"
+	"strings"
 
 	"github.com/gin-contrib/sessions"
 	"github.com/gin-contrib/sessions/cookie"
 	"github.com/gin-gonic/gin"
 	"github.com/songquanpeng/one-api/common"
 	"github.com/songquanpeng/one-api/common/config"
+	"github.com/songquanpeng/one-api/common/env"
 	"github.com/songquanpeng/one-api/common/logger"
 	"github.com/songquanpeng/one-api/controller"
 	"github.com/songquanpeng/one-api/middleware"
@@ -56,6 +58,35 @@ func main() {
 			logger.FatalLog("failed to close database: " + err.Error())
 		}
 	}()
+	if *common.RotateMasterKey {
+		newMasterKey, err := env.Secret("CHANNEL_NEW_MASTER_KEY")
+		if err != nil {
+			logger.FatalLog("failed to read the new master key: " + err.Error())
+		}
+		count, err := model.RotateChannelMasterKey(newMasterKey)
+		if err != nil {
+			logger.FatalLog("failed to rotate the master key: " + err.Error())
+		}
+		logger.SysLog(fmt.Sprintf("the keys of %d channels are now encrypted with the new master key, set CHANNEL_MASTER_KEY to it before restarting", count))
+		return
+	}
+	if *common.ExportChannelsPath != "" {
+		err = controller.ExportChannelsToFile(*common.ExportChannelsPath, *common.ChannelKeyMode, os.Getenv("CHANNEL_TRANSFER_PASSPHRASE"))
+		if err != nil {
+			logger.FatalLog("failed to export channels: " + err.Error())
+		}
+		return
+	}
+	if *common.ImportChannelsPath != "" {
+		err = controller.ImportChannelsFromFile(*common.ImportChannelsPath, controller.ChannelImportOptions{
+			Passphrase:         os.Getenv("CHANNEL_TRANSFER_PASSPHRASE"),
+			AllowUnknownModels: *common.AllowUnknownModels,
+		})
+		if err != nil {
+			logger.FatalLog("failed to import channels: " + err.Error())
+		}
+		return
+	}
 
 	// Initialize Redis
 	err = common.InitRedisClient()
@@ -65,6 +96,13 @@ func main() {
 
 	// Initialize options
 	model.InitOptionMap()
+	if config.ConfigFile != "" {
+		err = model.ReconcileManagedConfig()
+		if err != nil {
+			logger.FatalLog("failed to apply config file: " + err.Error())
+		}
+		go model.WatchManagedConfigReload()
+	}
 	logger.SysLog(fmt.Sprintf("using theme %s", config.Theme))
 	if common.RedisEnabled {
 		// for compatibility with old versions
@@ -79,6 +117,7 @@ func main() {
 		go model.SyncOptions(config.SyncFrequency)
 		go model.SyncChannelCache(config.SyncFrequency)
 	}
+	go model.SyncChannelSchedules()
 	if os.Getenv("CHANNEL_TEST_FREQUENCY") != "" {
 		frequency, err := strconv.Atoi(os.Getenv("CHANNEL_TEST_FREQUENCY"))
 		if err != nil {
@@ -86,6 +125,20 @@ func main() {
 		}
 		go controller.AutomaticallyTestChannels(frequency)
 	}
+	if os.Getenv("CHANNEL_UPDATE_FREQUENCY") != "" {
+		frequency, err := strconv.Atoi(os.Getenv("CHANNEL_UPDATE_FREQUENCY"))
+		if err != nil {
+			logger.FatalLog("failed to parse CHANNEL_UPDATE_FREQUENCY: " + err.Error())
+		}
+		go controller.AutomaticallyUpdateChannels(frequency)
+	}
+	if os.Getenv("CHANNEL_MODEL_SYNC_FREQUENCY") != "" {
+		frequency, err := strconv.Atoi(os.Getenv("CHANNEL_MODEL_SYNC_FREQUENCY"))
+		if err != nil {
+			logger.FatalLog("failed to parse CHANNEL_MODEL_SYNC_FREQUENCY: " + err.Error())
+		}
+		go controller.AutomaticallySyncChannelModels(frequency)
+	}
 	if os.Getenv("BATCH_UPDATE_ENABLED") == "true" {
 		config.BatchUpdateEnabled = true
 		logger.SysLog("batch update enabled with interval " + strconv.Itoa(config.BatchUpdateInterval) + "s")
@@ -101,6 +154,27 @@ func main() {
 
 	// Initialize HTTP server
 	server := gin.New()
+	// without trusted proxies the forwarded headers are ignored and the client IP is the connection address
+	var trustedProxies []string
+	for _, proxy := range strings.Split(config.TrustedProxies, ",") {
+		if proxy = strings.TrimSpace(proxy); proxy != "" {
+			trustedProxies = append(trustedProxies, proxy)
+		}
+	}
+	if len(trustedProxies) == 0 {
+		logger.SysError("TRUSTED_PROXIES is not set, the forwarded headers are ignored and the client IP is the address of the connection. " +
+			"Behind a reverse proxy every request then comes from the proxy, which breaks the IP allowlists and the IP rate limits, please set it to the address of the proxy.")
+	}
+	err = server.SetTrustedProxies(trustedProxies)
+	if err != nil {
+		logger.FatalLog("failed to parse TRUSTED_PROXIES: " + err.Error())
+	}
+	server.RemoteIPHeaders = nil
+	for _, header := range strings.Split(config.RemoteIPHeaders, ",") {
+		if header = strings.TrimSpace(header); header != "" {
+			server.RemoteIPHeaders = append(server.RemoteIPHeaders, header)
+		}
+	}
 	server.Use(gin.Recovery())
 	// This will cause SSE not to work!!!
 	//server.Use(gzip.Gzip(gzip.DefaultCompression))
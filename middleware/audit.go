@@ -0,0 +1,100 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/songquanpeng/one-api/common/logger"
+	"github.com/songquanpeng/one-api/model"
+)
+
+// request bodies larger than this are not recorded, the response is only read for its success and message
+const (
+	auditMaxRequestBody  = 64 * 1024
+	auditMaxResponseBody = 4 * 1024
+)
+
+type auditResponseWriter struct {
+	gin.ResponseWriter
+	body *bytes.Buffer
+}
+
+func (w *auditResponseWriter) Write(data []byte) (int, error) {
+	if remaining := auditMaxResponseBody - w.body.Len(); remaining > 0 {
+		if len(data) < remaining {
+			remaining = len(data)
+		}
+		w.body.Write(data[:remaining])
+	}
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *auditResponseWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+func isMutatingMethod(method string) bool {
+	return method == http.MethodPost || method == http.MethodPut || method == http.MethodPatch || method == http.MethodDelete
+}
+
+// AuditLog records the mutating calls of the management API with their actor, target and outcome.
+// Handlers describe their change by setting a *model.AuditChange under model.AuditChangeKey,
+// the JSON body of the request is recorded for the other calls.
+func AuditLog() func(c *gin.Context) {
+	return func(c *gin.Context) {
+		if !isMutatingMethod(c.Request.Method) {
+			c.Next()
+			return
+		}
+		var requestBody []byte
+		if strings.HasPrefix(c.ContentType(), "application/json") && c.Request.ContentLength <= auditMaxRequestBody {
+			body, err := io.ReadAll(io.LimitReader(c.Request.Body, auditMaxRequestBody+1))
+			if err == nil {
+				c.Request.Body = io.NopCloser(io.MultiReader(bytes.NewReader(body), c.Request.Body))
+				if len(body) <= auditMaxRequestBody {
+					requestBody = body
+				}
+			}
+		}
+		writer := &auditResponseWriter{ResponseWriter: c.Writer, body: &bytes.Buffer{}}
+		c.Writer = writer
+		c.Next()
+
+		path := c.FullPath()
+		if path == "" {
+			path = c.Request.URL.Path
+		}
+		auditLog := &model.AuditLog{
+			ActorId:    c.GetInt("id"),
+			ActorName:  c.GetString("username"),
+			Action:     c.Request.Method + " " + path,
+			TargetType: strings.Split(strings.TrimPrefix(path, "/api/"), "/")[0],
+			TargetId:   c.Param("id"),
+			Method:     c.Request.Method,
+			Path:       c.Request.URL.Path,
+			After:      string(requestBody),
+			Success:    writer.Status() < http.StatusBadRequest,
+			Ip:         c.ClientIP(),
+			RequestId:  c.GetString(logger.RequestIdKey),
+		}
+		var response struct {
+			Success *bool  `json:"success"`
+			Message string `json:"message"`
+		}
+		if json.Unmarshal(writer.body.Bytes(), &response) == nil {
+			if response.Success != nil {
+				auditLog.Success = auditLog.Success && *response.Success
+			}
+			auditLog.Message = response.Message
+		}
+		var change *model.AuditChange
+		if value, ok := c.Get(model.AuditChangeKey); ok {
+			change, _ = value.(*model.AuditChange)
+		}
+		model.RecordAuditLog(auditLog, change)
+	}
+}
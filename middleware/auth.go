@@ -1,8 +1,10 @@
 package middleware
 
 import (
+	"fmt"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/gin-contrib/sessions"
 	"github.com/gin-gonic/gin"
@@ -12,13 +14,29 @@ import (
 	"github.com/songquanpeng/one-api/model"
 )
 
-func authHelper(c *gin.Context, minRole int) {
+// authHelper authenticates the user by session or access token, and checks the permission unless it is empty
+func authHelper(c *gin.Context, minRole int, permission string) {
 	session := sessions.Default(c)
 	username := session.Get("username")
 	role := session.Get("role")
 	id := session.Get("id")
 	status := session.Get("status")
-	if username == nil {
+	if username != nil {
+		// the cookie is only valid as long as its session is recorded on the server
+		key, _ := session.Get("session_key").(string)
+		loginSession, err := model.ValidateSession(key, c.ClientIP())
+		if err != nil {
+			session.Clear()
+			_ = session.Save()
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"success": false,
+				"message": "Not logged in or the session has been revoked, please log in again",
+			})
+			c.Abort()
+			return
+		}
+		c.Set("session_id", loginSession.Id)
+	} else {
 		// Check access token
 		accessToken := c.Request.Header.Get("Authorization")
 		if accessToken == "" {
@@ -64,6 +82,14 @@ func authHelper(c *gin.Context, minRole int) {
 		c.Abort()
 		return
 	}
+	if permission != "" && !model.UserHasPermission(id.(int), permission) {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": fmt.Sprintf("You do not have permission to perform this operation, %s is required.", permission),
+		})
+		c.Abort()
+		return
+	}
 	c.Set("username", username)
 	c.Set("role", role)
 	c.Set("id", id)
@@ -72,19 +98,14 @@ func authHelper(c *gin.Context, minRole int) {
 
 func UserAuth() func(c *gin.Context) {
 	return func(c *gin.Context) {
-		authHelper(c, common.RoleCommonUser)
+		authHelper(c, common.RoleCommonUser, "")
 	}
 }
 
-func AdminAuth() func(c *gin.Context) {
+// PermissionAuth lets through the users whose role grants the permission
+func PermissionAuth(permission string) func(c *gin.Context) {
 	return func(c *gin.Context) {
-		authHelper(c, common.RoleAdminUser)
-	}
-}
-
-func RootAuth() func(c *gin.Context) {
-	return func(c *gin.Context) {
-		authHelper(c, common.RoleRootUser)
+		authHelper(c, common.RoleCommonUser, permission)
 	}
 }
 
@@ -109,6 +130,17 @@ func TokenAuth() func(c *gin.Context) {
 			abortWithMessage(c, http.StatusUnauthorized, err.Error())
 			return
 		}
+		if token.IsPreviousKey(key) {
+			model.RecordSecurityLog(c.Request.Context(), token.UserId, token.Name,
+				fmt.Sprintf("令牌通过轮换前的旧密钥访问，旧密钥将于 %s 失效，请求路径 %s",
+					time.Unix(token.PreviousKeyExpiredTime, 0).Format("2006-01-02 15:04:05"), c.Request.URL.Path))
+		}
+		if !token.IsIPAllowed(c.ClientIP()) {
+			model.RecordSecurityLog(c.Request.Context(), token.UserId, token.Name,
+				fmt.Sprintf("令牌被拒绝：来源 IP %s 不在允许列表中，请求路径 %s", c.ClientIP(), c.Request.URL.Path))
+			abortWithMessage(c, http.StatusForbidden, fmt.Sprintf("IP %s is not allowed to use this token", c.ClientIP()))
+			return
+		}
 		userEnabled, err := model.CacheIsUserEnabled(token.UserId)
 		if err != nil {
 			abortWithMessage(c, http.StatusInternalServerError, err.Error())
@@ -118,9 +150,26 @@ func TokenAuth() func(c *gin.Context) {
 			abortWithMessage(c, http.StatusForbidden, "User has been banned")
 			return
 		}
+		if token.OrganizationId != 0 {
+			isMember, err := model.IsOrganizationMember(token.OrganizationId, token.UserId)
+			if err != nil {
+				abortWithMessage(c, http.StatusInternalServerError, err.Error())
+				return
+			}
+			if !isMember {
+				abortWithMessage(c, http.StatusForbidden, "The user is no longer a member of the organization of this token")
+				return
+			}
+		}
 		c.Set("id", token.UserId)
 		c.Set("token_id", token.Id)
 		c.Set("token_name", token.Name)
+		c.Set("organization_id", token.OrganizationId)
+		c.Set("token_models", token.Models)
+		c.Set("token_denied_models", token.DeniedModels)
+		c.Set("token_endpoints", token.Endpoints)
+		c.Set("token_denied_endpoints", token.DeniedEndpoints)
+		c.Set("token_rate_limits", token.RateLimits)
 		if len(parts) > 1 {
 			if model.IsAdmin(token.UserId) {
 				c.Set("specific_channel_id", parts[1])
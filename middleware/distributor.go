@@ -26,6 +26,83 @@ func Distribute() func(c *gin.Context) {
 		c.Set("group", userGroup)
 		var requestModel string
 		var channel *model.Channel
+		shouldSelectChannel := true
+		var modelRequest ModelRequest
+		var err error
+		if strings.HasPrefix(c.Request.URL.Path, "/mj") {
+			relayMode := relayconstant.Path2RelayModeMidjourney((c.Request.URL.Path))
+			if !model.IsEndpointAllowedForToken(c.GetString("token_endpoints"), c.GetString("token_denied_endpoints"), relayconstant.RelayModeName(relayMode)) {
+				abortWithMidjourneyMessage(c, http.StatusForbidden, common.MjErrorUnknown, fmt.Sprintf("This token is not allowed to use %s", c.Request.URL.Path))
+				return
+			}
+			if relayMode == relayconstant.RelayModeMidjourneyTaskFetch ||
+				relayMode == relayconstant.RelayModeMidjourneyTaskFetchByCondition ||
+				relayMode == relayconstant.RelayModeMidjourneyNotify ||
+				relayMode == relayconstant.RelayModeMidjourneyTaskImageSeed {
+				shouldSelectChannel = false
+			} else {
+				midjourneyRequest := midjourney.MidjourneyRequest{}
+				err = common.UnmarshalBodyReusable(c, &midjourneyRequest)
+				if err != nil {
+					abortWithMidjourneyMessage(c, http.StatusBadRequest, common.MjErrorUnknown, "无效的请求, "+err.Error())
+					return
+				}
+				midjourneyModel, mjErr, success := midjourney.GetMjRequestModel(relayMode, &midjourneyRequest)
+				if mjErr != nil {
+					abortWithMidjourneyMessage(c, http.StatusBadRequest, mjErr.Response.Code, mjErr.Response.Description)
+					return
+				}
+				if midjourneyModel == "" {
+					if !success {
+						abortWithMidjourneyMessage(c, http.StatusBadRequest, common.MjErrorUnknown, "无效的请求, 无法解析模型")
+						return
+					} else {
+						// task fetch, task fetch by condition, notify
+						shouldSelectChannel = false
+					}
+				}
+				modelRequest.Model = midjourneyModel
+			}
+			c.Set("relay_mode", relayMode)
+			logger.SysLog(fmt.Sprintf("Use Mj model: %+v\n", relayMode))
+		} else {
+			err = common.UnmarshalBodyReusable(c, &modelRequest)
+			if err != nil {
+				abortWithMessage(c, http.StatusBadRequest, "Invalid request")
+				return
+			}
+		}
+		if strings.HasPrefix(c.Request.URL.Path, "/v1/moderations") {
+			if modelRequest.Model == "" {
+				modelRequest.Model = "text-moderation-stable"
+			}
+		}
+		if strings.HasSuffix(c.Request.URL.Path, "embeddings") {
+			if modelRequest.Model == "" {
+				modelRequest.Model = c.Param("model")
+			}
+		}
+		if strings.HasPrefix(c.Request.URL.Path, "/v1/images/generations") {
+			if modelRequest.Model == "" {
+				modelRequest.Model = "dall-e-2"
+			}
+		}
+		if strings.HasPrefix(c.Request.URL.Path, "/v1/audio/transcriptions") || strings.HasPrefix(c.Request.URL.Path, "/v1/audio/translations") {
+			if modelRequest.Model == "" {
+				modelRequest.Model = "whisper-1"
+			}
+		}
+		requestModel = modelRequest.Model
+		// checked before the channel is chosen, the model scopes of the token also apply to a specific channel
+		if requestModel != "" && !model.IsModelAllowedForToken(c.GetString("token_models"), c.GetString("token_denied_models"), requestModel) {
+			message := fmt.Sprintf("This token is not allowed to use model %s", requestModel)
+			if strings.HasPrefix(c.Request.URL.Path, "/mj") {
+				abortWithMidjourneyMessage(c, http.StatusForbidden, common.MjErrorUnknown, message)
+			} else {
+				abortWithMessage(c, http.StatusForbidden, message)
+			}
+			return
+		}
 		channelId, ok := c.Get("specific_channel_id")
 		if ok {
 			id, err := strconv.Atoi(channelId.(string))
@@ -42,97 +119,44 @@ func Distribute() func(c *gin.Context) {
 				abortWithMessage(c, http.StatusForbidden, "The channel has been disabled")
 				return
 			}
-		} else {
-			shouldSelectChannel := true
+		} else if shouldSelectChannel {
 			// Select a channel for the user
-			var modelRequest ModelRequest
-			var err error
-			if strings.HasPrefix(c.Request.URL.Path, "/mj") {
-				relayMode := relayconstant.Path2RelayModeMidjourney((c.Request.URL.Path))
-				if relayMode == relayconstant.RelayModeMidjourneyTaskFetch ||
-					relayMode == relayconstant.RelayModeMidjourneyTaskFetchByCondition ||
-					relayMode == relayconstant.RelayModeMidjourneyNotify ||
-					relayMode == relayconstant.RelayModeMidjourneyTaskImageSeed {
-					shouldSelectChannel = false
-				} else {
-					midjourneyRequest := midjourney.MidjourneyRequest{}
-					err = common.UnmarshalBodyReusable(c, &midjourneyRequest)
-					if err != nil {
-						abortWithMidjourneyMessage(c, http.StatusBadRequest, common.MjErrorUnknown, "无效的请求, "+err.Error())
-						return
-					}
-					midjourneyModel, mjErr, success := midjourney.GetMjRequestModel(relayMode, &midjourneyRequest)
-					if mjErr != nil {
-						abortWithMidjourneyMessage(c, http.StatusBadRequest, mjErr.Response.Code, mjErr.Response.Description)
-						return
-					}
-					if midjourneyModel == "" {
-						if !success {
-							abortWithMidjourneyMessage(c, http.StatusBadRequest, common.MjErrorUnknown, "无效的请求, 无法解析模型")
-							return
-						} else {
-							// task fetch, task fetch by condition, notify
-							shouldSelectChannel = false
-						}
-					}
-					modelRequest.Model = midjourneyModel
-				}
-				c.Set("relay_mode", relayMode)
-				logger.SysLog(fmt.Sprintf("Use Mj model: %+v\n", relayMode))
-			} else {
-				err = common.UnmarshalBodyReusable(c, &modelRequest)
-				if err != nil {
-					abortWithMessage(c, http.StatusBadRequest, "Invalid request")
-					return
-				}
-			}
-			if strings.HasPrefix(c.Request.URL.Path, "/v1/moderations") {
-				if modelRequest.Model == "" {
-					modelRequest.Model = "text-moderation-stable"
-				}
-			}
-			if strings.HasSuffix(c.Request.URL.Path, "embeddings") {
-				if modelRequest.Model == "" {
-					modelRequest.Model = c.Param("model")
-				}
-			}
-			if strings.HasPrefix(c.Request.URL.Path, "/v1/images/generations") {
-				if modelRequest.Model == "" {
-					modelRequest.Model = "dall-e-2"
-				}
-			}
-			if strings.HasPrefix(c.Request.URL.Path, "/v1/audio/transcriptions") || strings.HasPrefix(c.Request.URL.Path, "/v1/audio/translations") {
-				if modelRequest.Model == "" {
-					modelRequest.Model = "whisper-1"
+			channel, err = model.CacheGetRandomSatisfiedChannel(userGroup, modelRequest.Model, false)
+			if err != nil {
+				message := fmt.Sprintf("There are no channels available for model %s under the current group %s", userGroup, modelRequest.Model)
+				if channel != nil {
+					logger.SysError(fmt.Sprintf("Channel does not exist：%d", channel.Id))
+					message = "Database consistency has been violated, please contact the administrator"
 				}
+				abortWithMessage(c, http.StatusServiceUnavailable, message)
+				return
 			}
-			requestModel = modelRequest.Model
-			if shouldSelectChannel {
-				channel, err = model.CacheGetRandomSatisfiedChannel(userGroup, modelRequest.Model, false)
-				if err != nil {
-					message := fmt.Sprintf("There are no channels available for model %s under the current group %s", userGroup, modelRequest.Model)
-					if channel != nil {
-						logger.SysError(fmt.Sprintf("Channel does not exist：%d", channel.Id))
-						message = "Database consistency has been violated, please contact the administrator"
-					}
-					abortWithMessage(c, http.StatusServiceUnavailable, message)
-					return
-				}
-				SetupContextForSelectedChannel(c, channel, requestModel)
+			err = SetupContextForSelectedChannel(c, channel, requestModel)
+			if err != nil {
+				abortWithMessage(c, http.StatusInternalServerError, "The channel is misconfigured, please contact the administrator")
+				return
 			}
 		}
 		c.Next()
 	}
 }
 
-func SetupContextForSelectedChannel(c *gin.Context, channel *model.Channel, modelName string) {
+// SetupContextForSelectedChannel puts the settings of the channel in the context of the request,
+// it fails when the secrets of the channel cannot be decrypted
+func SetupContextForSelectedChannel(c *gin.Context, channel *model.Channel, modelName string) error {
 	c.Set("channel", channel.Type)
 	c.Set("channel_id", channel.Id)
 	c.Set("channel_name", channel.Name)
 	c.Set("model_mapping", channel.GetModelMapping())
 	c.Set("original_model", modelName) // for retry
 	logger.SysLog(fmt.Sprintf("channel:%d;requestModel:%s\n", channel.Id, modelName))
-	c.Request.Header.Set("Authorization", fmt.Sprintf("Bearer %s", channel.Key))
+	// channel keys are encrypted at rest, they are only decrypted here to build the upstream request
+	key, err := channel.DecryptKey()
+	if err != nil {
+		logger.SysError(fmt.Sprintf("failed to decrypt the key of channel #%d: %s", channel.Id, err.Error()))
+		return err
+	}
+	c.Request.Header.Set("Authorization", fmt.Sprintf("Bearer %s", key))
 	c.Set("base_url", channel.GetBaseURL())
 	// this is for backward compatibility
 	switch channel.Type {
@@ -147,8 +171,13 @@ func SetupContextForSelectedChannel(c *gin.Context, channel *model.Channel, mode
 	case common.ChannelTypeAli:
 		c.Set(common.ConfigKeyPlugin, channel.Other)
 	}
-	cfg, _ := channel.LoadConfig()
+	cfg, err := channel.LoadConfig()
+	if err != nil {
+		logger.SysError(fmt.Sprintf("failed to load the config of channel #%d: %s", channel.Id, err.Error()))
+		return err
+	}
 	for k, v := range cfg {
 		c.Set(common.ConfigKeyPrefix+k, v)
 	}
+	return nil
 }
@@ -0,0 +1,108 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-redis/redis/v8"
+	"github.com/songquanpeng/one-api/common"
+	"github.com/songquanpeng/one-api/common/helper"
+	"github.com/songquanpeng/one-api/common/logger"
+	"github.com/songquanpeng/one-api/common/ratelimit"
+	"github.com/songquanpeng/one-api/model"
+)
+
+// redisRelayCounter shares the counters of the relay rate limits between all nodes
+type redisRelayCounter struct{}
+
+func (redisRelayCounter) IncrBy(key string, value int64, expiration time.Duration) (int64, error) {
+	ctx := context.Background()
+	result, err := common.RDB.IncrBy(ctx, key, value).Result()
+	if err != nil {
+		return 0, err
+	}
+	common.RDB.Expire(ctx, key, expiration)
+	return result, nil
+}
+
+func (redisRelayCounter) Get(key string) (int64, error) {
+	value, err := common.RDB.Get(context.Background(), key).Int64()
+	if errors.Is(err, redis.Nil) {
+		return 0, nil
+	}
+	return value, err
+}
+
+var inMemoryRelayCounter ratelimit.MemoryCounter
+
+func getRelayCounter() ratelimit.Counter {
+	if common.RedisEnabled {
+		return redisRelayCounter{}
+	}
+	return &inMemoryRelayCounter
+}
+
+func abortWithRateLimit(c *gin.Context, err *ratelimit.Error) {
+	c.Header("Retry-After", strconv.FormatInt(err.RetryAfter, 10))
+	c.JSON(http.StatusTooManyRequests, gin.H{
+		"error": gin.H{
+			"message": helper.MessageWithRequestId(err.Message, c.GetString(logger.RequestIdKey)),
+			"type":    err.LimitType,
+			"code":    "rate_limit_exceeded",
+		},
+	})
+	c.Abort()
+	logger.Warn(c.Request.Context(), err.Message)
+}
+
+// RelayRateLimit enforces the RPM, TPM and concurrency limits of the token, its user and the group of the user.
+// Only the text relay (chat, completions, embeddings...) reports the tokens it used, so the TPM limit
+// counts these requests, audio, image and Midjourney requests are limited by RPM and concurrency only.
+func RelayRateLimit() func(c *gin.Context) {
+	return func(c *gin.Context) {
+		userId := c.GetInt("id")
+		scopes := make([]ratelimit.Scope, 0, 3)
+		addScope := func(name string, id string, limits model.RateLimits) {
+			if !limits.IsZero() {
+				scopes = append(scopes, ratelimit.Scope{Name: name, Id: id, RPM: limits.RPM, TPM: limits.TPM, Concurrency: limits.Concurrency})
+			}
+		}
+		if token, ok := c.Get("token_rate_limits"); ok {
+			addScope("token", strconv.Itoa(c.GetInt("token_id")), token.(model.RateLimits))
+		}
+		userLimits, err := model.CacheGetUserRateLimits(userId)
+		if err != nil {
+			logger.Error(c.Request.Context(), "failed to get user rate limits: "+err.Error())
+		}
+		addScope("user", strconv.Itoa(userId), userLimits)
+		// the limits of a group apply to each of its users
+		group, err := model.CacheGetUserGroup(userId)
+		if err == nil {
+			addScope("group", fmt.Sprintf("%s:%d", group, userId), model.GetGroupRateLimits(group))
+		}
+		if len(scopes) == 0 {
+			c.Next()
+			return
+		}
+		release, limitErr, err := ratelimit.Acquire(getRelayCounter(), scopes)
+		if err != nil {
+			// the relay keeps working when the counters are unavailable
+			logger.Error(c.Request.Context(), "failed to check relay rate limits: "+err.Error())
+			c.Next()
+			return
+		}
+		if limitErr != nil {
+			abortWithRateLimit(c, limitErr)
+			return
+		}
+		defer func() {
+			release(int64(c.GetInt("relay_total_tokens")))
+		}()
+		c.Next()
+	}
+}
@@ -74,22 +74,29 @@ func GetRandomSatisfiedChannel(group string, model string) (*Channel, error) {
 }
 
 func (channel *Channel) AddAbilities() error {
+	abilities := channel.buildAbilities()
+	return DB.Create(&abilities).Error
+}
+
+func (channel *Channel) buildAbilities() []Ability {
 	models_ := strings.Split(channel.Models, ",")
 	groups_ := strings.Split(channel.Group, ",")
 	abilities := make([]Ability, 0, len(models_))
+	priority := channel.GetEffectivePriority()
+	enabled := channel.Status == common.ChannelStatusEnabled && channel.IsScheduledAvailable()
 	for _, model := range models_ {
 		for _, group := range groups_ {
 			ability := Ability{
 				Group:     group,
 				Model:     model,
 				ChannelId: channel.Id,
-				Enabled:   channel.Status == common.ChannelStatusEnabled,
-				Priority:  channel.Priority,
+				Enabled:   enabled,
+				Priority:  &priority,
 			}
 			abilities = append(abilities, ability)
 		}
 	}
-	return DB.Create(&abilities).Error
+	return abilities
 }
 
 func (channel *Channel) DeleteAbilities() error {
@@ -0,0 +1,257 @@
+package model
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+
+	"github.com/songquanpeng/one-api/common/helper"
+	"github.com/songquanpeng/one-api/common/logger"
+	"gorm.io/gorm"
+)
+
+// AuditChangeKey is the context key of the change described by a management handler
+const AuditChangeKey = "audit_change"
+
+const auditRedacted = "[redacted]"
+
+// AuditLog records a mutating call of the management API, it is kept apart from the consume logs
+type AuditLog struct {
+	Id         int    `json:"id"`
+	CreatedAt  int64  `json:"created_at" gorm:"bigint;index"`
+	ActorId    int    `json:"actor_id" gorm:"index"`
+	ActorName  string `json:"actor_name" gorm:"type:varchar(64);index"`
+	Action     string `json:"action" gorm:"type:varchar(128);index"`
+	TargetType string `json:"target_type" gorm:"type:varchar(32);index"`
+	TargetId   string `json:"target_id" gorm:"type:varchar(255);index"`
+	Method     string `json:"method" gorm:"type:varchar(8)"`
+	Path       string `json:"path" gorm:"type:varchar(255)"`
+	Before     string `json:"before" gorm:"type:text"` // JSON object, sensitive fields are redacted
+	After      string `json:"after" gorm:"type:text"`
+	Diff       string `json:"diff" gorm:"type:text"` // JSON object of the changed fields with their values before and after
+	Success    bool   `json:"success"`
+	Message    string `json:"message" gorm:"type:text"`
+	Ip         string `json:"ip" gorm:"type:varchar(64);index"`
+	RequestId  string `json:"request_id" gorm:"type:varchar(64);index"`
+}
+
+// AuditChange is set by the handlers to describe what they changed, the middleware records it with the request.
+// Before is nil for creations and After is nil for deletions.
+type AuditChange struct {
+	Action     string
+	TargetType string
+	TargetId   string
+	Before     interface{}
+	After      interface{}
+}
+
+type AuditLogFilter struct {
+	ActorId        int
+	ActorName      string
+	Action         string // prefix, so that user. matches every action on users
+	TargetType     string
+	TargetId       string
+	Ip             string
+	RequestId      string
+	Success        *bool
+	StartTimestamp int64
+	EndTimestamp   int64
+}
+
+// isSensitiveAuditField reports whether the field holds a credential which must not be recorded,
+// data is the content of channel imports which holds their keys, and the secret entries of the channel config
+// are masked by the channel API as well
+func isSensitiveAuditField(name string) bool {
+	name = strings.ToLower(name)
+	if name == "code" || name == "data" || strings.Contains(name, "secret") || strings.Contains(name, "passphrase") {
+		return true
+	}
+	for _, key := range channelSecretConfigKeys {
+		if name == key {
+			return true
+		}
+	}
+	for _, suffix := range []string{"password", "token", "key"} {
+		if strings.HasSuffix(name, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// AuditSnapshot turns a value into a JSON object, maps are used as they are and other values are marshalled.
+// Values which are not objects are returned under the "value" field.
+func AuditSnapshot(v interface{}) map[string]interface{} {
+	if v == nil {
+		return nil
+	}
+	if value := reflect.ValueOf(v); value.Kind() == reflect.Ptr && value.IsNil() {
+		return nil
+	}
+	if snapshot, ok := v.(map[string]interface{}); ok {
+		return snapshot
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil
+	}
+	snapshot := make(map[string]interface{})
+	if json.Unmarshal(data, &snapshot) != nil {
+		return map[string]interface{}{"value": v}
+	}
+	return snapshot
+}
+
+// redactAuditValue redacts the sensitive fields of the objects nested in the value,
+// strings holding a JSON object such as the channel config are redacted as well
+func redactAuditValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		return redactAuditSnapshot(v)
+	case []interface{}:
+		redacted := make([]interface{}, len(v))
+		for i, item := range v {
+			redacted[i] = redactAuditValue(item)
+		}
+		return redacted
+	case string:
+		if !strings.HasPrefix(strings.TrimSpace(v), "{") {
+			return v
+		}
+		object := make(map[string]interface{})
+		if json.Unmarshal([]byte(v), &object) != nil {
+			return v
+		}
+		return marshalAuditSnapshot(redactAuditSnapshot(object))
+	}
+	return value
+}
+
+func redactAuditSnapshot(snapshot map[string]interface{}) map[string]interface{} {
+	if snapshot == nil {
+		return nil
+	}
+	redacted := make(map[string]interface{}, len(snapshot))
+	for field, value := range snapshot {
+		if isSensitiveAuditField(field) && value != nil && value != "" {
+			value = auditRedacted
+		} else {
+			value = redactAuditValue(value)
+		}
+		redacted[field] = value
+	}
+	return redacted
+}
+
+// AuditDiff returns the fields whose value differ, as {"field": {"before": ..., "after": ...}}.
+// Changes of sensitive fields are listed without their values.
+func AuditDiff(before map[string]interface{}, after map[string]interface{}) map[string]interface{} {
+	diff := make(map[string]interface{})
+	add := func(field string, beforeValue interface{}, afterValue interface{}) {
+		if reflect.DeepEqual(beforeValue, afterValue) {
+			return
+		}
+		if isSensitiveAuditField(field) {
+			beforeValue, afterValue = auditRedacted, auditRedacted
+		} else {
+			beforeValue, afterValue = redactAuditValue(beforeValue), redactAuditValue(afterValue)
+		}
+		diff[field] = map[string]interface{}{"before": beforeValue, "after": afterValue}
+	}
+	for field, value := range before {
+		add(field, value, after[field])
+	}
+	for field, value := range after {
+		if _, ok := before[field]; !ok {
+			add(field, nil, value)
+		}
+	}
+	return diff
+}
+
+func marshalAuditSnapshot(snapshot map[string]interface{}) string {
+	if snapshot == nil {
+		return ""
+	}
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
+
+// RecordAuditLog stores the call with the change described by the handler, if any
+func RecordAuditLog(auditLog *AuditLog, change *AuditChange) {
+	if change != nil {
+		auditLog.Action = change.Action
+		auditLog.TargetType = change.TargetType
+		auditLog.TargetId = change.TargetId
+		before := AuditSnapshot(change.Before)
+		after := AuditSnapshot(change.After)
+		if before != nil && after != nil {
+			auditLog.Diff = marshalAuditSnapshot(AuditDiff(before, after))
+		}
+		auditLog.Before = marshalAuditSnapshot(redactAuditSnapshot(before))
+		auditLog.After = marshalAuditSnapshot(redactAuditSnapshot(after))
+	} else if auditLog.After != "" {
+		// the request body of the calls without a described change
+		body := make(map[string]interface{})
+		if json.Unmarshal([]byte(auditLog.After), &body) == nil {
+			auditLog.After = marshalAuditSnapshot(redactAuditSnapshot(body))
+		} else {
+			auditLog.After = ""
+		}
+	}
+	if len(auditLog.TargetId) > 255 {
+		auditLog.TargetId = auditLog.TargetId[:255]
+	}
+	auditLog.CreatedAt = helper.GetTimestamp()
+	err := LOG_DB.Create(auditLog).Error
+	if err != nil {
+		logger.SysError("failed to record audit log: " + err.Error())
+	}
+}
+
+func (filter *AuditLogFilter) apply(tx *gorm.DB) *gorm.DB {
+	if filter.ActorId != 0 {
+		tx = tx.Where("actor_id = ?", filter.ActorId)
+	}
+	if filter.ActorName != "" {
+		tx = tx.Where("actor_name = ?", filter.ActorName)
+	}
+	if filter.Action != "" {
+		tx = tx.Where("action LIKE ?", filter.Action+"%")
+	}
+	if filter.TargetType != "" {
+		tx = tx.Where("target_type = ?", filter.TargetType)
+	}
+	if filter.TargetId != "" {
+		tx = tx.Where("target_id = ?", filter.TargetId)
+	}
+	if filter.Ip != "" {
+		tx = tx.Where("ip = ?", filter.Ip)
+	}
+	if filter.RequestId != "" {
+		tx = tx.Where("request_id = ?", filter.RequestId)
+	}
+	if filter.Success != nil {
+		tx = tx.Where("success = ?", *filter.Success)
+	}
+	if filter.StartTimestamp != 0 {
+		tx = tx.Where("created_at >= ?", filter.StartTimestamp)
+	}
+	if filter.EndTimestamp != 0 {
+		tx = tx.Where("created_at <= ?", filter.EndTimestamp)
+	}
+	return tx
+}
+
+func GetAuditLogs(filter *AuditLogFilter, startIdx int, num int) (auditLogs []*AuditLog, total int64, err error) {
+	tx := filter.apply(LOG_DB.Model(&AuditLog{}))
+	err = tx.Count(&total).Error
+	if err != nil {
+		return nil, 0, err
+	}
+	err = tx.Order("id desc").Limit(num).Offset(startIdx).Find(&auditLogs).Error
+	return auditLogs, total, err
+}
@@ -2,7 +2,6 @@ package model
 
 import (
 	"context"
-	"encoding/json"
 	"errors"
 	"fmt"
 	"math/rand"
@@ -14,44 +13,52 @@ import (
 
 	"github.com/songquanpeng/one-api/common"
 	"github.com/songquanpeng/one-api/common/config"
+	"github.com/songquanpeng/one-api/common/helper"
 	"github.com/songquanpeng/one-api/common/logger"
 )
 
 var (
-	TokenCacheSeconds         = config.SyncFrequency
-	UserId2GroupCacheSeconds  = config.SyncFrequency
-	UserId2QuotaCacheSeconds  = config.SyncFrequency
-	UserId2StatusCacheSeconds = config.SyncFrequency
+	TokenCacheSeconds              = config.SyncFrequency
+	UserId2GroupCacheSeconds       = config.SyncFrequency
+	UserId2QuotaCacheSeconds       = config.SyncFrequency
+	UserId2StatusCacheSeconds      = config.SyncFrequency
+	UserId2PermissionsCacheSeconds = config.SyncFrequency
 )
 
+// CacheGetTokenByKey caches tokens by the hash of their key, so that keys are not stored in Redis either
 func CacheGetTokenByKey(key string) (*Token, error) {
-	keyCol := "`key`"
-	if common.UsingPostgreSQL {
-		keyCol = `"key"`
-	}
-	var token Token
 	if !common.RedisEnabled {
-		err := DB.Where(keyCol+" = ?", key).First(&token).Error
-		return &token, err
+		return GetTokenByKey(key)
 	}
-	tokenObjectString, err := common.RedisGet(fmt.Sprintf("token:%s", key))
+	keyHash := HashTokenKey(key)
+	tokenObjectString, err := common.RedisGet(fmt.Sprintf("token:%s", keyHash))
 	if err != nil {
-		err := DB.Where(keyCol+" = ?", key).First(&token).Error
+		token, err := GetTokenByKey(key)
 		if err != nil {
 			return nil, err
 		}
-		jsonBytes, err := json.Marshal(token)
+		jsonBytes, err := marshalCachedToken(token)
 		if err != nil {
 			return nil, err
 		}
-		err = common.RedisSet(fmt.Sprintf("token:%s", key), string(jsonBytes), time.Duration(TokenCacheSeconds)*time.Second)
+		expiration := time.Duration(TokenCacheSeconds) * time.Second
+		if token.IsPreviousKey(key) {
+			// a previous key is cached until the end of its grace period at most
+			gracePeriod := time.Duration(token.PreviousKeyExpiredTime-helper.GetTimestamp()) * time.Second
+			if gracePeriod <= 0 {
+				return token, nil
+			}
+			if gracePeriod < expiration {
+				expiration = gracePeriod
+			}
+		}
+		err = common.RedisSet(fmt.Sprintf("token:%s", keyHash), string(jsonBytes), expiration)
 		if err != nil {
 			logger.SysError("Redis set token error: " + err.Error())
 		}
-		return &token, nil
+		return token, nil
 	}
-	err = json.Unmarshal([]byte(tokenObjectString), &token)
-	return &token, err
+	return unmarshalCachedToken([]byte(tokenObjectString))
 }
 
 func CacheGetUserGroup(id int) (group string, err error) {
@@ -72,6 +79,39 @@ func CacheGetUserGroup(id int) (group string, err error) {
 	return group, err
 }
 
+// CacheGetUserPermissions caches the permissions checked on every management request,
+// the entries are dropped when the role of the user or the permissions of the role change
+func CacheGetUserPermissions(id int) ([]string, error) {
+	if !common.RedisEnabled {
+		return GetUserPermissions(id)
+	}
+	permissions, err := common.RedisGet(fmt.Sprintf("user_permissions:%d", id))
+	if err == nil {
+		return SplitPermissions(permissions), nil
+	}
+	granted, err := GetUserPermissions(id)
+	if err != nil {
+		return nil, err
+	}
+	err = common.RedisSet(fmt.Sprintf("user_permissions:%d", id), strings.Join(granted, ","), time.Duration(UserId2PermissionsCacheSeconds)*time.Second)
+	if err != nil {
+		logger.SysError("Redis set user permissions error: " + err.Error())
+	}
+	return granted, nil
+}
+
+func InvalidateUserPermissionsCache(ids ...int) {
+	if !common.RedisEnabled {
+		return
+	}
+	for _, id := range ids {
+		err := common.RedisDel(fmt.Sprintf("user_permissions:%d", id))
+		if err != nil {
+			logger.SysError("Redis delete user permissions error: " + err.Error())
+		}
+	}
+}
+
 func fetchAndUpdateUserQuota(ctx context.Context, id int) (quota int64, err error) {
 	quota, err = GetUserQuota(id)
 	if err != nil {
@@ -167,9 +207,15 @@ func InitChannelCache() {
 	newChannelId2channel := make(map[int]*Channel)
 	var channels []*Channel
 	DB.Where("status = ?", common.ChannelStatusEnabled).Find(&channels)
+	scheduledChannels := make([]*Channel, 0, len(channels))
 	for _, channel := range channels {
+		if !channel.IsScheduledAvailable() {
+			continue
+		}
 		newChannelId2channel[channel.Id] = channel
+		scheduledChannels = append(scheduledChannels, channel)
 	}
+	channels = scheduledChannels
 	var abilities []*Ability
 	DB.Find(&abilities)
 	groups := make(map[string]bool)
@@ -195,11 +241,15 @@ func InitChannelCache() {
 		}
 	}
 
-	// sort by priority
+	// sort by priority, the effective priority evaluates the schedule so it is computed once per channel
+	priorities := make(map[int]int64, len(channels))
+	for _, channel := range channels {
+		priorities[channel.Id] = channel.GetEffectivePriority()
+	}
 	for group, model2channels := range newGroup2model2channels {
 		for model, channels := range model2channels {
 			sort.Slice(channels, func(i, j int) bool {
-				return channels[i].GetPriority() > channels[j].GetPriority()
+				return priorities[channels[i].Id] > priorities[channels[j].Id]
 			})
 			newGroup2model2channels[group][model] = channels
 		}
@@ -3,6 +3,9 @@ package model
 import (
 	"encoding/json"
 	"fmt"
+	"sort"
+	"strconv"
+	"strings"
 
 	"github.com/songquanpeng/one-api/common"
 	"github.com/songquanpeng/one-api/common/config"
@@ -31,6 +34,40 @@ type Channel struct {
 	ModelMapping       *string `json:"model_mapping" gorm:"type:varchar(1024);default:''"`
 	Priority           *int64  `json:"priority" gorm:"bigint;default:0"`
 	Config             string  `json:"config"`
+	Tags               string  `json:"tags" gorm:"type:varchar(255);default:''"`
+	Managed            bool    `json:"managed" gorm:"default:false"` // managed by the config file, read-only for admins
+	BalanceLow         bool    `json:"balance_low" gorm:"default:false"`
+	Schedule           *string `json:"schedule" gorm:"type:text"` // see ChannelSchedule
+}
+
+// keys of the channel config read outside the relay
+const (
+	ChannelConfigProxy         = "proxy"
+	ChannelConfigTLSCACert     = "tls_ca_cert"
+	ChannelConfigTLSClientCert = "tls_client_cert"
+	ChannelConfigTLSClientKey  = "tls_client_key"
+	ChannelConfigTLSServerName = "tls_server_name"
+
+	ChannelConfigBalanceProvider     = "balance_provider"
+	ChannelConfigBalanceURL          = "balance_url"
+	ChannelConfigBalancePath         = "balance_path"
+	ChannelConfigBalanceHeader       = "balance_header"
+	ChannelConfigBalanceCurrency     = "balance_currency"
+	ChannelConfigBalanceThreshold    = "balance_threshold"
+	ChannelConfigBalanceDeprioritize = "balance_deprioritize"
+)
+
+// LowBalancePriorityPenalty is subtracted from the priority of deprioritized low balance channels,
+// so they are only selected once every other channel has failed
+const LowBalancePriorityPenalty = 1000000
+
+// ChannelTagUpdate describes the fields changed by a tag-scoped bulk update, nil fields are left untouched
+type ChannelTagUpdate struct {
+	Models   *string `json:"models"`
+	Group    *string `json:"group"`
+	Priority *int64  `json:"priority"`
+	Weight   *uint   `json:"weight"`
+	Status   *int    `json:"status"`
 }
 
 func GetChannelsAndCount(page int, pageSize int) (channels []*Channel, total int64, err error) {
@@ -120,6 +157,12 @@ func GetChannelById(id int, selectAll bool) (*Channel, error) {
 
 func BatchInsertChannels(channels []Channel) error {
 	var err error
+	for i := range channels {
+		err = channels[i].encryptSecrets()
+		if err != nil {
+			return err
+		}
+	}
 	err = DB.Create(&channels).Error
 	if err != nil {
 		return err
@@ -140,6 +183,33 @@ func (channel *Channel) GetPriority() int64 {
 	return *channel.Priority
 }
 
+// GetEffectivePriority is the priority used for channel selection
+func (channel *Channel) GetEffectivePriority() int64 {
+	priority := channel.GetPriority()
+	if _, scheduledPriority := channel.evaluateSchedule(); scheduledPriority != nil {
+		priority = *scheduledPriority
+	}
+	if channel.BalanceLow && channel.GetBalanceDeprioritize() {
+		priority -= LowBalancePriorityPenalty
+	}
+	return priority
+}
+
+// GetBalanceThreshold returns the balance below which the channel is considered low, 0 when unset
+func (channel *Channel) GetBalanceThreshold() float64 {
+	cfg, _ := channel.LoadConfig()
+	threshold, err := strconv.ParseFloat(cfg[ChannelConfigBalanceThreshold], 64)
+	if err != nil {
+		return 0
+	}
+	return threshold
+}
+
+func (channel *Channel) GetBalanceDeprioritize() bool {
+	cfg, _ := channel.LoadConfig()
+	return cfg[ChannelConfigBalanceDeprioritize] == "true"
+}
+
 func (channel *Channel) GetWeight() *uint {
 	if channel.Weight == nil {
 		defaultWeight := uint(1) // 定义默认权重值为1
@@ -155,6 +225,34 @@ func (channel *Channel) GetBaseURL() string {
 	return *channel.BaseURL
 }
 
+func (channel *Channel) GetTags() []string {
+	return NormalizeChannelTags(channel.Tags)
+}
+
+func (channel *Channel) HasTag(tag string) bool {
+	for _, t := range channel.GetTags() {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// NormalizeChannelTags splits a comma separated tag list, trimming blanks and duplicates
+func NormalizeChannelTags(tags string) []string {
+	result := make([]string, 0)
+	seen := make(map[string]bool)
+	for _, tag := range strings.Split(tags, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "" || seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		result = append(result, tag)
+	}
+	return result
+}
+
 func (channel *Channel) GetModelMapping() map[string]string {
 	if channel.ModelMapping == nil || *channel.ModelMapping == "" || *channel.ModelMapping == "{}" {
 		return nil
@@ -170,6 +268,11 @@ func (channel *Channel) GetModelMapping() map[string]string {
 
 func (channel *Channel) Insert() error {
 	var err error
+	channel.Tags = strings.Join(channel.GetTags(), ",")
+	err = channel.encryptSecrets()
+	if err != nil {
+		return err
+	}
 	err = DB.Create(channel).Error
 	if err != nil {
 		return err
@@ -180,6 +283,15 @@ func (channel *Channel) Insert() error {
 
 func (channel *Channel) Update() error {
 	var err error
+	channel.Tags = strings.Join(channel.GetTags(), ",")
+	if channel.Key == MaskedChannelKey {
+		// the masked key returned by the admin APIs leaves the key unchanged
+		channel.Key = ""
+	}
+	err = channel.encryptSecrets()
+	if err != nil {
+		return err
+	}
 	err = DB.Model(channel).Updates(channel).Error
 	if err != nil {
 		return err
@@ -209,6 +321,19 @@ func (channel *Channel) UpdateBalance(balance float64) {
 	}
 }
 
+// UpdateBalanceLow records whether the balance is below the threshold and updates the priority of the abilities
+func (channel *Channel) UpdateBalanceLow(low bool) error {
+	channel.BalanceLow = low
+	priority := channel.GetEffectivePriority()
+	return DB.Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&Channel{}).Where("id = ?", channel.Id).Update("balance_low", low).Error
+		if err != nil {
+			return err
+		}
+		return tx.Model(&Ability{}).Where("channel_id = ?", channel.Id).Update("priority", priority).Error
+	})
+}
+
 func (channel *Channel) Delete() error {
 	var err error
 	err = DB.Delete(channel).Error
@@ -244,6 +369,122 @@ func BatchDeleteChannel(ids []int) error {
 	return tx.Commit().Error
 }
 
+// GetChannelsByTag returns all channels carrying the given tag
+func GetChannelsByTag(tag string, selectAll bool) ([]*Channel, error) {
+	var candidates []*Channel
+	query := DB.Order("id desc").Where("tags LIKE ?", "%"+tag+"%")
+	if !selectAll {
+		query = query.Omit("key")
+	}
+	err := query.Find(&candidates).Error
+	if err != nil {
+		return nil, err
+	}
+	// LIKE also matches substrings of other tags, keep exact matches only
+	channels := make([]*Channel, 0, len(candidates))
+	for _, channel := range candidates {
+		if channel.HasTag(tag) {
+			channels = append(channels, channel)
+		}
+	}
+	return channels, nil
+}
+
+func GetAllChannelTags() ([]string, error) {
+	var tagLists []string
+	err := DB.Model(&Channel{}).Where("tags <> ''").Distinct().Pluck("tags", &tagLists).Error
+	if err != nil {
+		return nil, err
+	}
+	tags := NormalizeChannelTags(strings.Join(tagLists, ","))
+	sort.Strings(tags)
+	return tags, nil
+}
+
+// BatchUpdateChannelsByTag applies the update to every channel with the tag and rebuilds their abilities
+func BatchUpdateChannelsByTag(tag string, update *ChannelTagUpdate) (int64, error) {
+	channels, err := GetChannelsByTag(tag, false)
+	if err != nil {
+		return 0, err
+	}
+	if len(channels) == 0 {
+		return 0, nil
+	}
+	ids := make([]int, 0, len(channels))
+	for _, channel := range channels {
+		ids = append(ids, channel.Id)
+	}
+	updates := make(map[string]interface{})
+	if update.Models != nil {
+		updates["models"] = *update.Models
+	}
+	if update.Group != nil {
+		updates["group"] = *update.Group
+	}
+	if update.Priority != nil {
+		updates["priority"] = *update.Priority
+	}
+	if update.Weight != nil {
+		updates["weight"] = *update.Weight
+	}
+	if update.Status != nil {
+		updates["status"] = *update.Status
+	}
+	if len(updates) == 0 {
+		return 0, nil
+	}
+	if err := CheckChannelsNotManaged(ids); err != nil {
+		return 0, err
+	}
+
+	err = DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&Channel{}).Where("id IN ?", ids).Updates(updates).Error; err != nil {
+			return err
+		}
+		var updatedChannels []*Channel
+		if err := tx.Omit("key").Where("id IN ?", ids).Find(&updatedChannels).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("channel_id IN ?", ids).Delete(&Ability{}).Error; err != nil {
+			return err
+		}
+		abilities := make([]Ability, 0)
+		for _, channel := range updatedChannels {
+			abilities = append(abilities, channel.buildAbilities()...)
+		}
+		if len(abilities) == 0 {
+			return nil
+		}
+		return tx.Create(&abilities).Error
+	})
+	if err != nil {
+		return 0, err
+	}
+	return int64(len(ids)), nil
+}
+
+// UpdateChannelModels replaces the models of the channel and rebuilds its abilities
+func UpdateChannelModels(id int, models string) error {
+	return DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&Channel{}).Where("id = ?", id).Update("models", models).Error; err != nil {
+			return err
+		}
+		var channel Channel
+		if err := tx.Omit("key").First(&channel, "id = ?", id).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("channel_id = ?", id).Delete(&Ability{}).Error; err != nil {
+			return err
+		}
+		abilities := channel.buildAbilities()
+		if len(abilities) == 0 {
+			return nil
+		}
+		return tx.Create(&abilities).Error
+	})
+}
+
+// LoadConfig returns the channel config with its secret entries decrypted
 func (channel *Channel) LoadConfig() (map[string]string, error) {
 	if channel.Config == "" {
 		return nil, nil
@@ -253,11 +494,27 @@ func (channel *Channel) LoadConfig() (map[string]string, error) {
 	if err != nil {
 		return nil, err
 	}
+	for _, key := range channelSecretConfigKeys {
+		if value := cfg[key]; value != "" {
+			cfg[key], err = decryptChannelKey(value)
+			if err != nil {
+				return nil, fmt.Errorf("failed to decrypt channel config %s: %w", key, err)
+			}
+		}
+	}
 	return cfg, nil
 }
 
 func UpdateChannelStatusById(id int, status int) {
-	err := UpdateAbilityStatus(id, status == common.ChannelStatusEnabled)
+	enabled := status == common.ChannelStatusEnabled
+	if enabled {
+		// the abilities of a channel outside its scheduled window stay disabled
+		channel, err := GetChannelById(id, false)
+		if err == nil {
+			enabled = channel.IsScheduledAvailable()
+		}
+	}
+	err := UpdateAbilityStatus(id, enabled)
 	if err != nil {
 		logger.SysError("failed to update ability status: " + err.Error())
 	}
@@ -288,6 +545,6 @@ func DeleteChannelByStatus(status int64) (int64, error) {
 }
 
 func DeleteDisabledChannel() (int64, error) {
-	result := DB.Where("status = ? or status = ?", common.ChannelStatusAutoDisabled, common.ChannelStatusManuallyDisabled).Delete(&Channel{})
+	result := DB.Where("(status = ? or status = ?) and managed = ?", common.ChannelStatusAutoDisabled, common.ChannelStatusManuallyDisabled, false).Delete(&Channel{})
 	return result.RowsAffected, result.Error
 }
@@ -0,0 +1,260 @@
+package model
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/songquanpeng/one-api/common"
+	"github.com/songquanpeng/one-api/common/config"
+	"github.com/songquanpeng/one-api/common/logger"
+	"gorm.io/gorm"
+)
+
+// MaskedChannelKey is returned by the admin APIs in place of the key of a channel,
+// sending it back when updating the channel keeps the key unchanged
+const MaskedChannelKey = "********"
+
+// encryptChannelKey encrypts the key with the master key, keys are stored as they are without a master key
+func encryptChannelKey(key string) (string, error) {
+	if config.ChannelMasterKey == "" || key == "" || common.IsEnvelopeEncrypted(key) {
+		return key, nil
+	}
+	return common.EnvelopeEncrypt(key, common.DeriveMasterKey(config.ChannelMasterKey))
+}
+
+func decryptChannelKey(key string) (string, error) {
+	if !common.IsEnvelopeEncrypted(key) {
+		return key, nil
+	}
+	if config.ChannelMasterKey == "" {
+		return "", errors.New("the channel key is encrypted but no master key is configured")
+	}
+	return common.EnvelopeDecrypt(key, common.DeriveMasterKey(config.ChannelMasterKey))
+}
+
+// channelSecretConfigKeys are the entries of the channel config which are as sensitive as the key,
+// they are encrypted at rest and masked by the admin APIs as well
+var channelSecretConfigKeys = []string{ChannelConfigTLSClientKey, ChannelConfigProxy, ChannelConfigBalanceHeader}
+
+// MapChannelConfigSecrets returns the channel config with fn applied to its secret entries which are set,
+// configs which are not a JSON object are returned as they are
+func MapChannelConfigSecrets(channelConfig string, fn func(value string) (string, error)) (string, error) {
+	cfg := make(map[string]string)
+	if channelConfig == "" || json.Unmarshal([]byte(channelConfig), &cfg) != nil {
+		return channelConfig, nil
+	}
+	changed := false
+	for _, key := range channelSecretConfigKeys {
+		value, ok := cfg[key]
+		if !ok || value == "" {
+			continue
+		}
+		newValue, err := fn(value)
+		if err != nil {
+			return "", fmt.Errorf("config %s: %w", key, err)
+		}
+		if newValue != value {
+			cfg[key] = newValue
+			changed = true
+		}
+	}
+	if !changed {
+		return channelConfig, nil
+	}
+	data, err := json.Marshal(cfg)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// restoreMaskedConfig puts back the stored secret entries which were sent masked, masked entries
+// without a stored value are dropped
+func restoreMaskedConfig(channelConfig string, storedConfig string) string {
+	cfg := make(map[string]string)
+	if channelConfig == "" || json.Unmarshal([]byte(channelConfig), &cfg) != nil {
+		return channelConfig
+	}
+	stored := make(map[string]string)
+	if storedConfig != "" {
+		_ = json.Unmarshal([]byte(storedConfig), &stored)
+	}
+	changed := false
+	for _, key := range channelSecretConfigKeys {
+		if cfg[key] != MaskedChannelKey {
+			continue
+		}
+		if value, ok := stored[key]; ok {
+			cfg[key] = value
+		} else {
+			delete(cfg, key)
+		}
+		changed = true
+	}
+	if !changed {
+		return channelConfig
+	}
+	data, err := json.Marshal(cfg)
+	if err != nil {
+		return channelConfig
+	}
+	return string(data)
+}
+
+// RestoreMaskedConfig keeps the stored value of the secret config entries sent back masked by the admin APIs
+func (channel *Channel) RestoreMaskedConfig() error {
+	var storedConfig string
+	if channel.Id != 0 {
+		err := DB.Model(&Channel{}).Where("id = ?", channel.Id).Select("config").Scan(&storedConfig).Error
+		if err != nil {
+			return err
+		}
+	}
+	channel.Config = restoreMaskedConfig(channel.Config, storedConfig)
+	return nil
+}
+
+// encryptSecrets encrypts the key and the secret config entries of the channel before it is written
+func (channel *Channel) encryptSecrets() error {
+	key, err := encryptChannelKey(channel.Key)
+	if err != nil {
+		return fmt.Errorf("failed to encrypt channel key: %w", err)
+	}
+	channelConfig, err := MapChannelConfigSecrets(channel.Config, encryptChannelKey)
+	if err != nil {
+		return fmt.Errorf("failed to encrypt channel config: %w", err)
+	}
+	channel.Key = key
+	channel.Config = channelConfig
+	return nil
+}
+
+// DecryptKey returns the plaintext key of the channel, it is only meant to be called to build upstream requests
+func (channel *Channel) DecryptKey() (string, error) {
+	return decryptChannelKey(channel.Key)
+}
+
+// DecryptConfig returns the channel config with its secret entries in plaintext
+func (channel *Channel) DecryptConfig() (string, error) {
+	return MapChannelConfigSecrets(channel.Config, decryptChannelKey)
+}
+
+// MaskSecrets hides the key and the secret config entries of the channel before it is returned by the admin APIs
+func (channel *Channel) MaskSecrets() {
+	if channel.Key != "" {
+		channel.Key = MaskedChannelKey
+	}
+	channel.Config, _ = MapChannelConfigSecrets(channel.Config, func(string) (string, error) {
+		return MaskedChannelKey, nil
+	})
+}
+
+// encryptChannelKeys encrypts the keys and configs stored before the master key was configured
+func encryptChannelKeys(db *gorm.DB) error {
+	if config.ChannelMasterKey == "" {
+		return nil
+	}
+	keyCol := "`key`"
+	if common.UsingPostgreSQL {
+		keyCol = `"key"`
+	}
+	var channels []*Channel
+	err := db.Select("id", "key", "config").
+		Where("("+keyCol+" <> ? AND "+keyCol+" NOT LIKE ?) OR config <> ?", "", "enc:%", "").Find(&channels).Error
+	if err != nil {
+		return err
+	}
+	encrypted := 0
+	for _, channel := range channels {
+		key, channelConfig := channel.Key, channel.Config
+		err = channel.encryptSecrets()
+		if err != nil {
+			return err
+		}
+		if channel.Key == key && channel.Config == channelConfig {
+			continue
+		}
+		err = db.Model(&Channel{}).Where("id = ?", channel.Id).Updates(map[string]interface{}{
+			"key":    channel.Key,
+			"config": channel.Config,
+		}).Error
+		if err != nil {
+			return err
+		}
+		encrypted++
+	}
+	if encrypted != 0 {
+		logger.SysLog(fmt.Sprintf("encrypted the secrets of %d channels", encrypted))
+	}
+	return nil
+}
+
+// RotateChannelMasterKey seals the keys and secret config entries of every channel, and the two-factor secrets, with the new master key,
+// the configured master key must then be replaced by the new one
+func RotateChannelMasterKey(newMasterKey string) (int, error) {
+	if newMasterKey == "" {
+		return 0, errors.New("the new master key is empty")
+	}
+	oldKey := common.DeriveMasterKey(config.ChannelMasterKey)
+	newKey := common.DeriveMasterKey(newMasterKey)
+	rewrap := func(value string) (string, error) {
+		if common.IsEnvelopeEncrypted(value) {
+			if config.ChannelMasterKey == "" {
+				return "", errors.New("the channel secrets are encrypted but no master key is configured")
+			}
+			return common.EnvelopeRewrap(value, oldKey, newKey)
+		}
+		return common.EnvelopeEncrypt(value, newKey)
+	}
+	count := 0
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		var channels []*Channel
+		err := tx.Select("id", "key", "config").Find(&channels).Error
+		if err != nil {
+			return err
+		}
+		for _, channel := range channels {
+			key := channel.Key
+			if key != "" {
+				key, err = rewrap(key)
+				if err != nil {
+					return fmt.Errorf("channel #%d: %w", channel.Id, err)
+				}
+			}
+			channelConfig, err := MapChannelConfigSecrets(channel.Config, rewrap)
+			if err != nil {
+				return fmt.Errorf("channel #%d: %w", channel.Id, err)
+			}
+			if key == channel.Key && channelConfig == channel.Config {
+				continue
+			}
+			err = tx.Model(&Channel{}).Where("id = ?", channel.Id).Updates(map[string]interface{}{
+				"key":    key,
+				"config": channelConfig,
+			}).Error
+			if err != nil {
+				return err
+			}
+			count++
+		}
+		// the two-factor secrets are sealed with the same master key
+		var twoFactors []*TwoFactor
+		err = tx.Select("user_id", "secret").Where("secret <> ?", "").Find(&twoFactors).Error
+		if err != nil {
+			return err
+		}
+		for _, twoFactor := range twoFactors {
+			secret, err := rewrap(twoFactor.Secret)
+			if err != nil {
+				return fmt.Errorf("two-factor secret of user #%d: %w", twoFactor.UserId, err)
+			}
+			err = tx.Model(&TwoFactor{}).Where("user_id = ?", twoFactor.UserId).Update("secret", secret).Error
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return count, err
+}
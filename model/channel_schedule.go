@@ -0,0 +1,203 @@
+package model
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/songquanpeng/one-api/common"
+	"github.com/songquanpeng/one-api/common/config"
+	"github.com/songquanpeng/one-api/common/cron"
+	"github.com/songquanpeng/one-api/common/logger"
+)
+
+const (
+	ScheduleActionEnable   = "enable"
+	ScheduleActionDisable  = "disable"
+	ScheduleActionPriority = "priority"
+)
+
+// ChannelSchedule describes when a channel is available, each rule is a window made of the minutes
+// matched by its cron expression, evaluated in the timezone of the schedule.
+// With enable rules the channel is only available inside them, disable rules take precedence,
+// and the first matching priority rule overrides the priority of the channel.
+type ChannelSchedule struct {
+	Timezone string                `json:"timezone,omitempty"`
+	Rules    []ChannelScheduleRule `json:"rules"`
+
+	location *time.Location
+}
+
+type ChannelScheduleRule struct {
+	Cron     string `json:"cron"`
+	Action   string `json:"action"`
+	Priority int64  `json:"priority,omitempty"`
+
+	schedule *cron.Schedule
+}
+
+func ParseChannelSchedule(data string) (*ChannelSchedule, error) {
+	if data == "" {
+		return nil, nil
+	}
+	var schedule ChannelSchedule
+	err := json.Unmarshal([]byte(data), &schedule)
+	if err != nil {
+		return nil, fmt.Errorf("invalid schedule: %w", err)
+	}
+	schedule.location = time.Local
+	if schedule.Timezone != "" {
+		schedule.location, err = time.LoadLocation(schedule.Timezone)
+		if err != nil {
+			return nil, fmt.Errorf("invalid schedule timezone: %w", err)
+		}
+	}
+	if len(schedule.Rules) == 0 {
+		return nil, errors.New("schedule has no rules")
+	}
+	for i := range schedule.Rules {
+		rule := &schedule.Rules[i]
+		switch rule.Action {
+		case ScheduleActionEnable, ScheduleActionDisable, ScheduleActionPriority:
+		default:
+			return nil, fmt.Errorf("unknown schedule action: %s", rule.Action)
+		}
+		rule.schedule, err = cron.Parse(rule.Cron)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return &schedule, nil
+}
+
+// Evaluate returns whether the channel is available at the given time and the priority overriding its own, if any
+func (schedule *ChannelSchedule) Evaluate(now time.Time) (available bool, priority *int64) {
+	now = now.In(schedule.location)
+	hasEnableRule, enabled, disabled := false, false, false
+	for i := range schedule.Rules {
+		rule := &schedule.Rules[i]
+		if rule.Action == ScheduleActionEnable {
+			hasEnableRule = true
+		}
+		if !rule.schedule.Match(now) {
+			continue
+		}
+		switch rule.Action {
+		case ScheduleActionEnable:
+			enabled = true
+		case ScheduleActionDisable:
+			disabled = true
+		case ScheduleActionPriority:
+			if priority == nil {
+				priority = &rule.Priority
+			}
+		}
+	}
+	available = !disabled && (!hasEnableRule || enabled)
+	return available, priority
+}
+
+func (channel *Channel) GetSchedule() string {
+	if channel.Schedule == nil {
+		return ""
+	}
+	return *channel.Schedule
+}
+
+// parsedChannelSchedules caches the parsed schedules by their JSON, invalid schedules are cached as nil
+var parsedChannelSchedules sync.Map
+
+func getParsedChannelSchedule(data string) *ChannelSchedule {
+	if data == "" {
+		return nil
+	}
+	if schedule, ok := parsedChannelSchedules.Load(data); ok {
+		return schedule.(*ChannelSchedule)
+	}
+	schedule, err := ParseChannelSchedule(data)
+	if err != nil {
+		schedule = nil
+	}
+	parsedChannelSchedules.Store(data, schedule)
+	return schedule
+}
+
+// evaluateSchedule evaluates the schedule of the channel now, channels without a valid schedule are always available
+func (channel *Channel) evaluateSchedule() (available bool, priority *int64) {
+	schedule := getParsedChannelSchedule(channel.GetSchedule())
+	if schedule == nil {
+		return true, nil
+	}
+	return schedule.Evaluate(time.Now())
+}
+
+// IsScheduledAvailable reports whether the schedule of the channel allows it to be used now
+func (channel *Channel) IsScheduledAvailable() bool {
+	available, _ := channel.evaluateSchedule()
+	return available
+}
+
+type channelScheduleState struct {
+	enabled  bool
+	priority int64
+}
+
+var channelScheduleStates = make(map[int]channelScheduleState)
+var channelScheduleLock sync.Mutex
+
+// ApplyChannelSchedules writes the scheduled state of the channels whose state changed to their abilities,
+// and refreshes the channel cache when any of them changed. Only the master node writes the abilities,
+// the other nodes only refresh their cache.
+func ApplyChannelSchedules() {
+	var channels []*Channel
+	err := DB.Omit("key").Where("schedule IS NOT NULL AND schedule <> ''").Find(&channels).Error
+	if err != nil {
+		logger.SysError("failed to get scheduled channels: " + err.Error())
+		return
+	}
+	channelScheduleLock.Lock()
+	defer channelScheduleLock.Unlock()
+	changed := false
+	states := make(map[int]channelScheduleState, len(channels))
+	for _, channel := range channels {
+		state := channelScheduleState{
+			enabled:  channel.Status == common.ChannelStatusEnabled && channel.IsScheduledAvailable(),
+			priority: channel.GetEffectivePriority(),
+		}
+		states[channel.Id] = state
+		// the state of every channel is written once after starting, status changes keep the schedule in effect
+		if previous, ok := channelScheduleStates[channel.Id]; ok && previous == state {
+			continue
+		}
+		changed = true
+		if !config.IsMasterNode {
+			continue
+		}
+		err := DB.Model(&Ability{}).Where("channel_id = ?", channel.Id).
+			Select("enabled", "priority").Updates(map[string]any{"enabled": state.enabled, "priority": state.priority}).Error
+		if err != nil {
+			logger.SysError(fmt.Sprintf("failed to apply schedule of channel #%d: %s", channel.Id, err.Error()))
+			// retried on the next run
+			delete(states, channel.Id)
+			continue
+		}
+		logger.SysLog(fmt.Sprintf("channel #%d scheduled state: enabled %t, priority %d", channel.Id, state.enabled, state.priority))
+	}
+	if len(states) != len(channelScheduleStates) {
+		changed = true
+	}
+	channelScheduleStates = states
+	if changed && config.MemoryCacheEnabled {
+		InitChannelCache()
+	}
+}
+
+func SyncChannelSchedules() {
+	for {
+		ApplyChannelSchedules()
+		// wake up at the start of every minute, as cron windows are minute based
+		time.Sleep(time.Until(time.Now().Truncate(time.Minute).Add(time.Minute)))
+	}
+}
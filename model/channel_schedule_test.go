@@ -0,0 +1,23 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetParsedChannelSchedule(t *testing.T) {
+	data := `{"timezone":"Asia/Shanghai","rules":[{"cron":"* 9-17 * * 1-5","action":"enable"}]}`
+	schedule := getParsedChannelSchedule(data)
+	if assert.NotNil(t, schedule) {
+		assert.Same(t, schedule, getParsedChannelSchedule(data))
+	}
+	assert.Nil(t, getParsedChannelSchedule(`{"rules":[{"cron":"* * * * *","action":"unknown"}]}`))
+	assert.Nil(t, getParsedChannelSchedule(""))
+
+	invalid := `{"rules":[]}`
+	channel := &Channel{Schedule: &invalid}
+	available, priority := channel.evaluateSchedule()
+	assert.True(t, available)
+	assert.Nil(t, priority)
+}
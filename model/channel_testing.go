@@ -0,0 +1,101 @@
+package model
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/songquanpeng/one-api/common/config"
+)
+
+// ChannelTest is the result of one test request sent to a channel
+type ChannelTest struct {
+	Id               int     `json:"id"`
+	RunId            string  `json:"run_id" gorm:"type:varchar(64);index"`
+	ChannelId        int     `json:"channel_id" gorm:"index"`
+	ModelName        string  `json:"model_name" gorm:"default:''"`
+	CaseName         string  `json:"case_name" gorm:"default:''"`
+	Stream           bool    `json:"stream"`
+	Success          bool    `json:"success"`
+	Message          string  `json:"message" gorm:"type:text"`
+	Output           string  `json:"output" gorm:"type:text"`
+	ResponseTime     int64   `json:"response_time"`    // in milliseconds
+	FirstTokenTime   int64   `json:"first_token_time"` // in milliseconds
+	TokensPerSecond  float64 `json:"tokens_per_second"`
+	PromptTokens     int     `json:"prompt_tokens"`
+	CompletionTokens int     `json:"completion_tokens"`
+	CreatedAt        int64   `json:"created_at" gorm:"bigint;index"`
+}
+
+// ChannelTestCase is an admin defined prompt with the assertions its answer must satisfy
+type ChannelTestCase struct {
+	Name             string          `json:"name"`
+	Prompt           string          `json:"prompt"`
+	MaxTokens        int             `json:"max_tokens"`
+	ExpectContains   string          `json:"expect_contains"`
+	ExpectJSONSchema json.RawMessage `json:"expect_json_schema"`
+}
+
+func ParseChannelTestCases(value string) ([]ChannelTestCase, error) {
+	cases := make([]ChannelTestCase, 0)
+	if value == "" {
+		return cases, nil
+	}
+	err := json.Unmarshal([]byte(value), &cases)
+	if err != nil {
+		return nil, err
+	}
+	for i, testCase := range cases {
+		if testCase.Name == "" || testCase.Prompt == "" {
+			return nil, fmt.Errorf("test case #%d must have a name and a prompt", i+1)
+		}
+	}
+	return cases, nil
+}
+
+// GetChannelTestCases returns the configured test cases, or a single greeting when none is configured
+func GetChannelTestCases() []ChannelTestCase {
+	config.OptionMapRWMutex.RLock()
+	value := config.OptionMap["ChannelTestCases"]
+	config.OptionMapRWMutex.RUnlock()
+	cases, err := ParseChannelTestCases(value)
+	if err != nil || len(cases) == 0 {
+		return []ChannelTestCase{{Name: "default", Prompt: "hi", MaxTokens: 16}}
+	}
+	return cases
+}
+
+func RecordChannelTests(tests []*ChannelTest) error {
+	if len(tests) == 0 {
+		return nil
+	}
+	return DB.Create(&tests).Error
+}
+
+// PruneChannelTests keeps the latest results of the channel, up to ChannelTestHistoryLimit
+func PruneChannelTests(channelId int) error {
+	if config.ChannelTestHistoryLimit <= 0 {
+		return nil
+	}
+	var ids []int
+	err := DB.Model(&ChannelTest{}).Where("channel_id = ?", channelId).Order("id desc").
+		Offset(config.ChannelTestHistoryLimit).Limit(1).Pluck("id", &ids).Error
+	if err != nil || len(ids) == 0 {
+		return err
+	}
+	return DB.Where("channel_id = ? AND id <= ?", channelId, ids[0]).Delete(&ChannelTest{}).Error
+}
+
+// GetChannelTestsAndCount returns the results of the channel, only those of the run when runId is set
+func GetChannelTestsAndCount(channelId int, runId string, page int, pageSize int) (tests []*ChannelTest, total int64, err error) {
+	tx := DB.Model(&ChannelTest{}).Where("channel_id = ?", channelId)
+	if runId != "" {
+		tx = tx.Where("run_id = ?", runId)
+	}
+	err = tx.Count(&total).Error
+	if err != nil {
+		return nil, 0, err
+	}
+	offset := (page - 1) * pageSize
+	err = tx.Order("id desc").Limit(pageSize).Offset(offset).Find(&tests).Error
+	return tests, total, err
+}
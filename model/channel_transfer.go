@@ -0,0 +1,88 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/songquanpeng/one-api/common/helper"
+	"gorm.io/gorm"
+)
+
+// columns overwritten when an imported channel matches an existing one by name,
+// runtime statistics such as used quota, balance and response time are kept
+var channelImportColumns = []string{
+	"type", "key", "status", "name", "weight", "base_url", "other", "models",
+	"group", "model_mapping", "priority", "config", "tags",
+}
+
+// UpsertChannelsByName inserts or updates the channels matched by name and rebuilds
+// their abilities in a single transaction. A channel with an empty key keeps the stored key.
+func UpsertChannelsByName(channels []*Channel) (created int, updated int, err error) {
+	err = DB.Transaction(func(tx *gorm.DB) error {
+		created, updated = 0, 0
+		for _, channel := range channels {
+			isNew, err := upsertChannelByName(tx, channel, false)
+			if err != nil {
+				return err
+			}
+			if isNew {
+				created++
+			} else {
+				updated++
+			}
+		}
+		return nil
+	})
+	return created, updated, err
+}
+
+// upsertChannelByName saves the channel over the first channel with the same name and rebuilds its abilities,
+// channels managed by the config file are only written when managed is true
+func upsertChannelByName(tx *gorm.DB, channel *Channel, managed bool) (created bool, err error) {
+	var existing Channel
+	err = tx.Where("name = ?", channel.Name).Order("id asc").First(&existing).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return false, err
+	}
+	columns := channelImportColumns
+	if managed {
+		channel.Managed = true
+		columns = append(columns, "managed")
+	}
+	// masked secret config entries keep the stored value, existing is empty when the channel is created
+	channel.Config = restoreMaskedConfig(channel.Config, existing.Config)
+	if err := channel.encryptSecrets(); err != nil {
+		return false, err
+	}
+	if err == nil {
+		if existing.Managed && !managed {
+			return false, fmt.Errorf("channel %s is managed by the config file", channel.Name)
+		}
+		channel.Id = existing.Id
+		channel.CreatedTime = existing.CreatedTime
+		if channel.Key == "" {
+			channel.Key = existing.Key
+		}
+		if err := tx.Model(&Channel{Id: existing.Id}).Select(columns).Updates(channel).Error; err != nil {
+			return false, err
+		}
+	} else {
+		if channel.Key == "" {
+			return false, fmt.Errorf("channel %s does not exist and has no key", channel.Name)
+		}
+		channel.Id = 0
+		channel.CreatedTime = helper.GetTimestamp()
+		if err := tx.Create(channel).Error; err != nil {
+			return false, err
+		}
+		created = true
+	}
+	if err := tx.Where("channel_id = ?", channel.Id).Delete(&Ability{}).Error; err != nil {
+		return false, err
+	}
+	abilities := channel.buildAbilities()
+	if len(abilities) == 0 {
+		return created, nil
+	}
+	return created, tx.Create(&abilities).Error
+}
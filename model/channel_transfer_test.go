@@ -0,0 +1,65 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/songquanpeng/one-api/common"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
+)
+
+func setupTestDB(t *testing.T, models ...interface{}) {
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{Logger: gormlogger.Default.LogMode(gormlogger.Silent)})
+	require.NoError(t, err)
+	sqlDB, err := db.DB()
+	require.NoError(t, err)
+	// every connection to :memory: opens another database
+	sqlDB.SetMaxOpenConns(1)
+	require.NoError(t, db.AutoMigrate(models...))
+	DB = db
+	redisEnabled := common.RedisEnabled
+	common.RedisEnabled = false
+	t.Cleanup(func() {
+		common.RedisEnabled = redisEnabled
+		_ = sqlDB.Close()
+	})
+}
+
+func TestUpsertChannelsByName(t *testing.T) {
+	setupTestDB(t, &Channel{}, &Ability{})
+	created, updated, err := UpsertChannelsByName([]*Channel{
+		{Name: "openai", Type: 1, Key: "sk-1", Models: "gpt-4", Group: "default", Status: common.ChannelStatusEnabled},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 1, created)
+	assert.Equal(t, 0, updated)
+
+	// the second import updates the channel by name, keeps the stored key and rebuilds the abilities
+	created, updated, err = UpsertChannelsByName([]*Channel{
+		{Name: "openai", Type: 1, Models: "gpt-4o,gpt-4o-mini", Group: "default", Status: common.ChannelStatusEnabled},
+		{Name: "claude", Type: 14, Key: "sk-2", Models: "claude-3-haiku", Group: "default", Status: common.ChannelStatusEnabled},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 1, created)
+	assert.Equal(t, 1, updated)
+	var channels []Channel
+	require.NoError(t, DB.Order("id").Find(&channels).Error)
+	require.Len(t, channels, 2)
+	assert.Equal(t, "sk-1", channels[0].Key)
+	assert.Equal(t, "gpt-4o,gpt-4o-mini", channels[0].Models)
+	var models []string
+	require.NoError(t, DB.Model(&Ability{}).Where("channel_id = ?", channels[0].Id).Order("model").Pluck("model", &models).Error)
+	assert.Equal(t, []string{"gpt-4o", "gpt-4o-mini"}, models)
+
+	// a new channel without a key is rejected and the whole import is rolled back
+	_, _, err = UpsertChannelsByName([]*Channel{
+		{Name: "claude", Type: 14, Models: "claude-3-opus", Group: "default"},
+		{Name: "gemini", Type: 24, Models: "gemini-pro", Group: "default"},
+	})
+	assert.Error(t, err)
+	require.NoError(t, DB.Where("name = ?", "claude").First(&channels[1]).Error)
+	assert.Equal(t, "claude-3-haiku", channels[1].Models)
+}
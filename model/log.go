@@ -17,6 +17,7 @@ type Log struct {
 	Id               int     `json:"id"`
 	RequestId        string  `json:"request_id"`
 	UserId           int     `json:"user_id" gorm:"index"`
+	OrganizationId   int     `json:"organization_id" gorm:"index;default:0"` // organization charged for the request, 0 for the user
 	CreatedAt        int64   `json:"created_at" gorm:"bigint;index:idx_created_at_type"`
 	Type             int     `json:"type" gorm:"index:idx_created_at_type"`
 	Content          string  `json:"content"`
@@ -36,6 +37,7 @@ const (
 	LogTypeConsume
 	LogTypeManage
 	LogTypeSystem
+	LogTypeSecurity
 )
 
 func RecordLog(userId int, logType int, content string) {
@@ -55,13 +57,49 @@ func RecordLog(userId int, logType int, content string) {
 	}
 }
 
-func RecordConsumeLog(ctx context.Context, userId int, channelId int, promptTokens int, completionTokens int, modelName string, tokenName string, quota int64, content string, duration float64) {
+// RecordOrganizationLog records an operation on the quota of an organization
+func RecordOrganizationLog(organizationId int, userId int, logType int, content string) {
+	log := &Log{
+		UserId:         userId,
+		OrganizationId: organizationId,
+		Username:       GetUsernameById(userId),
+		CreatedAt:      helper.GetTimestamp(),
+		Type:           logType,
+		Content:        content,
+	}
+	err := LOG_DB.Create(log).Error
+	if err != nil {
+		logger.SysError("failed to record log: " + err.Error())
+	}
+}
+
+// RecordSecurityLog records a rejected use of a token
+func RecordSecurityLog(ctx context.Context, userId int, tokenName string, content string) {
+	logger.Warn(ctx, fmt.Sprintf("record security log: userId=%d, tokenName=%s, content=%s", userId, tokenName, content))
+	requestId, _ := ctx.Value(logger.RequestIdKey).(string)
+	log := &Log{
+		RequestId: requestId,
+		UserId:    userId,
+		Username:  GetUsernameById(userId),
+		CreatedAt: helper.GetTimestamp(),
+		Type:      LogTypeSecurity,
+		Content:   content,
+		TokenName: tokenName,
+	}
+	err := LOG_DB.Create(log).Error
+	if err != nil {
+		logger.Error(ctx, "failed to record log: "+err.Error())
+	}
+}
+
+func RecordConsumeLog(ctx context.Context, userId int, organizationId int, channelId int, promptTokens int, completionTokens int, modelName string, tokenName string, quota int64, content string, duration float64) {
 	logger.Info(ctx, fmt.Sprintf("record consume log: userId=%d, channelId=%d, promptTokens=%d, completionTokens=%d, modelName=%s, tokenName=%s, quota=%d, content=%s", userId, channelId, promptTokens, completionTokens, modelName, tokenName, quota, content))
 	if !config.LogConsumeEnabled {
 		return
 	}
 	log := &Log{
 		UserId:           userId,
+		OrganizationId:   organizationId,
 		Username:         GetUsernameById(userId),
 		CreatedAt:        helper.GetTimestamp(),
 		Type:             LogTypeConsume,
@@ -172,6 +210,66 @@ func GetCurrentUserLogsAndCount(userId int, logType int, startTimestamp int64, e
 	return logs, total, nil
 }
 
+// GetOrganizationLogsAndCount returns the logs of an organization, of one of its members when userId is not 0
+func GetOrganizationLogsAndCount(organizationId int, userId int, logType int, startTimestamp int64, endTimestamp int64, modelName string, username string, page int, pageSize int) (logs []*Log, total int64, err error) {
+	tx := LOG_DB.Where("organization_id = ?", organizationId)
+	if logType != LogTypeUnknown {
+		tx = tx.Where("type = ?", logType)
+	}
+	if userId != 0 {
+		tx = tx.Where("user_id = ?", userId)
+	}
+	if username != "" {
+		tx = tx.Where("username = ?", username)
+	}
+	if modelName != "" {
+		tx = tx.Where("model_name = ?", modelName)
+	}
+	if startTimestamp != 0 {
+		tx = tx.Where("created_at >= ?", startTimestamp)
+	}
+	if endTimestamp != 0 {
+		tx = tx.Where("created_at <= ?", endTimestamp)
+	}
+	err = tx.Model(&Log{}).Count(&total).Error
+	if err != nil {
+		return nil, 0, err
+	}
+	offset := (page - 1) * pageSize
+	err = tx.Order("id desc").Limit(pageSize).Offset(offset).Find(&logs).Error
+	return logs, total, err
+}
+
+// GetUsersLogsAndCount returns the logs of a set of users, such as the sub-users of a reseller
+func GetUsersLogsAndCount(userIds []int, logType int, startTimestamp int64, endTimestamp int64, modelName string, tokenName string, page int, pageSize int) (logs []*Log, total int64, err error) {
+	if len(userIds) == 0 {
+		return []*Log{}, 0, nil
+	}
+	tx := LOG_DB.Where("user_id IN ?", userIds)
+	if logType != LogTypeUnknown {
+		tx = tx.Where("type = ?", logType)
+	}
+	if modelName != "" {
+		tx = tx.Where("model_name = ?", modelName)
+	}
+	if tokenName != "" {
+		tx = tx.Where("token_name = ?", tokenName)
+	}
+	if startTimestamp != 0 {
+		tx = tx.Where("created_at >= ?", startTimestamp)
+	}
+	if endTimestamp != 0 {
+		tx = tx.Where("created_at <= ?", endTimestamp)
+	}
+	err = tx.Model(&Log{}).Count(&total).Error
+	if err != nil {
+		return nil, 0, err
+	}
+	offset := (page - 1) * pageSize
+	err = tx.Select("id, request_id, user_id, created_at, type, content, username, token_name, model_name, quota, prompt_tokens, completion_tokens, duration").Order("id desc").Limit(pageSize).Offset(offset).Find(&logs).Error
+	return logs, total, err
+}
+
 func SearchAllLogs(keyword string) (logs []*Log, err error) {
 	err = LOG_DB.Where("type = ? or content LIKE ?", keyword, keyword+"%").Order("id desc").Limit(config.MaxRecentItems).Find(&logs).Error
 	return logs, err
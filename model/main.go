@@ -44,7 +44,6 @@ func CreateRootAccountIfNeed() error {
 			token := Token{
 				Id:             1,
 				UserId:         rootUser.Id,
-				Key:            config.InitialRootToken,
 				Status:         common.TokenStatusEnabled,
 				Name:           "Initial Root Token",
 				CreatedTime:    helper.GetTimestamp(),
@@ -53,6 +52,7 @@ func CreateRootAccountIfNeed() error {
 				RemainQuota:    500000000000000,
 				UnlimitedQuota: true,
 			}
+			token.SetKey(config.InitialRootToken)
 			DB.Create(&token)
 		}
 	}
@@ -146,6 +146,50 @@ func InitDB(envName string) (db *gorm.DB, err error) {
 		if err != nil {
 			return nil, err
 		}
+		err = db.AutoMigrate(&ChannelTest{})
+		if err != nil {
+			return nil, err
+		}
+		err = db.AutoMigrate(&Organization{})
+		if err != nil {
+			return nil, err
+		}
+		err = db.AutoMigrate(&OrganizationMember{})
+		if err != nil {
+			return nil, err
+		}
+		err = db.AutoMigrate(&Role{})
+		if err != nil {
+			return nil, err
+		}
+		err = createBuiltinRoles(db)
+		if err != nil {
+			return nil, err
+		}
+		err = db.AutoMigrate(&TwoFactor{})
+		if err != nil {
+			return nil, err
+		}
+		err = db.AutoMigrate(&Session{})
+		if err != nil {
+			return nil, err
+		}
+		err = db.AutoMigrate(&AuditLog{})
+		if err != nil {
+			return nil, err
+		}
+		err = migrateTokenKeys(db)
+		if err != nil {
+			return nil, err
+		}
+		err = encryptChannelKeys(db)
+		if err != nil {
+			return nil, err
+		}
+		err = encryptTwoFactorSecrets(db)
+		if err != nil {
+			return nil, err
+		}
 		logger.SysLog("database migrated")
 		return db, err
 	} else {
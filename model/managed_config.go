@@ -0,0 +1,537 @@
+package model
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+
+	"github.com/songquanpeng/one-api/common"
+	"github.com/songquanpeng/one-api/common/config"
+	"github.com/songquanpeng/one-api/common/helper"
+	"github.com/songquanpeng/one-api/common/logger"
+	"gopkg.in/yaml.v3"
+	"gorm.io/gorm"
+)
+
+// ManagedConfig is the declarative config file, every entry listed here is owned by the file
+// and becomes read-only for admins. Entries not listed keep being managed through the web UI.
+type ManagedConfig struct {
+	Channels    []ManagedChannel   `json:"channels" yaml:"channels"`
+	GroupRatios map[string]float64 `json:"group_ratios" yaml:"group_ratios"`
+	ModelRatios map[string]float64 `json:"model_ratios" yaml:"model_ratios"`
+	ModelPrices map[string]float64 `json:"model_prices" yaml:"model_prices"`
+	Options     map[string]string  `json:"options" yaml:"options"`
+}
+
+type ManagedChannel struct {
+	Name         string `json:"name" yaml:"name"`
+	Type         int    `json:"type" yaml:"type"`
+	Key          string `json:"key" yaml:"key"` // supports ${ENV} references so secrets stay out of the file, $${ is a literal ${
+	Status       int    `json:"status" yaml:"status"`
+	Weight       uint   `json:"weight" yaml:"weight"`
+	Priority     int64  `json:"priority" yaml:"priority"`
+	BaseURL      string `json:"base_url" yaml:"base_url"`
+	Other        string `json:"other" yaml:"other"`
+	Models       string `json:"models" yaml:"models"`
+	Group        string `json:"group" yaml:"group"`
+	ModelMapping string `json:"model_mapping" yaml:"model_mapping"`
+	Config       string `json:"config" yaml:"config"`
+	Tags         string `json:"tags" yaml:"tags"`
+}
+
+const (
+	DriftKindChannel    = "channel"
+	DriftKindOption     = "option"
+	DriftKindGroupRatio = "group_ratio"
+	DriftKindModelRatio = "model_ratio"
+	DriftKindModelPrice = "model_price"
+
+	DriftActionCreate = "create"
+	DriftActionUpdate = "update"
+	DriftActionDelete = "delete"
+)
+
+// ConfigDrift is a difference between the config file and the database, values are never reported
+type ConfigDrift struct {
+	Kind   string   `json:"kind"`
+	Name   string   `json:"name"`
+	Action string   `json:"action"`
+	Fields []string `json:"fields,omitempty"`
+}
+
+type ManagedConfigStatus struct {
+	Enabled        bool          `json:"enabled"`
+	Path           string        `json:"path"`
+	LastReconciled int64         `json:"last_reconciled"`
+	LastError      string        `json:"last_error"`
+	LastDrift      []ConfigDrift `json:"last_drift"`
+	Channels       []string      `json:"channels"`
+	Options        []string      `json:"options"`
+	GroupRatios    []string      `json:"group_ratios"`
+	ModelRatios    []string      `json:"model_ratios"`
+	ModelPrices    []string      `json:"model_prices"`
+}
+
+var managedConfig *ManagedConfig
+var managedConfigStatus ManagedConfigStatus
+var managedConfigLock sync.RWMutex
+
+// ratio options whose entries can be managed one by one
+var managedRatioOptions = map[string]string{
+	"GroupRatio": DriftKindGroupRatio,
+	"ModelRatio": DriftKindModelRatio,
+	"ModelPrice": DriftKindModelPrice,
+}
+
+var envReferencePattern = regexp.MustCompile(`\$\$\{|\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// expandEnvReferences replaces the ${VAR} references with the environment, any other $ is kept as is
+// since keys may contain it, and $${ is the escape for a literal ${
+func expandEnvReferences(value string) (string, error) {
+	var err error
+	expanded := envReferencePattern.ReplaceAllStringFunc(value, func(match string) string {
+		if match == "$${" {
+			return "${"
+		}
+		name := match[2 : len(match)-1]
+		env, ok := os.LookupEnv(name)
+		if !ok && err == nil {
+			err = fmt.Errorf("environment variable %s is not set", name)
+		}
+		return env
+	})
+	return expanded, err
+}
+
+func LoadManagedConfig(path string) (*ManagedConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var cfg ManagedConfig
+	ext := strings.ToLower(filepath.Ext(path))
+	if ext == ".json" {
+		err = json.Unmarshal(data, &cfg)
+	} else {
+		err = yaml.Unmarshal(data, &cfg)
+	}
+	if err != nil {
+		return nil, err
+	}
+	names := make(map[string]bool)
+	for i := range cfg.Channels {
+		channel := &cfg.Channels[i]
+		if channel.Name == "" {
+			return nil, fmt.Errorf("channel #%d has no name", i+1)
+		}
+		if names[channel.Name] {
+			return nil, fmt.Errorf("duplicated channel name: %s", channel.Name)
+		}
+		names[channel.Name] = true
+		if channel.Type <= common.ChannelTypeUnknown || channel.Type >= common.ChannelTypeDummy {
+			return nil, fmt.Errorf("channel %s: invalid type %d", channel.Name, channel.Type)
+		}
+		if strings.TrimSpace(channel.Models) == "" {
+			return nil, fmt.Errorf("channel %s: models cannot be empty", channel.Name)
+		}
+		channel.Key, err = expandEnvReferences(channel.Key)
+		if err != nil {
+			return nil, fmt.Errorf("channel %s: %w", channel.Name, err)
+		}
+		if channel.Key == "" {
+			return nil, fmt.Errorf("channel %s: key cannot be empty", channel.Name)
+		}
+		if channel.Status == 0 {
+			channel.Status = common.ChannelStatusEnabled
+		}
+		if channel.Group == "" {
+			channel.Group = "default"
+		}
+		channel.Tags = strings.Join(NormalizeChannelTags(channel.Tags), ",")
+	}
+	for key := range cfg.Options {
+		if _, ok := managedRatioOptions[key]; ok {
+			return nil, fmt.Errorf("option %s must be set through its own section", key)
+		}
+	}
+	return &cfg, nil
+}
+
+func (managedChannel *ManagedChannel) toChannel() *Channel {
+	weight := managedChannel.Weight
+	priority := managedChannel.Priority
+	baseURL := managedChannel.BaseURL
+	modelMapping := managedChannel.ModelMapping
+	return &Channel{
+		Name:         managedChannel.Name,
+		Type:         managedChannel.Type,
+		Key:          managedChannel.Key,
+		Status:       managedChannel.Status,
+		Weight:       &weight,
+		Priority:     &priority,
+		BaseURL:      &baseURL,
+		Other:        managedChannel.Other,
+		Models:       managedChannel.Models,
+		Group:        managedChannel.Group,
+		ModelMapping: &modelMapping,
+		Config:       managedChannel.Config,
+		Tags:         managedChannel.Tags,
+	}
+}
+
+func diffManagedChannel(existing *Channel, desired *Channel) []string {
+	fields := make([]string, 0)
+	if existing.Type != desired.Type {
+		fields = append(fields, "type")
+	}
+	if existingKey, err := existing.DecryptKey(); err != nil || existingKey != desired.Key {
+		fields = append(fields, "key")
+	}
+	// channels disabled automatically are runtime state, not drift
+	if existing.Status != desired.Status && existing.Status != common.ChannelStatusAutoDisabled {
+		fields = append(fields, "status")
+	}
+	if *existing.GetWeight() != *desired.Weight {
+		fields = append(fields, "weight")
+	}
+	if existing.GetPriority() != *desired.Priority {
+		fields = append(fields, "priority")
+	}
+	if existing.GetBaseURL() != *desired.BaseURL {
+		fields = append(fields, "base_url")
+	}
+	if existing.Other != desired.Other {
+		fields = append(fields, "other")
+	}
+	if existing.Models != desired.Models {
+		fields = append(fields, "models")
+	}
+	if existing.Group != desired.Group {
+		fields = append(fields, "group")
+	}
+	existingModelMapping := ""
+	if existing.ModelMapping != nil {
+		existingModelMapping = *existing.ModelMapping
+	}
+	if existingModelMapping != *desired.ModelMapping {
+		fields = append(fields, "model_mapping")
+	}
+	if !sameChannelConfig(existing, desired) {
+		fields = append(fields, "config")
+	}
+	if existing.Tags != desired.Tags {
+		fields = append(fields, "tags")
+	}
+	if !existing.Managed {
+		fields = append(fields, "managed")
+	}
+	return fields
+}
+
+// sameChannelConfig compares the configs entry by entry, the stored secret entries are encrypted
+func sameChannelConfig(existing *Channel, desired *Channel) bool {
+	existingConfig, err := existing.LoadConfig()
+	if err != nil {
+		return false
+	}
+	desiredConfig, err := desired.LoadConfig()
+	if err != nil {
+		return existing.Config == desired.Config
+	}
+	if len(existingConfig) != len(desiredConfig) {
+		return false
+	}
+	for key, value := range desiredConfig {
+		if existingValue, ok := existingConfig[key]; !ok || existingValue != value {
+			return false
+		}
+	}
+	return true
+}
+
+func getOptionValue(key string) (string, bool) {
+	config.OptionMapRWMutex.RLock()
+	defer config.OptionMapRWMutex.RUnlock()
+	value, ok := config.OptionMap[key]
+	return value, ok
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// mergeManagedRatios returns the ratio option with the managed entries applied, together with the drifted entries
+func mergeManagedRatios(optionKey string, managed map[string]float64) (string, []ConfigDrift, error) {
+	current := make(map[string]float64)
+	if value, ok := getOptionValue(optionKey); ok && value != "" {
+		if err := json.Unmarshal([]byte(value), &current); err != nil {
+			return "", nil, fmt.Errorf("failed to parse option %s: %s", optionKey, err.Error())
+		}
+	}
+	drifts := make([]ConfigDrift, 0)
+	for _, name := range sortedKeys(managed) {
+		value, ok := current[name]
+		if ok && value == managed[name] {
+			continue
+		}
+		action := DriftActionUpdate
+		if !ok {
+			action = DriftActionCreate
+		}
+		drifts = append(drifts, ConfigDrift{Kind: managedRatioOptions[optionKey], Name: name, Action: action})
+		current[name] = managed[name]
+	}
+	jsonBytes, err := json.Marshal(current)
+	if err != nil {
+		return "", nil, err
+	}
+	return string(jsonBytes), drifts, nil
+}
+
+// reconcileManagedConfig computes the drift between cfg and the database, and fixes it when apply is true
+func reconcileManagedConfig(cfg *ManagedConfig, apply bool) ([]ConfigDrift, error) {
+	drifts := make([]ConfigDrift, 0)
+
+	var existingChannels []*Channel
+	if err := DB.Find(&existingChannels).Error; err != nil {
+		return nil, err
+	}
+	existingByName := make(map[string]*Channel)
+	for _, channel := range existingChannels {
+		if _, ok := existingByName[channel.Name]; !ok {
+			existingByName[channel.Name] = channel
+		}
+	}
+	desiredNames := make(map[string]bool)
+	channelsToSave := make([]*Channel, 0)
+	for i := range cfg.Channels {
+		desired := cfg.Channels[i].toChannel()
+		desiredNames[desired.Name] = true
+		existing, ok := existingByName[desired.Name]
+		if !ok {
+			drifts = append(drifts, ConfigDrift{Kind: DriftKindChannel, Name: desired.Name, Action: DriftActionCreate})
+			channelsToSave = append(channelsToSave, desired)
+			continue
+		}
+		fields := diffManagedChannel(existing, desired)
+		if len(fields) == 0 {
+			continue
+		}
+		if existing.Status == common.ChannelStatusAutoDisabled {
+			desired.Status = existing.Status
+		}
+		drifts = append(drifts, ConfigDrift{Kind: DriftKindChannel, Name: desired.Name, Action: DriftActionUpdate, Fields: fields})
+		channelsToSave = append(channelsToSave, desired)
+	}
+	channelIdsToDelete := make([]int, 0)
+	for _, channel := range existingChannels {
+		if channel.Managed && !desiredNames[channel.Name] {
+			drifts = append(drifts, ConfigDrift{Kind: DriftKindChannel, Name: channel.Name, Action: DriftActionDelete})
+			channelIdsToDelete = append(channelIdsToDelete, channel.Id)
+		}
+	}
+
+	optionsToSave := make(map[string]string)
+	ratioSections := map[string]map[string]float64{
+		"GroupRatio": cfg.GroupRatios,
+		"ModelRatio": cfg.ModelRatios,
+		"ModelPrice": cfg.ModelPrices,
+	}
+	for _, optionKey := range sortedKeys(ratioSections) {
+		if len(ratioSections[optionKey]) == 0 {
+			continue
+		}
+		value, ratioDrifts, err := mergeManagedRatios(optionKey, ratioSections[optionKey])
+		if err != nil {
+			return nil, err
+		}
+		if len(ratioDrifts) != 0 {
+			drifts = append(drifts, ratioDrifts...)
+			optionsToSave[optionKey] = value
+		}
+	}
+	for _, key := range sortedKeys(cfg.Options) {
+		value, ok := getOptionValue(key)
+		if !ok {
+			logger.SysError(fmt.Sprintf("config file: unknown option %s", key))
+		}
+		if ok && value == cfg.Options[key] {
+			continue
+		}
+		drifts = append(drifts, ConfigDrift{Kind: DriftKindOption, Name: key, Action: DriftActionUpdate})
+		optionsToSave[key] = cfg.Options[key]
+	}
+
+	if !apply {
+		return drifts, nil
+	}
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		for _, channel := range channelsToSave {
+			if _, err := upsertChannelByName(tx, channel, true); err != nil {
+				return err
+			}
+		}
+		if len(channelIdsToDelete) != 0 {
+			if err := tx.Where("channel_id IN ?", channelIdsToDelete).Delete(&Ability{}).Error; err != nil {
+				return err
+			}
+			if err := tx.Where("id IN ?", channelIdsToDelete).Delete(&Channel{}).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, key := range sortedKeys(optionsToSave) {
+		if err := UpdateOption(key, optionsToSave[key]); err != nil {
+			return nil, fmt.Errorf("failed to update option %s: %s", key, err.Error())
+		}
+	}
+	return drifts, nil
+}
+
+// ReconcileManagedConfig loads the config file and makes the database match it,
+// only the master node writes to the database, other nodes just enforce the read-only entries
+func ReconcileManagedConfig() error {
+	if config.ConfigFile == "" {
+		return nil
+	}
+	cfg, err := LoadManagedConfig(config.ConfigFile)
+	if err != nil {
+		setManagedConfigError(err)
+		return err
+	}
+	drifts := make([]ConfigDrift, 0)
+	if config.IsMasterNode {
+		drifts, err = reconcileManagedConfig(cfg, true)
+		if err != nil {
+			setManagedConfigError(err)
+			return err
+		}
+		for _, drift := range drifts {
+			logger.SysLog(fmt.Sprintf("config file: %s %s %s %s", drift.Action, drift.Kind, drift.Name, strings.Join(drift.Fields, ",")))
+		}
+		if config.MemoryCacheEnabled {
+			InitChannelCache()
+		}
+	}
+	managedConfigLock.Lock()
+	managedConfig = cfg
+	managedConfigStatus = ManagedConfigStatus{
+		Enabled:        true,
+		Path:           config.ConfigFile,
+		LastReconciled: helper.GetTimestamp(),
+		LastDrift:      drifts,
+	}
+	for _, channel := range cfg.Channels {
+		managedConfigStatus.Channels = append(managedConfigStatus.Channels, channel.Name)
+	}
+	managedConfigStatus.Options = sortedKeys(cfg.Options)
+	managedConfigStatus.GroupRatios = sortedKeys(cfg.GroupRatios)
+	managedConfigStatus.ModelRatios = sortedKeys(cfg.ModelRatios)
+	managedConfigStatus.ModelPrices = sortedKeys(cfg.ModelPrices)
+	managedConfigLock.Unlock()
+	logger.SysLog(fmt.Sprintf("config file %s reconciled, %d drifts fixed", config.ConfigFile, len(drifts)))
+	return nil
+}
+
+// WatchManagedConfigReload reconciles the config file again whenever SIGHUP is received
+func WatchManagedConfigReload() {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGHUP)
+	for range signals {
+		logger.SysLog("SIGHUP received, reloading config file")
+		_ = ReconcileManagedConfig()
+	}
+}
+
+func setManagedConfigError(err error) {
+	managedConfigLock.Lock()
+	managedConfigStatus.Enabled = true
+	managedConfigStatus.Path = config.ConfigFile
+	managedConfigStatus.LastError = err.Error()
+	managedConfigLock.Unlock()
+	logger.SysError("failed to reconcile config file: " + err.Error())
+}
+
+func GetManagedConfigStatus() ManagedConfigStatus {
+	managedConfigLock.RLock()
+	defer managedConfigLock.RUnlock()
+	return managedConfigStatus
+}
+
+// GetManagedConfigDrift compares the database with the loaded config file without changing anything
+func GetManagedConfigDrift() ([]ConfigDrift, error) {
+	managedConfigLock.RLock()
+	cfg := managedConfig
+	managedConfigLock.RUnlock()
+	if cfg == nil {
+		return nil, errors.New("config file is not enabled")
+	}
+	return reconcileManagedConfig(cfg, false)
+}
+
+// CheckManagedOption rejects changes to options owned by the config file
+func CheckManagedOption(key string, value string) error {
+	managedConfigLock.RLock()
+	cfg := managedConfig
+	managedConfigLock.RUnlock()
+	if cfg == nil {
+		return nil
+	}
+	if _, ok := cfg.Options[key]; ok {
+		return fmt.Errorf("option %s is managed by the config file", key)
+	}
+	var managed map[string]float64
+	switch key {
+	case "GroupRatio":
+		managed = cfg.GroupRatios
+	case "ModelRatio":
+		managed = cfg.ModelRatios
+	case "ModelPrice":
+		managed = cfg.ModelPrices
+	default:
+		return nil
+	}
+	if len(managed) == 0 {
+		return nil
+	}
+	ratios := make(map[string]float64)
+	if err := json.Unmarshal([]byte(value), &ratios); err != nil {
+		return err
+	}
+	for _, name := range sortedKeys(managed) {
+		if ratio, ok := ratios[name]; !ok || ratio != managed[name] {
+			return fmt.Errorf("%s of %s is managed by the config file", key, name)
+		}
+	}
+	return nil
+}
+
+// CheckChannelsNotManaged rejects changes to channels owned by the config file
+func CheckChannelsNotManaged(ids []int) error {
+	var names []string
+	err := DB.Model(&Channel{}).Where("id IN ? AND managed = ?", ids, true).Pluck("name", &names).Error
+	if err != nil {
+		return err
+	}
+	if len(names) != 0 {
+		return fmt.Errorf("channels managed by the config file cannot be changed: %s", strings.Join(names, ","))
+	}
+	return nil
+}
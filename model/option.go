@@ -30,6 +30,10 @@ func InitOptionMap() {
 	config.OptionMap["EmailVerificationEnabled"] = strconv.FormatBool(config.EmailVerificationEnabled)
 	config.OptionMap["GitHubOAuthEnabled"] = strconv.FormatBool(config.GitHubOAuthEnabled)
 	config.OptionMap["GoogleOAuthEnabled"] = strconv.FormatBool(config.GoogleOAuthEnabled)
+	config.OptionMap["OidcEnabled"] = strconv.FormatBool(config.OidcEnabled)
+	config.OptionMap["LdapEnabled"] = strconv.FormatBool(config.LdapEnabled)
+	config.OptionMap["LdapStartTlsEnabled"] = strconv.FormatBool(config.LdapStartTlsEnabled)
+	config.OptionMap["LdapGroupShortNameEnabled"] = strconv.FormatBool(config.LdapGroupShortNameEnabled)
 	config.OptionMap["WeChatAuthEnabled"] = strconv.FormatBool(config.WeChatAuthEnabled)
 	config.OptionMap["TurnstileCheckEnabled"] = strconv.FormatBool(config.TurnstileCheckEnabled)
 	config.OptionMap["RegisterEnabled"] = strconv.FormatBool(config.RegisterEnabled)
@@ -39,6 +43,8 @@ func InitOptionMap() {
 	config.OptionMap["LogConsumeEnabled"] = strconv.FormatBool(config.LogConsumeEnabled)
 	config.OptionMap["DisplayInCurrencyEnabled"] = strconv.FormatBool(config.DisplayInCurrencyEnabled)
 	config.OptionMap["DisplayTokenStatEnabled"] = strconv.FormatBool(config.DisplayTokenStatEnabled)
+	config.OptionMap["SubUserEnabled"] = strconv.FormatBool(config.SubUserEnabled)
+	config.OptionMap["SubUserGroups"] = config.SubUserGroups
 	config.OptionMap["ChannelDisableThreshold"] = strconv.FormatFloat(config.ChannelDisableThreshold, 'f', -1, 64)
 	config.OptionMap["EmailDomainRestrictionEnabled"] = strconv.FormatBool(config.EmailDomainRestrictionEnabled)
 	config.OptionMap["EmailDomainWhitelist"] = strings.Join(config.EmailDomainWhitelist, ",")
@@ -59,6 +65,25 @@ func InitOptionMap() {
 	config.OptionMap["GoogleClientId"] = ""
 	config.OptionMap["GoogleClientSecret"] = ""
 	config.OptionMap["GoogleRedirectUri"] = ""
+	config.OptionMap["OidcDiscoveryUrl"] = ""
+	config.OptionMap["OidcClientId"] = ""
+	config.OptionMap["OidcClientSecret"] = ""
+	config.OptionMap["OidcRedirectUri"] = ""
+	config.OptionMap["OidcScopes"] = config.OidcScopes
+	config.OptionMap["OidcUsernameClaim"] = config.OidcUsernameClaim
+	config.OptionMap["OidcEmailClaim"] = config.OidcEmailClaim
+	config.OptionMap["OidcGroupsClaim"] = config.OidcGroupsClaim
+	config.OptionMap["OidcRoleMapping"] = ""
+	config.OptionMap["OidcGroupMapping"] = ""
+	config.OptionMap["LdapUrl"] = ""
+	config.OptionMap["LdapUserDn"] = ""
+	config.OptionMap["LdapBaseDn"] = ""
+	config.OptionMap["LdapUserFilter"] = config.LdapUserFilter
+	config.OptionMap["LdapEmailAttribute"] = config.LdapEmailAttribute
+	config.OptionMap["LdapDisplayNameAttribute"] = config.LdapDisplayNameAttribute
+	config.OptionMap["LdapGroupAttribute"] = config.LdapGroupAttribute
+	config.OptionMap["LdapRoleMapping"] = ""
+	config.OptionMap["LdapGroupMapping"] = ""
 	config.OptionMap["WeChatServerAddress"] = ""
 	config.OptionMap["WeChatServerToken"] = ""
 	config.OptionMap["WeChatAccountQRCodeImageURL"] = ""
@@ -73,11 +98,14 @@ func InitOptionMap() {
 	config.OptionMap["PreConsumedQuota"] = strconv.FormatInt(config.PreConsumedQuota, 10)
 	config.OptionMap["ModelRatio"] = common.ModelRatio2JSONString()
 	config.OptionMap["GroupRatio"] = common.GroupRatio2JSONString()
+	config.OptionMap["GroupRateLimits"] = GroupRateLimits2JSONString()
 	config.OptionMap["CompletionRatio"] = common.CompletionRatio2JSONString()
+	config.OptionMap["ModelPrice"] = common.ModelPrice2JSONString()
 	config.OptionMap["TopUpLink"] = config.TopUpLink
 	config.OptionMap["ChatLink"] = config.ChatLink
 	config.OptionMap["QuotaPerUnit"] = strconv.FormatFloat(config.QuotaPerUnit, 'f', -1, 64)
 	config.OptionMap["RetryTimes"] = strconv.Itoa(config.RetryTimes)
+	config.OptionMap["ChannelTestCases"] = "[]"
 	config.OptionMap["Theme"] = config.Theme
 	config.OptionMap["CryptPaymentEnabled"] = strconv.FormatBool(config.CryptPaymentEnabled)
 	config.OptionMap["CryptCallbackUrl"] = ""
@@ -144,6 +172,14 @@ func updateOptionMap(key string, value string) (err error) {
 			config.GitHubOAuthEnabled = boolValue
 		case "GoogleOAuthEnabled":
 			config.GoogleOAuthEnabled = boolValue
+		case "OidcEnabled":
+			config.OidcEnabled = boolValue
+		case "LdapEnabled":
+			config.LdapEnabled = boolValue
+		case "LdapStartTlsEnabled":
+			config.LdapStartTlsEnabled = boolValue
+		case "LdapGroupShortNameEnabled":
+			config.LdapGroupShortNameEnabled = boolValue
 		case "WeChatAuthEnabled":
 			config.WeChatAuthEnabled = boolValue
 		case "TurnstileCheckEnabled":
@@ -164,6 +200,8 @@ func updateOptionMap(key string, value string) (err error) {
 			config.DisplayInCurrencyEnabled = boolValue
 		case "DisplayTokenStatEnabled":
 			config.DisplayTokenStatEnabled = boolValue
+		case "SubUserEnabled":
+			config.SubUserEnabled = boolValue
 		case "CryptPaymentEnabled":
 			config.CryptPaymentEnabled = boolValue
 		case "StripePaymentEnabled":
@@ -173,6 +211,8 @@ func updateOptionMap(key string, value string) (err error) {
 	switch key {
 	case "EmailDomainWhitelist":
 		config.EmailDomainWhitelist = strings.Split(value, ",")
+	case "SubUserGroups":
+		config.SubUserGroups = value
 	case "SMTPServer":
 		config.SMTPServer = value
 	case "SMTPPort":
@@ -192,6 +232,44 @@ func updateOptionMap(key string, value string) (err error) {
 		config.GoogleClientSecret = value
 	case "GoogleRedirectUri":
 		config.GoogleRedirectUri = value
+	case "OidcDiscoveryUrl":
+		config.OidcDiscoveryUrl = value
+	case "OidcClientId":
+		config.OidcClientId = value
+	case "OidcClientSecret":
+		config.OidcClientSecret = value
+	case "OidcRedirectUri":
+		config.OidcRedirectUri = value
+	case "OidcScopes":
+		config.OidcScopes = value
+	case "OidcUsernameClaim":
+		config.OidcUsernameClaim = value
+	case "OidcEmailClaim":
+		config.OidcEmailClaim = value
+	case "OidcGroupsClaim":
+		config.OidcGroupsClaim = value
+	case "OidcRoleMapping":
+		config.OidcRoleMapping = value
+	case "OidcGroupMapping":
+		config.OidcGroupMapping = value
+	case "LdapUrl":
+		config.LdapUrl = value
+	case "LdapUserDn":
+		config.LdapUserDn = value
+	case "LdapBaseDn":
+		config.LdapBaseDn = value
+	case "LdapUserFilter":
+		config.LdapUserFilter = value
+	case "LdapEmailAttribute":
+		config.LdapEmailAttribute = value
+	case "LdapDisplayNameAttribute":
+		config.LdapDisplayNameAttribute = value
+	case "LdapGroupAttribute":
+		config.LdapGroupAttribute = value
+	case "LdapRoleMapping":
+		config.LdapRoleMapping = value
+	case "LdapGroupMapping":
+		config.LdapGroupMapping = value
 	case "GitHubClientId":
 		config.GitHubClientId = value
 	case "GitHubClientSecret":
@@ -232,8 +310,12 @@ func updateOptionMap(key string, value string) (err error) {
 		err = common.UpdateModelRatioByJSONString(value)
 	case "GroupRatio":
 		err = common.UpdateGroupRatioByJSONString(value)
+	case "GroupRateLimits":
+		err = UpdateGroupRateLimitsByJSONString(value)
 	case "CompletionRatio":
 		err = common.UpdateCompletionRatioByJSONString(value)
+	case "ModelPrice":
+		err = common.UpdateModelPriceByJSONString(value)
 	case "TopUpLink":
 		config.TopUpLink = value
 	case "ChatLink":
@@ -0,0 +1,300 @@
+package model
+
+import (
+	"context"
+	"errors"
+
+	"github.com/songquanpeng/one-api/common"
+	"github.com/songquanpeng/one-api/common/config"
+	"github.com/songquanpeng/one-api/common/helper"
+	"gorm.io/gorm"
+)
+
+const (
+	OrganizationRoleOwner  = "owner"
+	OrganizationRoleAdmin  = "admin"
+	OrganizationRoleMember = "member"
+)
+
+var organizationRoleLevels = map[string]int{
+	OrganizationRoleMember: 1,
+	OrganizationRoleAdmin:  2,
+	OrganizationRoleOwner:  3,
+}
+
+// Organization holds a quota shared by its members, it is spent by the tokens the members create for the organization
+type Organization struct {
+	Id          int    `json:"id"`
+	Name        string `json:"name" gorm:"type:varchar(64);uniqueIndex"`
+	Status      int    `json:"status" gorm:"type:int;default:1"`
+	Quota       int64  `json:"quota" gorm:"bigint;default:0"`
+	UsedQuota   int64  `json:"used_quota" gorm:"bigint;default:0"`
+	CreatedTime int64  `json:"created_time" gorm:"bigint"`
+	Role        string `json:"role,omitempty" gorm:"-"` // role of the current user in the organization
+}
+
+type OrganizationMember struct {
+	Id             int    `json:"id"`
+	OrganizationId int    `json:"organization_id" gorm:"uniqueIndex:idx_organization_user,priority:1"`
+	UserId         int    `json:"user_id" gorm:"uniqueIndex:idx_organization_user,priority:2;index"`
+	Role           string `json:"role" gorm:"type:varchar(16);default:'member'"`
+	CreatedTime    int64  `json:"created_time" gorm:"bigint"`
+	Username       string `json:"username" gorm:"-"`
+}
+
+func IsValidOrganizationRole(role string) bool {
+	_, ok := organizationRoleLevels[role]
+	return ok
+}
+
+// IsOrganizationRoleAtLeast reports whether the role grants at least the rights of minRole
+func IsOrganizationRoleAtLeast(role string, minRole string) bool {
+	return organizationRoleLevels[role] >= organizationRoleLevels[minRole]
+}
+
+func GetOrganizationsAndCount(page int, pageSize int) (organizations []*Organization, total int64, err error) {
+	err = DB.Model(&Organization{}).Count(&total).Error
+	if err != nil {
+		return nil, 0, err
+	}
+	offset := (page - 1) * pageSize
+	err = DB.Order("id desc").Limit(pageSize).Offset(offset).Find(&organizations).Error
+	return organizations, total, err
+}
+
+// GetUserOrganizations returns the organizations the user is a member of, with the role of the user
+func GetUserOrganizations(userId int) ([]*Organization, error) {
+	var members []*OrganizationMember
+	err := DB.Where("user_id = ?", userId).Find(&members).Error
+	if err != nil {
+		return nil, err
+	}
+	organizations := make([]*Organization, 0, len(members))
+	if len(members) == 0 {
+		return organizations, nil
+	}
+	roles := make(map[int]string, len(members))
+	ids := make([]int, 0, len(members))
+	for _, member := range members {
+		roles[member.OrganizationId] = member.Role
+		ids = append(ids, member.OrganizationId)
+	}
+	err = DB.Where("id IN ?", ids).Order("id desc").Find(&organizations).Error
+	if err != nil {
+		return nil, err
+	}
+	for _, organization := range organizations {
+		organization.Role = roles[organization.Id]
+	}
+	return organizations, nil
+}
+
+func GetOrganizationById(id int) (*Organization, error) {
+	if id == 0 {
+		return nil, errors.New("id 为空！")
+	}
+	organization := Organization{Id: id}
+	err := DB.First(&organization, "id = ?", id).Error
+	return &organization, err
+}
+
+// IsOrganizationMember reports whether the user is still a member of the organization
+func IsOrganizationMember(organizationId int, userId int) (bool, error) {
+	_, err := GetOrganizationMemberRole(organizationId, userId)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// GetOrganizationMemberRole returns the role of the user in the organization, gorm.ErrRecordNotFound if the user is not a member
+func GetOrganizationMemberRole(organizationId int, userId int) (string, error) {
+	var member OrganizationMember
+	err := DB.Where("organization_id = ? AND user_id = ?", organizationId, userId).First(&member).Error
+	return member.Role, err
+}
+
+// Insert creates the organization with the user as its owner
+func (organization *Organization) Insert(ownerId int) error {
+	organization.CreatedTime = helper.GetTimestamp()
+	return DB.Transaction(func(tx *gorm.DB) error {
+		err := tx.Create(organization).Error
+		if err != nil {
+			return err
+		}
+		return tx.Create(&OrganizationMember{
+			OrganizationId: organization.Id,
+			UserId:         ownerId,
+			Role:           OrganizationRoleOwner,
+			CreatedTime:    organization.CreatedTime,
+		}).Error
+	})
+}
+
+func (organization *Organization) Update() error {
+	return DB.Model(organization).Select("name", "status", "quota").Updates(organization).Error
+}
+
+// disableTokens disables the tokens matched by the query, it returns their key hashes so that they can be
+// dropped from the cache once the transaction is committed
+func disableTokens(tx *gorm.DB, query string, args ...interface{}) ([]string, error) {
+	var tokens []*Token
+	err := tx.Select("id", "key", "previous_key").Where(query, args...).Find(&tokens).Error
+	if err != nil || len(tokens) == 0 {
+		return nil, err
+	}
+	keyHashes := make([]string, 0, len(tokens)*2)
+	for _, token := range tokens {
+		keyHashes = append(keyHashes, token.KeyHash, token.PreviousKeyHash)
+	}
+	err = tx.Model(&Token{}).Where(query, args...).Update("status", common.TokenStatusDisabled).Error
+	return keyHashes, err
+}
+
+// Delete removes the organization and its members, the tokens of the organization are disabled
+func (organization *Organization) Delete() error {
+	var keyHashes []string
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		err := tx.Where("organization_id = ?", organization.Id).Delete(&OrganizationMember{}).Error
+		if err != nil {
+			return err
+		}
+		keyHashes, err = disableTokens(tx, "organization_id = ?", organization.Id)
+		if err != nil {
+			return err
+		}
+		return tx.Delete(organization).Error
+	})
+	if err == nil {
+		deleteTokenCache(keyHashes...)
+	}
+	return err
+}
+
+func GetOrganizationMembers(organizationId int) ([]*OrganizationMember, error) {
+	var members []*OrganizationMember
+	err := DB.Where("organization_id = ?", organizationId).Order("id").Find(&members).Error
+	if err != nil || len(members) == 0 {
+		return members, err
+	}
+	ids := make([]int, 0, len(members))
+	for _, member := range members {
+		ids = append(ids, member.UserId)
+	}
+	var users []*User
+	err = DB.Model(&User{}).Select("id", "username").Where("id IN ?", ids).Find(&users).Error
+	if err != nil {
+		return nil, err
+	}
+	usernames := make(map[int]string, len(users))
+	for _, user := range users {
+		usernames[user.Id] = user.Username
+	}
+	for _, member := range members {
+		member.Username = usernames[member.UserId]
+	}
+	return members, nil
+}
+
+func AddOrganizationMember(organizationId int, userId int, role string) error {
+	return DB.Create(&OrganizationMember{
+		OrganizationId: organizationId,
+		UserId:         userId,
+		Role:           role,
+		CreatedTime:    helper.GetTimestamp(),
+	}).Error
+}
+
+func UpdateOrganizationMemberRole(organizationId int, userId int, role string) error {
+	return DB.Model(&OrganizationMember{}).Where("organization_id = ? AND user_id = ?", organizationId, userId).Update("role", role).Error
+}
+
+// RemoveOrganizationMember removes the user from the organization and disables the tokens the user created for it
+func RemoveOrganizationMember(organizationId int, userId int) error {
+	var keyHashes []string
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		err := tx.Where("organization_id = ? AND user_id = ?", organizationId, userId).Delete(&OrganizationMember{}).Error
+		if err != nil {
+			return err
+		}
+		keyHashes, err = disableTokens(tx, "organization_id = ? AND user_id = ?", organizationId, userId)
+		return err
+	})
+	if err == nil {
+		deleteTokenCache(keyHashes...)
+	}
+	return err
+}
+
+// GetOrganizationQuota returns the quota of the organization, it fails once the organization is disabled
+func GetOrganizationQuota(id int) (quota int64, err error) {
+	var organization Organization
+	err = DB.Select("id", "status", "quota").First(&organization, "id = ?", id).Error
+	if err != nil {
+		return 0, err
+	}
+	if organization.Status != common.OrganizationStatusEnabled {
+		return 0, errors.New("the organization has been disabled")
+	}
+	return organization.Quota, nil
+}
+
+// IncreaseOrganizationQuota gives back quota consumed by the organization
+func IncreaseOrganizationQuota(id int, quota int64) (err error) {
+	if quota < 0 {
+		return errors.New("quota 不能为负数！")
+	}
+	if config.BatchUpdateEnabled {
+		addNewRecord(BatchUpdateTypeOrganizationQuota, id, quota)
+		return nil
+	}
+	return increaseOrganizationQuota(id, quota)
+}
+
+func increaseOrganizationQuota(id int, quota int64) (err error) {
+	err = DB.Model(&Organization{}).Where("id = ?", id).Updates(
+		map[string]interface{}{
+			"quota":      gorm.Expr("quota + ?", quota),
+			"used_quota": gorm.Expr("used_quota - ?", quota),
+		},
+	).Error
+	return err
+}
+
+func DecreaseOrganizationQuota(id int, quota int64) (err error) {
+	if quota < 0 {
+		return errors.New("quota 不能为负数！")
+	}
+	if config.BatchUpdateEnabled {
+		addNewRecord(BatchUpdateTypeOrganizationQuota, id, -quota)
+		return nil
+	}
+	return increaseOrganizationQuota(id, -quota)
+}
+
+// CacheGetBillingQuota returns the quota a request is charged to, the one of the organization owning the token if any
+func CacheGetBillingQuota(ctx context.Context, userId int, organizationId int) (int64, error) {
+	if organizationId != 0 {
+		return GetOrganizationQuota(organizationId)
+	}
+	return CacheGetUserQuota(ctx, userId)
+}
+
+// CacheDecreaseBillingQuota updates the cached quota of the user, the quota of organizations is not cached
+func CacheDecreaseBillingQuota(userId int, organizationId int, quota int64) error {
+	if organizationId != 0 {
+		return nil
+	}
+	return CacheDecreaseUserQuota(userId, quota)
+}
+
+func checkOrganizationQuota(organizationId int, quota int64) error {
+	organizationQuota, err := GetOrganizationQuota(organizationId)
+	if err != nil {
+		return err
+	}
+	if organizationQuota < quota {
+		return errors.New("Insufficient organization quota")
+	}
+	return nil
+}
@@ -0,0 +1,39 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/songquanpeng/one-api/common"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRemoveOrganizationMember(t *testing.T) {
+	setupTestDB(t, &Organization{}, &OrganizationMember{}, &Token{})
+	organization := &Organization{Name: "org", Status: common.OrganizationStatusEnabled, Quota: 1000}
+	require.NoError(t, organization.Insert(1))
+	require.NoError(t, AddOrganizationMember(organization.Id, 2, OrganizationRoleMember))
+	require.NoError(t, DB.Create(&Token{UserId: 2, OrganizationId: organization.Id, KeyHash: "member", Status: common.TokenStatusEnabled}).Error)
+	require.NoError(t, DB.Create(&Token{UserId: 1, OrganizationId: organization.Id, KeyHash: "owner", Status: common.TokenStatusEnabled}).Error)
+
+	isMember, err := IsOrganizationMember(organization.Id, 2)
+	require.NoError(t, err)
+	assert.True(t, isMember)
+
+	require.NoError(t, RemoveOrganizationMember(organization.Id, 2))
+	isMember, err = IsOrganizationMember(organization.Id, 2)
+	require.NoError(t, err)
+	assert.False(t, isMember)
+	assert.Equal(t, common.TokenStatusDisabled, getTestTokenStatus(t, "member"))
+	// the tokens of the other members are left alone
+	assert.Equal(t, common.TokenStatusEnabled, getTestTokenStatus(t, "owner"))
+
+	require.NoError(t, organization.Delete())
+	assert.Equal(t, common.TokenStatusDisabled, getTestTokenStatus(t, "owner"))
+}
+
+func getTestTokenStatus(t *testing.T, keyHash string) int {
+	var token Token
+	require.NoError(t, DB.First(&token, "`key` = ?", keyHash).Error)
+	return token.Status
+}
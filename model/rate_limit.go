@@ -0,0 +1,96 @@
+package model
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/songquanpeng/one-api/common"
+	"github.com/songquanpeng/one-api/common/logger"
+)
+
+// RateLimits are the relay limits of a token, a user or a group, 0 means unlimited
+type RateLimits struct {
+	RPM         int `json:"rpm_limit" gorm:"column:rpm_limit;default:0"`                 // requests per minute
+	TPM         int `json:"tpm_limit" gorm:"column:tpm_limit;default:0"`                 // prompt and completion tokens per minute of the text relay
+	Concurrency int `json:"concurrency_limit" gorm:"column:concurrency_limit;default:0"` // requests in flight
+}
+
+func (limits RateLimits) IsZero() bool {
+	return limits.RPM <= 0 && limits.TPM <= 0 && limits.Concurrency <= 0
+}
+
+var groupRateLimits = make(map[string]RateLimits)
+var groupRateLimitsLock sync.RWMutex
+
+func GroupRateLimits2JSONString() string {
+	groupRateLimitsLock.RLock()
+	defer groupRateLimitsLock.RUnlock()
+	jsonBytes, err := json.Marshal(groupRateLimits)
+	if err != nil {
+		logger.SysError("error marshalling group rate limits: " + err.Error())
+	}
+	return string(jsonBytes)
+}
+
+func UpdateGroupRateLimitsByJSONString(jsonStr string) error {
+	limits := make(map[string]RateLimits)
+	err := json.Unmarshal([]byte(jsonStr), &limits)
+	if err != nil {
+		return err
+	}
+	groupRateLimitsLock.Lock()
+	groupRateLimits = limits
+	groupRateLimitsLock.Unlock()
+	return nil
+}
+
+func GetGroupRateLimits(group string) RateLimits {
+	groupRateLimitsLock.RLock()
+	defer groupRateLimitsLock.RUnlock()
+	return groupRateLimits[group]
+}
+
+func GetUserRateLimits(id int) (limits RateLimits, err error) {
+	var user User
+	err = DB.Model(&User{}).Where("id = ?", id).Select("rpm_limit", "tpm_limit", "concurrency_limit").Find(&user).Error
+	return user.RateLimits, err
+}
+
+// UpdateUserRateLimits writes the limits of the user, including the zero ones
+func UpdateUserRateLimits(id int, limits RateLimits) error {
+	err := DB.Model(&User{}).Where("id = ?", id).Select("rpm_limit", "tpm_limit", "concurrency_limit").
+		Updates(User{RateLimits: limits}).Error
+	if err != nil {
+		return err
+	}
+	if common.RedisEnabled {
+		return common.RedisDel(fmt.Sprintf("user_rate_limits:%d", id))
+	}
+	return nil
+}
+
+func CacheGetUserRateLimits(id int) (limits RateLimits, err error) {
+	if !common.RedisEnabled {
+		return GetUserRateLimits(id)
+	}
+	limitsString, err := common.RedisGet(fmt.Sprintf("user_rate_limits:%d", id))
+	if err != nil {
+		limits, err = GetUserRateLimits(id)
+		if err != nil {
+			return limits, err
+		}
+		jsonBytes, err := json.Marshal(limits)
+		if err != nil {
+			return limits, err
+		}
+		err = common.RedisSet(fmt.Sprintf("user_rate_limits:%d", id), string(jsonBytes), time.Duration(UserId2GroupCacheSeconds)*time.Second)
+		if err != nil {
+			logger.SysError("Redis set user rate limits error: " + err.Error())
+		}
+		return limits, nil
+	}
+	err = json.Unmarshal([]byte(limitsString), &limits)
+	return limits, err
+}
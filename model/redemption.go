@@ -96,12 +96,39 @@ func GetRedemptionById(id int) (*Redemption, error) {
 }
 
 func Redeem(key string, userId int) (quota int64, err error) {
-	if key == "" {
-		return 0, errors.New("未提供兑换码")
-	}
 	if userId == 0 {
 		return 0, errors.New("无效的 user id")
 	}
+	quota, err = redeem(key, func(tx *gorm.DB, quota int64) error {
+		return tx.Model(&User{}).Where("id = ?", userId).Update("quota", gorm.Expr("quota + ?", quota)).Error
+	})
+	if err != nil {
+		return 0, err
+	}
+	RecordLog(userId, LogTypeTopup, fmt.Sprintf("通过兑换码充值 %s", common.LogQuota(quota)))
+	return quota, nil
+}
+
+// RedeemForOrganization credits the redemption code to the shared quota of an organization
+func RedeemForOrganization(key string, userId int, organizationId int) (quota int64, err error) {
+	if organizationId == 0 {
+		return 0, errors.New("无效的组织 id")
+	}
+	quota, err = redeem(key, func(tx *gorm.DB, quota int64) error {
+		return tx.Model(&Organization{}).Where("id = ?", organizationId).Update("quota", gorm.Expr("quota + ?", quota)).Error
+	})
+	if err != nil {
+		return 0, err
+	}
+	RecordOrganizationLog(organizationId, userId, LogTypeTopup, fmt.Sprintf("通过兑换码为组织充值 %s", common.LogQuota(quota)))
+	return quota, nil
+}
+
+// redeem marks the redemption code as used and credits its quota in the same transaction
+func redeem(key string, credit func(tx *gorm.DB, quota int64) error) (quota int64, err error) {
+	if key == "" {
+		return 0, errors.New("未提供兑换码")
+	}
 	redemption := &Redemption{}
 
 	keyCol := "`key`"
@@ -117,7 +144,7 @@ func Redeem(key string, userId int) (quota int64, err error) {
 		if redemption.Status != common.RedemptionCodeStatusEnabled {
 			return errors.New("该兑换码已被使用")
 		}
-		err = tx.Model(&User{}).Where("id = ?", userId).Update("quota", gorm.Expr("quota + ?", redemption.Quota)).Error
+		err = credit(tx, redemption.Quota)
 		if err != nil {
 			return err
 		}
@@ -129,7 +156,6 @@ func Redeem(key string, userId int) (quota int64, err error) {
 	if err != nil {
 		return 0, errors.New("兑换失败，" + err.Error())
 	}
-	RecordLog(userId, LogTypeTopup, fmt.Sprintf("通过兑换码充值 %s", common.LogQuota(redemption.Quota)))
 	return redemption.Quota, nil
 }
 
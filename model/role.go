@@ -0,0 +1,337 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/songquanpeng/one-api/common"
+	"github.com/songquanpeng/one-api/common/helper"
+	"github.com/songquanpeng/one-api/common/logger"
+	"gorm.io/gorm"
+)
+
+// permissions checked by the management API, roles grant them by name or by wildcard patterns such as channel.*
+const (
+	PermissionChannelRead        = "channel.read"
+	PermissionChannelWrite       = "channel.write"
+	PermissionChannelKeyExport   = "channel.key_export"
+	PermissionUserRead           = "user.read"
+	PermissionUserManage         = "user.manage"
+	PermissionOptionRead         = "option.read"
+	PermissionOptionWrite        = "option.write"
+	PermissionRedemptionRead     = "redemption.read"
+	PermissionRedemptionCreate   = "redemption.create"
+	PermissionRedemptionWrite    = "redemption.write"
+	PermissionLogRead            = "log.read"
+	PermissionLogDelete          = "log.delete"
+	PermissionGroupRead          = "group.read"
+	PermissionOrderRead          = "order.read"
+	PermissionDashboardRead      = "dashboard.read"
+	PermissionMidjourneyRead     = "midjourney.read"
+	PermissionOrganizationManage = "organization.manage"
+	PermissionRoleManage         = "role.manage"
+	PermissionAuditRead          = "audit.read"
+)
+
+var AllPermissions = []string{
+	PermissionChannelRead,
+	PermissionChannelWrite,
+	PermissionChannelKeyExport,
+	PermissionUserRead,
+	PermissionUserManage,
+	PermissionOptionRead,
+	PermissionOptionWrite,
+	PermissionRedemptionRead,
+	PermissionRedemptionCreate,
+	PermissionRedemptionWrite,
+	PermissionLogRead,
+	PermissionLogDelete,
+	PermissionGroupRead,
+	PermissionOrderRead,
+	PermissionDashboardRead,
+	PermissionMidjourneyRead,
+	PermissionOrganizationManage,
+	PermissionRoleManage,
+	PermissionAuditRead,
+}
+
+// built-in roles, given to the users without a role according to their role level
+const (
+	RoleNameRoot  = "root"
+	RoleNameAdmin = "admin"
+	RoleNameUser  = "user"
+)
+
+type Role struct {
+	Id          int    `json:"id"`
+	Name        string `json:"name" gorm:"type:varchar(32);uniqueIndex"`
+	Description string `json:"description"`
+	Permissions string `json:"permissions" gorm:"type:text"` // comma separated, * is a wildcard
+	Builtin     bool   `json:"builtin" gorm:"default:false"`
+	// TwoFactorRequired makes the users of the role enroll in two-factor authentication before they can log in
+	TwoFactorRequired bool  `json:"two_factor_required" gorm:"default:false"`
+	CreatedTime       int64 `json:"created_time" gorm:"bigint"`
+}
+
+func builtinRoles() []*Role {
+	adminPermissions := make([]string, 0, len(AllPermissions))
+	for _, permission := range AllPermissions {
+		// administrators could not change options nor export plain channel keys before roles existed
+		if permission != PermissionOptionRead && permission != PermissionOptionWrite && permission != PermissionRoleManage &&
+			permission != PermissionChannelKeyExport {
+			adminPermissions = append(adminPermissions, permission)
+		}
+	}
+	return []*Role{
+		{Name: RoleNameRoot, Description: "超级管理员", Permissions: "*", Builtin: true},
+		{Name: RoleNameAdmin, Description: "管理员", Permissions: strings.Join(adminPermissions, ","), Builtin: true},
+		{Name: RoleNameUser, Description: "普通用户", Permissions: "", Builtin: true},
+	}
+}
+
+// createBuiltinRoles creates the built-in roles missing from the database, existing ones keep their permissions
+func createBuiltinRoles(db *gorm.DB) error {
+	for _, role := range builtinRoles() {
+		role.CreatedTime = helper.GetTimestamp()
+		err := db.Where(Role{Name: role.Name}).FirstOrCreate(role).Error
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func builtinRoleName(roleLevel int) string {
+	if roleLevel >= common.RoleRootUser {
+		return RoleNameRoot
+	}
+	if roleLevel >= common.RoleAdminUser {
+		return RoleNameAdmin
+	}
+	return RoleNameUser
+}
+
+func SplitPermissions(permissions string) []string {
+	result := make([]string, 0)
+	for _, permission := range strings.Split(permissions, ",") {
+		permission = strings.TrimSpace(permission)
+		if permission != "" {
+			result = append(result, permission)
+		}
+	}
+	return result
+}
+
+// ValidatePermissions checks that every pattern of the list grants at least one permission
+func ValidatePermissions(permissions string) error {
+	for _, pattern := range SplitPermissions(permissions) {
+		matched := false
+		for _, permission := range AllPermissions {
+			if helper.MatchWildcard(pattern, permission) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return fmt.Errorf("unknown permission: %s", pattern)
+		}
+	}
+	return nil
+}
+
+// HasPermission reports whether one of the granted patterns matches the permission
+func HasPermission(granted []string, permission string) bool {
+	for _, pattern := range granted {
+		if helper.MatchWildcard(pattern, permission) {
+			return true
+		}
+	}
+	return false
+}
+
+// getRole returns the role assigned to a user, or the built-in role of its role level when none is assigned.
+// The role is nil when it does not exist anymore.
+func getRole(roleLevel int, roleId int) (*Role, error) {
+	var role Role
+	var err error
+	if roleId != 0 {
+		err = DB.First(&role, "id = ?", roleId).Error
+	} else {
+		err = DB.First(&role, "name = ?", builtinRoleName(roleLevel)).Error
+	}
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &role, nil
+}
+
+// getUserRole returns the user with its role, see getRole
+func getUserRole(userId int) (*User, *Role, error) {
+	var user User
+	err := DB.Select("id", "role", "role_id").First(&user, "id = ?", userId).Error
+	if err != nil {
+		return nil, nil, err
+	}
+	role, err := getRole(user.Role, user.RoleId)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &user, role, nil
+}
+
+// GetRolePermissions returns the permissions of a user with the given role level and assigned role, see GetUserPermissions
+func GetRolePermissions(roleLevel int, roleId int) ([]string, error) {
+	if roleLevel >= common.RoleRootUser {
+		return []string{"*"}, nil
+	}
+	role, err := getRole(roleLevel, roleId)
+	if err != nil {
+		return nil, err
+	}
+	if role == nil {
+		return []string{}, nil
+	}
+	return SplitPermissions(role.Permissions), nil
+}
+
+// GetUserPermissions returns the permissions granted by the role of the user, root users are granted all of them
+func GetUserPermissions(userId int) ([]string, error) {
+	var user User
+	err := DB.Select("id", "role", "role_id").First(&user, "id = ?", userId).Error
+	if err != nil {
+		return nil, err
+	}
+	return GetRolePermissions(user.Role, user.RoleId)
+}
+
+// IsTwoFactorRequired reports whether the role of the user requires two-factor authentication
+func IsTwoFactorRequired(userId int) (bool, error) {
+	_, role, err := getUserRole(userId)
+	if err != nil {
+		return false, err
+	}
+	return role != nil && role.TwoFactorRequired, nil
+}
+
+func UserHasPermission(userId int, permission string) bool {
+	permissions, err := CacheGetUserPermissions(userId)
+	return err == nil && HasPermission(permissions, permission)
+}
+
+// Outranks reports whether the granted patterns grant every permission of the other patterns and more,
+// a user holding every permission outranks everyone
+func Outranks(granted []string, other []string) bool {
+	grantedCount, otherCount := 0, 0
+	for _, permission := range AllPermissions {
+		mine := HasPermission(granted, permission)
+		theirs := HasPermission(other, permission)
+		if theirs && !mine {
+			return false
+		}
+		if mine {
+			grantedCount++
+		}
+		if theirs {
+			otherCount++
+		}
+	}
+	return grantedCount == len(AllPermissions) || grantedCount > otherCount
+}
+
+// CanManageUser reports whether the user may manage a user with the given role level and assigned role,
+// users may only manage the users they outrank so that managing them cannot be used to gain more rights
+func CanManageUser(userId int, roleLevel int, roleId int) bool {
+	myPermissions, err := CacheGetUserPermissions(userId)
+	if err != nil {
+		return false
+	}
+	permissions, err := GetRolePermissions(roleLevel, roleId)
+	if err != nil {
+		return false
+	}
+	return Outranks(myPermissions, permissions)
+}
+
+func GetAllRoles() (roles []*Role, err error) {
+	err = DB.Order("id").Find(&roles).Error
+	return roles, err
+}
+
+func GetRoleById(id int) (*Role, error) {
+	if id == 0 {
+		return nil, errors.New("id 为空！")
+	}
+	role := Role{Id: id}
+	err := DB.First(&role, "id = ?", id).Error
+	return &role, err
+}
+
+func GetRoleByName(name string) (*Role, error) {
+	role := Role{}
+	err := DB.First(&role, "name = ?", name).Error
+	return &role, err
+}
+
+func (role *Role) Insert() error {
+	role.Builtin = false
+	role.CreatedTime = helper.GetTimestamp()
+	return DB.Create(role).Error
+}
+
+// Update changes the description and permissions of the role, built-in roles keep their name
+func (role *Role) Update() error {
+	err := DB.Model(role).Select("name", "description", "permissions", "two_factor_required").Updates(role).Error
+	if err != nil {
+		return err
+	}
+	invalidateRolePermissionsCache(role)
+	return nil
+}
+
+// Delete removes the role, its users fall back to the built-in role of their role level
+func (role *Role) Delete() error {
+	// the users are looked up before they lose the role
+	invalidateRolePermissionsCache(role)
+	return DB.Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&User{}).Where("role_id = ?", role.Id).Update("role_id", 0).Error
+		if err != nil {
+			return err
+		}
+		return tx.Delete(role).Error
+	})
+}
+
+func AssignUserRole(userId int, roleId int) error {
+	err := DB.Model(&User{}).Where("id = ?", userId).Update("role_id", roleId).Error
+	if err != nil {
+		return err
+	}
+	InvalidateUserPermissionsCache(userId)
+	return nil
+}
+
+// invalidateRolePermissionsCache drops the cached permissions of the users of the role,
+// built-in roles also apply to the users of their role level without an assigned role
+func invalidateRolePermissionsCache(role *Role) {
+	if !common.RedisEnabled {
+		return
+	}
+	var userIds []int
+	tx := DB.Model(&User{}).Where("role_id = ?", role.Id)
+	switch role.Name {
+	case RoleNameAdmin:
+		tx = tx.Or("role_id = ? AND role >= ? AND role < ?", 0, common.RoleAdminUser, common.RoleRootUser)
+	case RoleNameUser:
+		tx = tx.Or("role_id = ? AND role < ?", 0, common.RoleAdminUser)
+	}
+	err := tx.Pluck("id", &userIds).Error
+	if err != nil {
+		logger.SysError("failed to find the users of the role: " + err.Error())
+		return
+	}
+	InvalidateUserPermissionsCache(userIds...)
+}
@@ -0,0 +1,221 @@
+package model
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/songquanpeng/one-api/common"
+	"github.com/songquanpeng/one-api/common/helper"
+	"github.com/songquanpeng/one-api/common/logger"
+	"gorm.io/gorm"
+)
+
+// SessionMaxAge matches the max age of the session cookie
+const SessionMaxAge = 30 * 24 * time.Hour
+
+// the last seen time of a session is written at most once per interval
+const sessionTouchInterval = time.Minute
+
+// Session is a login of a user, the cookie only holds its key so that it can be listed and revoked
+type Session struct {
+	Id           int    `json:"id"`
+	UserId       int    `json:"user_id" gorm:"index"`
+	KeyHash      string `json:"-" gorm:"type:char(64);uniqueIndex"`
+	Device       string `json:"device"`
+	UserAgent    string `json:"user_agent"`
+	Ip           string `json:"ip" gorm:"type:varchar(64)"`
+	CreatedTime  int64  `json:"created_time" gorm:"bigint"`
+	LastSeenTime int64  `json:"last_seen_time" gorm:"bigint"`
+	ExpiredTime  int64  `json:"expired_time" gorm:"bigint;index"`
+}
+
+func hashSessionKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+func sessionCacheKey(keyHash string) string {
+	return fmt.Sprintf("session:%s", keyHash)
+}
+
+// describeDevice returns the browser and the operating system found in the user agent
+func describeDevice(userAgent string) string {
+	browser := "未知浏览器"
+	for _, b := range []struct{ token, name string }{
+		{"Edg/", "Edge"},
+		{"OPR/", "Opera"},
+		{"Firefox/", "Firefox"},
+		{"Chrome/", "Chrome"},
+		{"Safari/", "Safari"},
+		{"curl/", "curl"},
+	} {
+		if strings.Contains(userAgent, b.token) {
+			browser = b.name
+			break
+		}
+	}
+	os := "未知系统"
+	for _, o := range []struct{ token, name string }{
+		{"Windows", "Windows"},
+		{"iPhone", "iOS"},
+		{"iPad", "iPadOS"},
+		{"Android", "Android"},
+		{"Mac OS X", "macOS"},
+		{"Linux", "Linux"},
+	} {
+		if strings.Contains(userAgent, o.token) {
+			os = o.name
+			break
+		}
+	}
+	return browser + " / " + os
+}
+
+// CreateSession records a new login of the user and returns the key to put in the cookie
+func CreateSession(userId int, userAgent string, ip string) (string, error) {
+	key := helper.GetUUID() + helper.GetUUID()
+	now := helper.GetTimestamp()
+	if len(userAgent) > 255 {
+		userAgent = userAgent[:255]
+	}
+	session := &Session{
+		UserId:       userId,
+		KeyHash:      hashSessionKey(key),
+		Device:       describeDevice(userAgent),
+		UserAgent:    userAgent,
+		Ip:           ip,
+		CreatedTime:  now,
+		LastSeenTime: now,
+		ExpiredTime:  now + int64(SessionMaxAge.Seconds()),
+	}
+	err := DB.Create(session).Error
+	if err != nil {
+		return "", err
+	}
+	DB.Where("user_id = ? AND expired_time < ?", userId, now).Delete(&Session{})
+	return key, nil
+}
+
+func getSessionByKeyHash(keyHash string) (*Session, error) {
+	session := Session{}
+	result := DB.Where("key_hash = ?", keyHash).Limit(1).Find(&session)
+	if result.Error == nil && result.RowsAffected == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &session, result.Error
+}
+
+// ValidateSession returns the active session of the key and refreshes its last seen time and IP
+func ValidateSession(key string, ip string) (*Session, error) {
+	if key == "" {
+		return nil, errors.New("session not found")
+	}
+	keyHash := hashSessionKey(key)
+	var session *Session
+	var err error
+	cached := false
+	if common.RedisEnabled {
+		var sessionString string
+		sessionString, err = common.RedisGet(sessionCacheKey(keyHash))
+		if err == nil {
+			session = &Session{}
+			err = json.Unmarshal([]byte(sessionString), session)
+			cached = err == nil
+		}
+	}
+	if !cached {
+		session, err = getSessionByKeyHash(keyHash)
+		if err != nil {
+			return nil, errors.New("session not found")
+		}
+	}
+	now := helper.GetTimestamp()
+	if session.ExpiredTime < now {
+		return nil, errors.New("session expired")
+	}
+	touch := now-session.LastSeenTime >= int64(sessionTouchInterval.Seconds()) || session.Ip != ip
+	if touch {
+		session.LastSeenTime = now
+		session.Ip = ip
+		result := DB.Model(&Session{}).Where("id = ?", session.Id).Updates(map[string]interface{}{
+			"last_seen_time": now,
+			"ip":             ip,
+		})
+		if result.Error == nil && result.RowsAffected == 0 {
+			// the cached session may have been revoked meanwhile
+			if _, err := getSessionByKeyHash(keyHash); err != nil {
+				return nil, errors.New("session not found")
+			}
+		}
+	}
+	if common.RedisEnabled && (touch || !cached) {
+		jsonBytes, err := json.Marshal(session)
+		if err == nil {
+			err = common.RedisSet(sessionCacheKey(keyHash), string(jsonBytes), sessionTouchInterval)
+		}
+		if err != nil {
+			logger.SysError("Redis set session error: " + err.Error())
+		}
+	}
+	return session, nil
+}
+
+func GetUserSessions(userId int) (sessions []*Session, err error) {
+	err = DB.Where("user_id = ? AND expired_time >= ?", userId, helper.GetTimestamp()).
+		Order("last_seen_time desc").Find(&sessions).Error
+	return sessions, err
+}
+
+// revokeSessions deletes the sessions matched by the query and their cache entries
+func revokeSessions(query string, args ...interface{}) (int64, error) {
+	var sessions []*Session
+	err := DB.Select("id", "key_hash").Where(query, args...).Find(&sessions).Error
+	if err != nil || len(sessions) == 0 {
+		return 0, err
+	}
+	ids := make([]int, 0, len(sessions))
+	for _, session := range sessions {
+		ids = append(ids, session.Id)
+	}
+	result := DB.Where("id IN ?", ids).Delete(&Session{})
+	if result.Error != nil {
+		return 0, result.Error
+	}
+	if common.RedisEnabled {
+		for _, session := range sessions {
+			err = common.RedisDel(sessionCacheKey(session.KeyHash))
+			if err != nil {
+				logger.SysError("Redis delete session error: " + err.Error())
+			}
+		}
+	}
+	return result.RowsAffected, nil
+}
+
+// RevokeSession deletes a session of the user
+func RevokeSession(userId int, id int) error {
+	count, err := revokeSessions("user_id = ? AND id = ?", userId, id)
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		return errors.New("会话不存在")
+	}
+	return nil
+}
+
+// RevokeSessionByKey deletes the session of the key, it is used when logging out
+func RevokeSessionByKey(key string) error {
+	_, err := revokeSessions("key_hash = ?", hashSessionKey(key))
+	return err
+}
+
+// RevokeUserSessions logs the user out everywhere, except from the session with the given id if it is not 0
+func RevokeUserSessions(userId int, exceptId int) (int64, error) {
+	return revokeSessions("user_id = ? AND id <> ?", userId, exceptId)
+}
@@ -0,0 +1,78 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/songquanpeng/one-api/common"
+	"github.com/songquanpeng/one-api/common/helper"
+	"gorm.io/gorm"
+)
+
+// InsertSubUser creates a user under the parent, it starts without quota and gets none of the registration rewards
+func (user *User) InsertSubUser(parentId int) error {
+	var err error
+	user.Password, err = common.Password2Hash(user.Password)
+	if err != nil {
+		return err
+	}
+	user.ParentId = parentId
+	user.InviterId = parentId
+	user.Role = common.RoleCommonUser
+	user.Status = common.UserStatusEnabled
+	user.Quota = 0
+	user.AccessToken = helper.GetUUID()
+	user.AffCode = helper.GetRandomString(4)
+	return DB.Create(user).Error
+}
+
+func GetSubUsers(parentId int) (users []*User, err error) {
+	err = DB.Omit("password").Where("parent_id = ? AND status != ?", parentId, common.UserStatusDeleted).Order("id desc").Find(&users).Error
+	return users, err
+}
+
+func GetSubUserIds(parentId int) (ids []int, err error) {
+	err = DB.Model(&User{}).Where("parent_id = ?", parentId).Pluck("id", &ids).Error
+	return ids, err
+}
+
+// GetSubUser returns the user only if it was created by the parent
+func GetSubUser(parentId int, id int) (*User, error) {
+	user := User{}
+	err := DB.Omit("password").Where("id = ? AND parent_id = ? AND status != ?", id, parentId, common.UserStatusDeleted).First(&user).Error
+	return &user, err
+}
+
+// TransferQuotaToSubUser moves quota from the parent to the sub-user, a negative quota takes it back.
+// The quota of a sub-user is always carved out of the one of its parent, so they never spend more together.
+func TransferQuotaToSubUser(parentId int, subUserId int, quota int64) error {
+	if quota == 0 {
+		return errors.New("额度不能为 0")
+	}
+	from, to, amount := parentId, subUserId, quota
+	if quota < 0 {
+		from, to, amount = subUserId, parentId, -quota
+	}
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&User{}).Where("id = ? AND quota >= ?", from, amount).Update("quota", gorm.Expr("quota - ?", amount))
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errors.New("额度不足")
+		}
+		return tx.Model(&User{}).Where("id = ?", to).Update("quota", gorm.Expr("quota + ?", amount)).Error
+	})
+	if err != nil {
+		return err
+	}
+	subUsername := GetUsernameById(subUserId)
+	if quota > 0 {
+		RecordLog(parentId, LogTypeManage, fmt.Sprintf("向子用户 %s 分配额度 %s", subUsername, common.LogQuota(amount)))
+		RecordLog(subUserId, LogTypeTopup, fmt.Sprintf("上级用户分配额度 %s", common.LogQuota(amount)))
+	} else {
+		RecordLog(parentId, LogTypeManage, fmt.Sprintf("从子用户 %s 收回额度 %s", subUsername, common.LogQuota(amount)))
+		RecordLog(subUserId, LogTypeManage, fmt.Sprintf("上级用户收回额度 %s", common.LogQuota(amount)))
+	}
+	return nil
+}
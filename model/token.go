@@ -3,6 +3,8 @@ package model
 import (
 	"errors"
 	"fmt"
+	"net"
+	"strings"
 	"time"
 
 	"github.com/songquanpeng/one-api/common"
@@ -16,7 +18,10 @@ import (
 type Token struct {
 	Id                   int    `json:"id"`
 	UserId               int    `json:"user_id"`
-	Key                  string `json:"key" gorm:"type:char(48);uniqueIndex"`
+	OrganizationId       int    `json:"organization_id" gorm:"index;default:0"` // organization charged for the token, 0 charges the user
+	Key                  string `json:"key,omitempty" gorm:"-"` // the full key, only returned when it is issued
+	KeyHash              string `json:"-" gorm:"column:key;type:char(48);uniqueIndex"`
+	KeyPrefix            string `json:"key_prefix" gorm:"type:varchar(16);default:''"` // start of the key, to tell tokens apart
 	Status               int    `json:"status" gorm:"default:1"`
 	Name                 string `json:"name" gorm:"index" `
 	CreatedTime          int64  `json:"created_time" gorm:"bigint"`
@@ -27,6 +32,116 @@ type Token struct {
 	UsedQuota            int64  `json:"used_quota" gorm:"default:0"` // used quota
 	TokenRemindThreshold int64  `json:"token_remind_threshold"`
 	TokenLastNoticeTime  int64  `json:"token_last_notice_time" gorm:"default:0"`
+	Models               string `json:"models" gorm:"type:text"`           // allowed models, comma separated, * is a wildcard, empty means all
+	DeniedModels         string `json:"denied_models" gorm:"type:text"`    // denied models, take precedence over the allowed ones
+	AllowIps             string `json:"allow_ips" gorm:"type:text"`        // allowed client IPs and CIDR ranges, empty means all
+	Endpoints            string `json:"endpoints" gorm:"type:text"`        // allowed relay modes by name, comma separated, * is a wildcard, empty means all
+	DeniedEndpoints      string `json:"denied_endpoints" gorm:"type:text"` // denied relay modes, take precedence over the allowed ones
+	RateLimits           `gorm:"embedded"`
+	TokenBudget          `gorm:"embedded"`
+	// hash of the key replaced by the last rotation, accepted until PreviousKeyExpiredTime
+	PreviousKeyHash        string `json:"-" gorm:"column:previous_key;type:char(48);index"`
+	PreviousKeyExpiredTime int64  `json:"previous_key_expired_time" gorm:"bigint;default:0"`
+}
+
+func splitTokenAllowIps(allowIps string) []string {
+	return strings.FieldsFunc(allowIps, func(r rune) bool {
+		return r == ',' || r == '\n' || r == ' ' || r == '\r' || r == '\t'
+	})
+}
+
+// ValidateTokenAllowIps checks that every entry of the list is an IP or a CIDR range
+func ValidateTokenAllowIps(allowIps string) error {
+	for _, entry := range splitTokenAllowIps(allowIps) {
+		if strings.Contains(entry, "/") {
+			if _, _, err := net.ParseCIDR(entry); err != nil {
+				return fmt.Errorf("invalid CIDR: %s", entry)
+			}
+		} else if net.ParseIP(entry) == nil {
+			return fmt.Errorf("invalid IP: %s", entry)
+		}
+	}
+	return nil
+}
+
+// IsIPAllowed reports whether the token may be used from the client IP
+func (token *Token) IsIPAllowed(clientIP string) bool {
+	entries := splitTokenAllowIps(token.AllowIps)
+	if len(entries) == 0 {
+		return true
+	}
+	ip := net.ParseIP(clientIP)
+	if ip == nil {
+		return false
+	}
+	for _, entry := range entries {
+		if strings.Contains(entry, "/") {
+			_, ipNet, err := net.ParseCIDR(entry)
+			if err == nil && ipNet.Contains(ip) {
+				return true
+			}
+		} else if allowedIP := net.ParseIP(entry); allowedIP != nil && allowedIP.Equal(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// isAllowedByPatterns checks the name against comma separated lists of allowed and denied wildcard patterns
+func isAllowedByPatterns(allowedPatterns string, deniedPatterns string, name string) bool {
+	for _, pattern := range strings.Split(deniedPatterns, ",") {
+		pattern = strings.TrimSpace(pattern)
+		if pattern != "" && helper.MatchWildcard(pattern, name) {
+			return false
+		}
+	}
+	allowed := true
+	for _, pattern := range strings.Split(allowedPatterns, ",") {
+		pattern = strings.TrimSpace(pattern)
+		if pattern == "" {
+			continue
+		}
+		if helper.MatchWildcard(pattern, name) {
+			return true
+		}
+		allowed = false
+	}
+	return allowed
+}
+
+// IsModelAllowedForToken checks the model against the allowed and denied model lists of a token
+func IsModelAllowedForToken(models string, deniedModels string, modelName string) bool {
+	return isAllowedByPatterns(models, deniedModels, modelName)
+}
+
+// IsEndpointAllowedForToken checks the name of a relay mode against the endpoint scopes of a token
+func IsEndpointAllowedForToken(endpoints string, deniedEndpoints string, endpoint string) bool {
+	return isAllowedByPatterns(endpoints, deniedEndpoints, endpoint)
+}
+
+// ValidateTokenEndpoints checks that every pattern of the list matches at least one of the endpoints
+func ValidateTokenEndpoints(patterns string, endpoints []string) error {
+	for _, pattern := range strings.Split(patterns, ",") {
+		pattern = strings.TrimSpace(pattern)
+		if pattern == "" {
+			continue
+		}
+		matched := false
+		for _, endpoint := range endpoints {
+			if helper.MatchWildcard(pattern, endpoint) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return fmt.Errorf("unknown endpoint: %s", pattern)
+		}
+	}
+	return nil
+}
+
+func (token *Token) IsModelAllowed(modelName string) bool {
+	return IsModelAllowedForToken(token.Models, token.DeniedModels, modelName)
 }
 
 func GetAllUserTokens(userId int, startIdx int, num int, order string) ([]*Token, error) {
@@ -112,6 +227,9 @@ func ValidateUserToken(key string) (token *Token, err error) {
 		}
 		return nil, errors.New("Token verification failed")
 	}
+	if token.IsPreviousKey(key) && token.PreviousKeyExpiredTime <= helper.GetTimestamp() {
+		return nil, errors.New("The token key has been rotated")
+	}
 	if token.Status == common.TokenStatusExhausted {
 		return nil, errors.New("The token quota has been exhausted")
 	} else if token.Status == common.TokenStatusExpired {
@@ -141,6 +259,9 @@ func ValidateUserToken(key string) (token *Token, err error) {
 		}
 		return nil, errors.New("The token quota has been exhausted")
 	}
+	if token.IsBudgetExhausted() {
+		return nil, fmt.Errorf("The %s budget of the token has been exhausted", token.BudgetPeriod)
+	}
 	return token, nil
 }
 
@@ -171,9 +292,15 @@ func (token *Token) Insert() error {
 }
 
 // Update Make sure your token's fields is completed, because this will update non-zero values
-func (token *Token) Update() error {
+// Update writes the settings of the token, the budget counter is only written when restartBudget is set
+// since relayed requests keep updating it concurrently
+func (token *Token) Update(restartBudget bool) error {
 	var err error
-	err = DB.Model(token).Select("name", "status", "expired_time", "remain_quota", "token_remind_threshold", "unlimited_quota").Updates(token).Error
+	columns := []string{"name", "status", "expired_time", "remain_quota", "token_remind_threshold", "unlimited_quota", "models", "denied_models", "allow_ips", "endpoints", "denied_endpoints", "rpm_limit", "tpm_limit", "concurrency_limit", "budget_period", "budget_quota"}
+	if restartBudget {
+		columns = append(columns, "budget_used", "budget_reset_time")
+	}
+	err = DB.Model(token).Select(columns).Updates(token).Error
 	return err
 }
 
@@ -299,13 +426,40 @@ func PreConsumeTokenQuota(tokenId int, quota int64) (err error) {
 	if !token.UnlimitedQuota && token.RemainQuota < quota {
 		return errors.New("Insufficient token amount")
 	}
+	if token.HasBudget() && token.GetBudgetRemain() < quota {
+		return fmt.Errorf("Insufficient %s budget of the token", token.BudgetPeriod)
+	}
 
-	user, err := GetUserById(token.UserId, true)
+	if token.OrganizationId != 0 {
+		err = checkOrganizationQuota(token.OrganizationId, quota)
+	} else {
+		err = checkUserQuota(token.UserId, quota, currentTime)
+	}
+	if err != nil {
+		return err
+	}
+
+	if !token.UnlimitedQuota {
+		err = DecreaseTokenQuota(tokenId, quota)
+		if err != nil {
+			return err
+		}
+	}
+	err = consumeTokenBudget(token, quota)
+	if err != nil {
+		return err
+	}
+	return decreaseBillingQuota(token, quota)
+}
+
+// checkUserQuota checks the quota of the user and reminds the user by email when it runs low
+func checkUserQuota(userId int, quota int64, currentTime int64) error {
+	user, err := GetUserById(userId, true)
 	if err != nil {
 		return err
 	}
 	var userQuota int64
-	userQuota, err = GetUserQuota(token.UserId)
+	userQuota, err = GetUserQuota(userId)
 	if err != nil {
 		return err
 	}
@@ -320,7 +474,7 @@ func PreConsumeTokenQuota(tokenId int, quota int64) (err error) {
 	if quotaTooLow || noMoreQuota {
 		if currentTime-user.UserLastNoticeTime > 3600 { // 3600秒等于1小时
 			var email string
-			email, err = GetUserEmail(token.UserId)
+			email, err = GetUserEmail(userId)
 			if err != nil {
 				logger.SysError("failed to fetch user email: " + err.Error())
 				return err
@@ -337,22 +491,29 @@ func PreConsumeTokenQuota(tokenId int, quota int64) (err error) {
 					return err
 				}
 				// 更新用户上次发送时间
-				err = UpdateUserLastNoticeTime(token.UserId, currentTime)
+				err = UpdateUserLastNoticeTime(userId, currentTime)
 				if err != nil {
 					return err
 				}
 			}
 		}
 	}
+	return nil
+}
 
-	if !token.UnlimitedQuota {
-		err = DecreaseTokenQuota(tokenId, quota)
-		if err != nil {
-			return err
-		}
+// decreaseBillingQuota charges the organization owning the token, or its user
+func decreaseBillingQuota(token *Token, quota int64) error {
+	if token.OrganizationId != 0 {
+		return DecreaseOrganizationQuota(token.OrganizationId, quota)
 	}
-	err = DecreaseUserQuota(token.UserId, quota)
-	return err
+	return DecreaseUserQuota(token.UserId, quota)
+}
+
+func increaseBillingQuota(token *Token, quota int64) error {
+	if token.OrganizationId != 0 {
+		return IncreaseOrganizationQuota(token.OrganizationId, quota)
+	}
+	return IncreaseUserQuota(token.UserId, quota)
 }
 
 func UpdateTokenLastNoticeTime(tokenId int, lastNoticeTime int64) error {
@@ -368,10 +529,13 @@ func UpdateUserLastNoticeTime(userId int, lastNoticeTime int64) error {
 
 func PostConsumeTokenQuota(tokenId int, quota int64) (err error) {
 	token, err := GetTokenById(tokenId)
+	if err != nil {
+		return err
+	}
 	if quota > 0 {
-		err = DecreaseUserQuota(token.UserId, quota)
+		err = decreaseBillingQuota(token, quota)
 	} else {
-		err = IncreaseUserQuota(token.UserId, -quota)
+		err = increaseBillingQuota(token, -quota)
 	}
 	if err != nil {
 		return err
@@ -386,5 +550,6 @@ func PostConsumeTokenQuota(tokenId int, quota int64) (err error) {
 			return err
 		}
 	}
-	return nil
+	// the budget is charged like the lifetime quota, requests are refused by ValidateUserToken once it is exhausted
+	return consumeTokenBudget(token, quota)
 }
@@ -0,0 +1,118 @@
+package model
+
+import (
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	TokenBudgetPeriodDaily   = "daily"
+	TokenBudgetPeriodWeekly  = "weekly"
+	TokenBudgetPeriodMonthly = "monthly"
+)
+
+// TokenBudget is a recurring spend budget of a token, reset at the start of every day, week (monday) or month
+// in the local time of the server. It applies together with the lifetime quota, also on tokens with unlimited quota.
+type TokenBudget struct {
+	BudgetPeriod    string `json:"budget_period" gorm:"type:varchar(16);default:''"` // empty means no budget
+	BudgetQuota     int64  `json:"budget_quota" gorm:"default:0"`
+	BudgetUsed      int64  `json:"budget_used" gorm:"default:0"`              // used in the current period
+	BudgetResetTime int64  `json:"budget_reset_time" gorm:"bigint;default:0"` // end of the current period
+	BudgetRemain    int64  `json:"budget_remain" gorm:"-"`
+}
+
+func ValidateTokenBudget(period string, quota int64) error {
+	switch period {
+	case "":
+		return nil
+	case TokenBudgetPeriodDaily, TokenBudgetPeriodWeekly, TokenBudgetPeriodMonthly:
+	default:
+		return fmt.Errorf("unknown budget period: %s", period)
+	}
+	if quota <= 0 {
+		return fmt.Errorf("the budget quota must be positive")
+	}
+	return nil
+}
+
+// NextTokenBudgetResetTime returns the start of the period following the one now is in
+func NextTokenBudgetResetTime(period string, now time.Time) int64 {
+	year, month, day := now.Date()
+	switch period {
+	case TokenBudgetPeriodDaily:
+		return time.Date(year, month, day+1, 0, 0, 0, 0, now.Location()).Unix()
+	case TokenBudgetPeriodWeekly:
+		daysToMonday := (8 - int(now.Weekday())) % 7
+		if daysToMonday == 0 {
+			daysToMonday = 7
+		}
+		return time.Date(year, month, day+daysToMonday, 0, 0, 0, 0, now.Location()).Unix()
+	case TokenBudgetPeriodMonthly:
+		return time.Date(year, month+1, 1, 0, 0, 0, 0, now.Location()).Unix()
+	}
+	return 0
+}
+
+func (budget *TokenBudget) HasBudget() bool {
+	return budget.BudgetPeriod != ""
+}
+
+// StartBudgetPeriod restarts the budget from the current period, it is used when the budget is configured
+func (budget *TokenBudget) StartBudgetPeriod() {
+	budget.BudgetUsed = 0
+	budget.BudgetResetTime = NextTokenBudgetResetTime(budget.BudgetPeriod, time.Now())
+}
+
+// RefreshBudget moves the budget to the current period when the previous one is over, it reports whether it did
+func (budget *TokenBudget) RefreshBudget() bool {
+	if !budget.HasBudget() || time.Now().Unix() < budget.BudgetResetTime {
+		return false
+	}
+	budget.StartBudgetPeriod()
+	return true
+}
+
+// GetBudgetRemain returns what is left of the budget in the current period, without changing the budget
+func (budget TokenBudget) GetBudgetRemain() int64 {
+	budget.RefreshBudget()
+	remain := budget.BudgetQuota - budget.BudgetUsed
+	if remain < 0 {
+		return 0
+	}
+	return remain
+}
+
+// FillBudgetRemain shows the budget of the current period, the stored one may still be the previous period
+func (budget *TokenBudget) FillBudgetRemain() {
+	budget.RefreshBudget()
+	budget.BudgetRemain = budget.GetBudgetRemain()
+}
+
+func (budget TokenBudget) IsBudgetExhausted() bool {
+	return budget.HasBudget() && budget.GetBudgetRemain() <= 0
+}
+
+// consumeTokenBudget adds the quota to the budget used by the token, a negative quota gives it back
+func consumeTokenBudget(token *Token, quota int64) error {
+	if !token.HasBudget() || quota == 0 {
+		return nil
+	}
+	previousResetTime := token.BudgetResetTime
+	if token.RefreshBudget() {
+		// only the first request of the new period resets the counter
+		err := DB.Model(&Token{}).Where("id = ? AND budget_reset_time = ?", token.Id, previousResetTime).Updates(
+			map[string]interface{}{
+				"budget_used":       0,
+				"budget_reset_time": token.BudgetResetTime,
+			},
+		).Error
+		if err != nil {
+			return err
+		}
+	}
+	return DB.Model(&Token{}).Where("id = ?", token.Id).Update(
+		"budget_used", gorm.Expr("CASE WHEN budget_used + ? < 0 THEN 0 ELSE budget_used + ? END", quota, quota),
+	).Error
+}
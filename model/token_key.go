@@ -0,0 +1,147 @@
+package model
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/songquanpeng/one-api/common"
+	"github.com/songquanpeng/one-api/common/config"
+	"github.com/songquanpeng/one-api/common/helper"
+	"github.com/songquanpeng/one-api/common/logger"
+	"gorm.io/gorm"
+)
+
+const tokenKeyPrefixLength = 6
+
+// HashTokenKey returns the keyed hash stored in place of a token key,
+// truncated to the 48 hex characters of the key column
+func HashTokenKey(key string) string {
+	mac := hmac.New(sha256.New, []byte(config.TokenKeySecret))
+	mac.Write([]byte(key))
+	return hex.EncodeToString(mac.Sum(nil))[:48]
+}
+
+func tokenKeyPrefix(key string) string {
+	if len(key) > tokenKeyPrefixLength {
+		return key[:tokenKeyPrefixLength]
+	}
+	return key
+}
+
+// SetKey gives the token a new key, which is kept in Key only until the token is returned to its owner
+func (token *Token) SetKey(key string) {
+	token.Key = key
+	token.KeyHash = HashTokenKey(key)
+	token.KeyPrefix = tokenKeyPrefix(key)
+}
+
+// GetTokenByKey finds the token by its key, or by its previous key during the grace period of a rotation
+func GetTokenByKey(key string) (*Token, error) {
+	keyCol := "`key`"
+	if common.UsingPostgreSQL {
+		keyCol = `"key"`
+	}
+	keyHash := HashTokenKey(key)
+	var token Token
+	err := DB.Where(keyCol+" = ?", keyHash).First(&token).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		err = DB.Where("previous_key = ? AND previous_key_expired_time > ?", keyHash, helper.GetTimestamp()).First(&token).Error
+	}
+	return &token, err
+}
+
+// IsPreviousKey reports whether the key is the one replaced by the last rotation of the token
+func (token *Token) IsPreviousKey(key string) bool {
+	return HashTokenKey(key) != token.KeyHash
+}
+
+// RotateKey issues a new key, the current one stays valid for gracePeriod seconds.
+// Only the last replaced key is kept, rotating again ends the grace period of the one before.
+func (token *Token) RotateKey(gracePeriod int64) error {
+	previousKeyHash := token.KeyHash
+	replacedKeyHash := token.PreviousKeyHash
+	token.SetKey(helper.GenerateKey())
+	token.PreviousKeyHash = ""
+	token.PreviousKeyExpiredTime = 0
+	if gracePeriod > 0 {
+		token.PreviousKeyHash = previousKeyHash
+		token.PreviousKeyExpiredTime = helper.GetTimestamp() + gracePeriod
+	}
+	err := DB.Model(token).Select("key", "key_prefix", "previous_key", "previous_key_expired_time").Updates(token).Error
+	if err != nil {
+		return err
+	}
+	// the cached token must not outlive the grace period, nor keep the key whose grace period ended early
+	deleteTokenCache(previousKeyHash, replacedKeyHash)
+	return nil
+}
+
+// deleteTokenCache removes the tokens cached under the given key hashes
+func deleteTokenCache(keyHashes ...string) {
+	if !common.RedisEnabled {
+		return
+	}
+	for _, keyHash := range keyHashes {
+		if keyHash == "" {
+			continue
+		}
+		err := common.RedisDel(fmt.Sprintf("token:%s", keyHash))
+		if err != nil {
+			logger.SysError("Redis delete token error: " + err.Error())
+		}
+	}
+}
+
+// cachedToken is a token as cached in Redis, with the key hashes that the API does not return
+type cachedToken struct {
+	*Token
+	KeyHash         string `json:"key_hash"`
+	PreviousKeyHash string `json:"previous_key_hash"`
+}
+
+func marshalCachedToken(token *Token) ([]byte, error) {
+	return json.Marshal(cachedToken{Token: token, KeyHash: token.KeyHash, PreviousKeyHash: token.PreviousKeyHash})
+}
+
+func unmarshalCachedToken(data []byte) (*Token, error) {
+	cached := cachedToken{Token: &Token{}}
+	err := json.Unmarshal(data, &cached)
+	if err != nil {
+		return nil, err
+	}
+	cached.Token.KeyHash = cached.KeyHash
+	cached.Token.PreviousKeyHash = cached.PreviousKeyHash
+	return cached.Token, nil
+}
+
+// migrateTokenKeys replaces the plaintext keys stored before keys were hashed, they have no prefix yet
+func migrateTokenKeys(db *gorm.DB) error {
+	var tokens []*Token
+	err := db.Select("id", "key", "previous_key").Where("key_prefix = ? OR key_prefix IS NULL", "").Find(&tokens).Error
+	if err != nil {
+		return err
+	}
+	if len(tokens) == 0 {
+		return nil
+	}
+	logger.SysLog(fmt.Sprintf("hashing the keys of %d tokens", len(tokens)))
+	for _, token := range tokens {
+		// KeyHash and PreviousKeyHash still hold the plaintext keys here
+		updates := map[string]interface{}{
+			"key":        HashTokenKey(token.KeyHash),
+			"key_prefix": tokenKeyPrefix(token.KeyHash),
+		}
+		if token.PreviousKeyHash != "" {
+			updates["previous_key"] = HashTokenKey(token.PreviousKeyHash)
+		}
+		err = db.Model(&Token{}).Where("id = ?", token.Id).Updates(updates).Error
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,205 @@
+package model
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/songquanpeng/one-api/common"
+	"github.com/songquanpeng/one-api/common/config"
+	"github.com/songquanpeng/one-api/common/helper"
+	"github.com/songquanpeng/one-api/common/logger"
+	"github.com/songquanpeng/one-api/common/totp"
+	"gorm.io/gorm"
+)
+
+const twoFactorRecoveryCodeCount = 10
+
+// TwoFactor holds the TOTP secret of a user, it is enabled once the user confirmed a first code
+type TwoFactor struct {
+	UserId        int    `json:"user_id" gorm:"primaryKey;autoIncrement:false"`
+	Secret        string `json:"-" gorm:"type:text"` // encrypted with the channel master key when it is configured
+	Enabled       bool   `json:"enabled" gorm:"default:false"`
+	RecoveryCodes string `json:"-" gorm:"type:text"` // hashes of the unused recovery codes, comma separated
+	LastStep      int64  `json:"-" gorm:"bigint"`    // time step of the last accepted code, a code is accepted only once
+	CreatedTime   int64  `json:"created_time" gorm:"bigint"`
+}
+
+func GetTwoFactor(userId int) (*TwoFactor, error) {
+	twoFactor := TwoFactor{}
+	err := DB.First(&twoFactor, "user_id = ?", userId).Error
+	return &twoFactor, err
+}
+
+func IsTwoFactorEnabled(userId int) bool {
+	var count int64
+	err := DB.Model(&TwoFactor{}).Where("user_id = ? AND enabled = ?", userId, true).Count(&count).Error
+	return err == nil && count > 0
+}
+
+// validate checks the code against the secret, which is decrypted only for the check
+func (twoFactor *TwoFactor) validate(code string) (int64, bool, error) {
+	secret, err := decryptChannelKey(twoFactor.Secret)
+	if err != nil {
+		return 0, false, err
+	}
+	step, ok := totp.Validate(secret, code, time.Now())
+	return step, ok, nil
+}
+
+func hashRecoveryCode(code string) string {
+	code = strings.ToLower(strings.ReplaceAll(strings.TrimSpace(code), "-", ""))
+	sum := sha256.Sum256([]byte(code))
+	return hex.EncodeToString(sum[:])
+}
+
+// generateRecoveryCodes returns new recovery codes and the hashes to store
+func generateRecoveryCodes() (codes []string, hashes string) {
+	hashList := make([]string, 0, twoFactorRecoveryCodeCount)
+	for i := 0; i < twoFactorRecoveryCodeCount; i++ {
+		code := common.GenerateVerificationCode(10)
+		code = code[:5] + "-" + code[5:]
+		codes = append(codes, code)
+		hashList = append(hashList, hashRecoveryCode(code))
+	}
+	return codes, strings.Join(hashList, ",")
+}
+
+// PrepareTwoFactor generates a new secret for the user, it has to be confirmed by EnableTwoFactor
+func PrepareTwoFactor(userId int) (string, error) {
+	if IsTwoFactorEnabled(userId) {
+		return "", errors.New("已启用两步验证")
+	}
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return "", err
+	}
+	encryptedSecret, err := encryptChannelKey(secret)
+	if err != nil {
+		return "", err
+	}
+	err = DB.Save(&TwoFactor{
+		UserId:      userId,
+		Secret:      encryptedSecret,
+		CreatedTime: helper.GetTimestamp(),
+	}).Error
+	return secret, err
+}
+
+// EnableTwoFactor enables two-factor authentication once the code matches the prepared secret,
+// it returns the recovery codes which are only shown this time
+func EnableTwoFactor(userId int, code string) ([]string, error) {
+	twoFactor, err := GetTwoFactor(userId)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, errors.New("请先生成两步验证密钥")
+	}
+	if err != nil {
+		return nil, err
+	}
+	if twoFactor.Enabled {
+		return nil, errors.New("已启用两步验证")
+	}
+	step, ok, err := twoFactor.validate(code)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, errors.New("验证码错误")
+	}
+	codes, hashes := generateRecoveryCodes()
+	err = DB.Model(twoFactor).Updates(map[string]interface{}{
+		"enabled":        true,
+		"recovery_codes": hashes,
+		"last_step":      step,
+	}).Error
+	if err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// VerifyTwoFactor checks a TOTP code or a recovery code of the user, recovery codes can be used once
+func VerifyTwoFactor(userId int, code string) error {
+	twoFactor, err := GetTwoFactor(userId)
+	if err != nil || !twoFactor.Enabled {
+		return errors.New("未启用两步验证")
+	}
+	step, ok, err := twoFactor.validate(code)
+	if err != nil {
+		return err
+	}
+	if ok {
+		result := DB.Model(&TwoFactor{}).Where("user_id = ? AND last_step < ?", userId, step).Update("last_step", step)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errors.New("验证码已被使用，请等待下一个验证码")
+		}
+		return nil
+	}
+	hash := hashRecoveryCode(code)
+	hashes := strings.Split(twoFactor.RecoveryCodes, ",")
+	for i, h := range hashes {
+		if h == "" || h != hash {
+			continue
+		}
+		remaining := strings.Join(append(hashes[:i:i], hashes[i+1:]...), ",")
+		result := DB.Model(&TwoFactor{}).Where("user_id = ? AND recovery_codes = ?", userId, twoFactor.RecoveryCodes).Update("recovery_codes", remaining)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errors.New("恢复码已被使用")
+		}
+		RecordLog(userId, LogTypeSecurity, "使用两步验证恢复码登录")
+		return nil
+	}
+	return errors.New("验证码错误")
+}
+
+// RegenerateRecoveryCodes replaces the recovery codes of the user
+func RegenerateRecoveryCodes(userId int) ([]string, error) {
+	if !IsTwoFactorEnabled(userId) {
+		return nil, errors.New("未启用两步验证")
+	}
+	codes, hashes := generateRecoveryCodes()
+	err := DB.Model(&TwoFactor{}).Where("user_id = ?", userId).Update("recovery_codes", hashes).Error
+	if err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+func DisableTwoFactor(userId int) error {
+	return DB.Where("user_id = ?", userId).Delete(&TwoFactor{}).Error
+}
+
+// encryptTwoFactorSecrets encrypts the TOTP secrets stored before the master key was configured
+func encryptTwoFactorSecrets(db *gorm.DB) error {
+	if config.ChannelMasterKey == "" {
+		return nil
+	}
+	var twoFactors []*TwoFactor
+	err := db.Select("user_id", "secret").Where("secret <> ? AND secret NOT LIKE ?", "", "enc:%").Find(&twoFactors).Error
+	if err != nil {
+		return err
+	}
+	for _, twoFactor := range twoFactors {
+		secret, err := encryptChannelKey(twoFactor.Secret)
+		if err != nil {
+			return err
+		}
+		err = db.Model(&TwoFactor{}).Where("user_id = ?", twoFactor.UserId).Update("secret", secret).Error
+		if err != nil {
+			return err
+		}
+	}
+	if len(twoFactors) != 0 {
+		logger.SysLog(fmt.Sprintf("encrypted the two-factor secrets of %d users", len(twoFactors)))
+	}
+	return nil
+}
@@ -1,6 +1,7 @@
 package model
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
 	"strings"
@@ -20,12 +21,15 @@ type User struct {
 	Username            string `json:"username" gorm:"unique;index" validate:"max=12"`
 	Password            string `json:"password" gorm:"not null;" validate:"min=8,max=20"`
 	DisplayName         string `json:"display_name" gorm:"index" validate:"max=20"`
-	Role                int    `json:"role" gorm:"type:int;default:1"`   // admin, util
-	Status              int    `json:"status" gorm:"type:int;default:1"` // enabled, disabled
+	Role                int    `json:"role" gorm:"type:int;default:1"`    // admin, util
+	RoleId              int    `json:"role_id" gorm:"type:int;default:0"` // role granting the permissions, 0 is the built-in role of the role level
+	Status              int    `json:"status" gorm:"type:int;default:1"`  // enabled, disabled
 	Email               string `json:"email" gorm:"index" validate:"max=50"`
 	GitHubId            string `json:"github_id" gorm:"column:github_id;index"`
 	GoogleId            string `json:"google_id" gorm:"column:google_id;index"`
 	WeChatId            string `json:"wechat_id" gorm:"column:wechat_id;index"`
+	OidcId              string `json:"oidc_id" gorm:"column:oidc_id;index"`
+	LdapId              string `json:"ldap_id" gorm:"column:ldap_id;index"`
 	VerificationCode    string `json:"verification_code" gorm:"-:all"`                                    // this field is only for Email verification, don't save it to database!
 	AccessToken         string `json:"access_token" gorm:"type:char(32);column:access_token;uniqueIndex"` // this token is for system management
 	Quota               int64  `json:"quota" gorm:"type:int;default:0"`
@@ -34,8 +38,10 @@ type User struct {
 	Group               string `json:"group" gorm:"type:varchar(32);default:'Lv1"`
 	AffCode             string `json:"aff_code" gorm:"type:varchar(32);column:aff_code;uniqueIndex"`
 	InviterId           int    `json:"inviter_id" gorm:"type:int;column:inviter_id;index"`
+	ParentId            int    `json:"parent_id" gorm:"type:int;default:0;index"` // reseller who created the user and allocates its quota
 	UserRemindThreshold int64  `json:"user_remind_threshold"`
 	UserLastNoticeTime  int64  `json:"user_last_notice_time" gorm:"default:0"`
+	RateLimits          `gorm:"embedded"`
 }
 
 func GetMaxUserId() int {
@@ -221,7 +227,11 @@ func (user *User) Update(updatePassword bool) error {
 		blacklist.UnbanUser(user.Id)
 	}
 	err = DB.Model(user).Updates(user).Error
-	return err
+	if err != nil {
+		return err
+	}
+	InvalidateUserPermissionsCache(user.Id)
+	return nil
 }
 
 func (user *User) Delete() error {
@@ -232,6 +242,11 @@ func (user *User) Delete() error {
 	user.Username = fmt.Sprintf("deleted_%s", helper.GetUUID())
 	user.Status = common.UserStatusDeleted
 	err := DB.Model(user).Updates(user).Error
+	if err != nil {
+		return err
+	}
+	InvalidateUserPermissionsCache(user.Id)
+	_, err = RevokeUserSessions(user.Id, 0)
 	return err
 }
 
@@ -291,6 +306,22 @@ func (user *User) FillUserByGoogleId() error {
 	DB.Where(User{GoogleId: user.GoogleId}).First(user)
 	return nil
 }
+func (user *User) FillUserByOidcId() error {
+	if user.OidcId == "" {
+		return errors.New("OIDC id 为空！")
+	}
+	DB.Where(User{OidcId: user.OidcId}).First(user)
+	return nil
+}
+
+func (user *User) FillUserByLdapId() error {
+	if user.LdapId == "" {
+		return errors.New("LDAP id 为空！")
+	}
+	DB.Where(User{LdapId: user.LdapId}).First(user)
+	return nil
+}
+
 func (user *User) FillUserByWeChatId() error {
 	if user.WeChatId == "" {
 		return errors.New("WeChat id 为空！")
@@ -327,6 +358,109 @@ func IsGoogleIdAlreadyTaken(GoogleId string) bool {
 	return DB.Where("google_id = ?", GoogleId).Find(&User{}).RowsAffected == 1
 }
 
+func IsOidcIdAlreadyTaken(oidcId string) bool {
+	return DB.Where("oidc_id = ?", oidcId).Find(&User{}).RowsAffected == 1
+}
+
+func IsLdapIdAlreadyTaken(ldapId string) bool {
+	return DB.Where("ldap_id = ?", ldapId).Find(&User{}).RowsAffected == 1
+}
+
+func parseExternalMapping(value string) (map[string]string, error) {
+	mapping := make(map[string]string)
+	if strings.TrimSpace(value) == "" {
+		return mapping, nil
+	}
+	err := json.Unmarshal([]byte(value), &mapping)
+	return mapping, err
+}
+
+// countRolePermissions returns how many permissions the role grants, to rank the roles mapped from external groups
+func countRolePermissions(role *Role) int {
+	granted := SplitPermissions(role.Permissions)
+	count := 0
+	for _, permission := range AllPermissions {
+		if HasPermission(granted, permission) {
+			count++
+		}
+	}
+	return count
+}
+
+// SyncExternalRoleAndGroup sets the role and the group of the user from the groups given by an identity provider,
+// the mappings are JSON objects from the group names to role names and user groups.
+// When several groups are mapped, the role granting the most permissions and the user group with the lowest ratio win,
+// ties are broken by name, so that the result does not depend on the order of the groups.
+// Without a matching group the role falls back to the one of a common user, the group is kept.
+// Root users are never changed and the root role cannot be granted this way.
+func (user *User) SyncExternalRoleAndGroup(groups []string, roleMappingJSON string, groupMappingJSON string) error {
+	roleMapping, err := parseExternalMapping(roleMappingJSON)
+	if err != nil {
+		return fmt.Errorf("invalid role mapping: %w", err)
+	}
+	groupMapping, err := parseExternalMapping(groupMappingJSON)
+	if err != nil {
+		return fmt.Errorf("invalid group mapping: %w", err)
+	}
+	if len(roleMapping) == 0 && len(groupMapping) == 0 {
+		return nil
+	}
+	if len(roleMapping) != 0 && user.Role < common.RoleRootUser {
+		var bestRole *Role
+		bestCount := 0
+		for _, group := range groups {
+			roleName, ok := roleMapping[group]
+			if !ok || roleName == RoleNameRoot {
+				continue
+			}
+			role, err := GetRoleByName(roleName)
+			if err != nil {
+				return fmt.Errorf("role %s mapped from %s: %w", roleName, group, err)
+			}
+			count := countRolePermissions(role)
+			if bestRole == nil || count > bestCount || (count == bestCount && role.Name < bestRole.Name) {
+				bestRole, bestCount = role, count
+			}
+		}
+		user.Role = common.RoleCommonUser
+		user.RoleId = 0
+		if bestRole != nil {
+			if bestRole.Name == RoleNameAdmin {
+				user.Role = common.RoleAdminUser
+			} else if !bestRole.Builtin {
+				user.RoleId = bestRole.Id
+			}
+		}
+	}
+	bestGroup := ""
+	for _, group := range groups {
+		userGroup, ok := groupMapping[group]
+		if !ok {
+			continue
+		}
+		if _, ok := common.GroupRatio[userGroup]; !ok {
+			return fmt.Errorf("group %s mapped from %s does not exist", userGroup, group)
+		}
+		if bestGroup == "" {
+			bestGroup = userGroup
+			continue
+		}
+		ratio, bestRatio := common.GetGroupRatio(userGroup), common.GetGroupRatio(bestGroup)
+		if ratio < bestRatio || (ratio == bestRatio && userGroup < bestGroup) {
+			bestGroup = userGroup
+		}
+	}
+	if bestGroup != "" {
+		user.Group = bestGroup
+	}
+	err = DB.Model(user).Select("role", "role_id", "group").Updates(user).Error
+	if err != nil {
+		return err
+	}
+	InvalidateUserPermissionsCache(user.Id)
+	return nil
+}
+
 func ResetUserPasswordByEmail(email string, password string) error {
 	if email == "" || password == "" {
 		return errors.New("邮箱地址或密码为空！")
@@ -335,8 +469,27 @@ func ResetUserPasswordByEmail(email string, password string) error {
 	if err != nil {
 		return err
 	}
-	err = DB.Model(&User{}).Where("email = ?", email).Update("password", hashedPassword).Error
-	return err
+	var userIds []int
+	err = DB.Model(&User{}).Where("email = ?", email).Pluck("id", &userIds).Error
+	if err != nil {
+		return err
+	}
+	for _, userId := range userIds {
+		err = DB.Model(&User{}).Where("id = ?", userId).Update("password", hashedPassword).Error
+		if err != nil {
+			return err
+		}
+		// a leaked access token must not survive the reset either
+		err = RevokeAccessToken(userId)
+		if err != nil {
+			return err
+		}
+		_, err = RevokeUserSessions(userId, 0)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 func IsAdmin(userId int) bool {
@@ -364,6 +517,11 @@ func IsUserEnabled(userId int) (bool, error) {
 	return user.Status == common.UserStatusEnabled, nil
 }
 
+// RevokeAccessToken replaces the system access token of the user with one that is never shown
+func RevokeAccessToken(userId int) error {
+	return DB.Model(&User{}).Where("id = ?", userId).Update("access_token", helper.GetUUID()).Error
+}
+
 func ValidateAccessToken(token string) (user *User) {
 	if token == "" {
 		return nil
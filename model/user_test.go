@@ -0,0 +1,30 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/songquanpeng/one-api/common"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSyncExternalRoleAndGroup(t *testing.T) {
+	setupTestDB(t, &User{}, &Role{})
+	require.NoError(t, createBuiltinRoles(DB))
+	groupRatio := common.GroupRatio
+	common.GroupRatio = map[string]float64{"default": 1, "vip": 0.8, "svip": 0.8}
+	t.Cleanup(func() {
+		common.GroupRatio = groupRatio
+	})
+	roleMapping := `{"staff":"user","ops":"admin"}`
+	groupMapping := `{"staff":"default","ops":"vip","sales":"svip"}`
+	for _, groups := range [][]string{{"staff", "ops", "sales"}, {"sales", "ops", "staff"}} {
+		user := &User{Username: "alice", Role: common.RoleCommonUser, Group: "default"}
+		require.NoError(t, DB.Create(user).Error)
+		require.NoError(t, user.SyncExternalRoleAndGroup(groups, roleMapping, groupMapping))
+		// the order of the groups does not matter
+		assert.Equal(t, common.RoleAdminUser, user.Role, "%v", groups)
+		assert.Equal(t, "svip", user.Group, "%v", groups)
+		require.NoError(t, DB.Unscoped().Delete(user).Error)
+	}
+}
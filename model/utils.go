@@ -13,6 +13,7 @@ const (
 	BatchUpdateTypeUsedQuota
 	BatchUpdateTypeChannelUsedQuota
 	BatchUpdateTypeRequestCount
+	BatchUpdateTypeOrganizationQuota
 	BatchUpdateTypeCount // if you add a new type, you need to add a new map and a new lock
 )
 
@@ -71,6 +72,11 @@ func batchUpdate() {
 				updateUserRequestCount(key, int(value))
 			case BatchUpdateTypeChannelUsedQuota:
 				updateChannelUsedQuota(key, value)
+			case BatchUpdateTypeOrganizationQuota:
+				err := increaseOrganizationQuota(key, value)
+				if err != nil {
+					logger.SysError("failed to batch update organization quota: " + err.Error())
+				}
 			}
 		}
 	}
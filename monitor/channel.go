@@ -53,3 +53,21 @@ func EnableChannel(channelId int, channelName string) {
 	content := fmt.Sprintf("渠道「%s」（#%d）已被启用", channelName, channelId)
 	notifyRootUser(subject, content)
 }
+
+// NotifyLowBalance notify the root user that the balance of the channel dropped below its threshold
+func NotifyLowBalance(channelId int, channelName string, balance float64, threshold float64, deprioritized bool) {
+	logger.SysLog(fmt.Sprintf("channel #%d balance %.2f is below the threshold %.2f", channelId, balance, threshold))
+	subject := fmt.Sprintf("渠道「%s」（#%d）余额不足", channelName, channelId)
+	content := fmt.Sprintf("渠道「%s」（#%d）当前余额为 %.2f，低于阈值 %.2f，请及时充值。", channelName, channelId, balance, threshold)
+	if deprioritized {
+		content += "该渠道已被降低优先级，仅在其他渠道均不可用时使用。"
+	}
+	notifyRootUser(subject, content)
+}
+
+func NotifyBalanceRecovered(channelId int, channelName string, balance float64) {
+	logger.SysLog(fmt.Sprintf("channel #%d balance %.2f is back above the threshold", channelId, balance))
+	subject := fmt.Sprintf("渠道「%s」（#%d）余额已恢复", channelName, channelId)
+	content := fmt.Sprintf("渠道「%s」（#%d）当前余额为 %.2f，已恢复至阈值以上。", channelName, channelId, balance)
+	notifyRootUser(subject, content)
+}
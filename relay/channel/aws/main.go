@@ -22,16 +22,25 @@ import (
 	"github.com/songquanpeng/one-api/common/logger"
 	"github.com/songquanpeng/one-api/relay/channel/anthropic"
 	relaymodel "github.com/songquanpeng/one-api/relay/model"
+	"github.com/songquanpeng/one-api/relay/util"
 )
 
 func newAwsClient(c *gin.Context) (*bedrockruntime.Client, error) {
 	ak := c.GetString(ctxkey.ConfigAK)
 	sk := c.GetString(ctxkey.ConfigSK)
 	region := c.GetString(ctxkey.ConfigRegion)
-	client := bedrockruntime.New(bedrockruntime.Options{
+	options := bedrockruntime.Options{
 		Region:      region,
 		Credentials: aws.NewCredentialsCache(credentials.NewStaticCredentialsProvider(ak, sk, "")),
-	})
+	}
+	if transportOptions := util.GetTransportOptions(c); transportOptions != (util.TransportOptions{}) {
+		httpClient, err := util.GetChannelHTTPClient(transportOptions)
+		if err != nil {
+			return nil, err
+		}
+		options.HTTPClient = httpClient
+	}
+	client := bedrockruntime.New(options)
 
 	return client, nil
 }
@@ -64,7 +64,7 @@ func (a *Adaptor) GetRequestURL(meta *util.RelayMeta) (string, error) {
 	fullRequestURL := fmt.Sprintf("%s/rpc/2.0/ai_custom/v1/wenxinworkshop/%s", meta.BaseURL, suffix)
 	var accessToken string
 	var err error
-	if accessToken, err = GetAccessToken(meta.APIKey); err != nil {
+	if accessToken, err = GetAccessToken(meta.APIKey, meta.Transport); err != nil {
 		return "", err
 	}
 	fullRequestURL += "?access_token=" + accessToken
@@ -270,20 +270,21 @@ func EmbeddingHandler(c *gin.Context, resp *http.Response) (*model.ErrorWithStat
 	return nil, &fullTextResponse.Usage
 }
 
-func GetAccessToken(apiKey string) (string, error) {
+// GetAccessToken fetches the token through the proxy and TLS settings of the channel, like the relay requests
+func GetAccessToken(apiKey string, transport util.TransportOptions) (string, error) {
 	if val, ok := baiduTokenStore.Load(apiKey); ok {
 		var accessToken AccessToken
 		if accessToken, ok = val.(AccessToken); ok {
 			// soon this will expire
 			if time.Now().Add(time.Hour).After(accessToken.ExpiresAt) {
 				go func() {
-					_, _ = getBaiduAccessTokenHelper(apiKey)
+					_, _ = getBaiduAccessTokenHelper(apiKey, transport)
 				}()
 			}
 			return accessToken.AccessToken, nil
 		}
 	}
-	accessToken, err := getBaiduAccessTokenHelper(apiKey)
+	accessToken, err := getBaiduAccessTokenHelper(apiKey, transport)
 	if err != nil {
 		return "", err
 	}
@@ -293,7 +294,7 @@ func GetAccessToken(apiKey string) (string, error) {
 	return (*accessToken).AccessToken, nil
 }
 
-func getBaiduAccessTokenHelper(apiKey string) (*AccessToken, error) {
+func getBaiduAccessTokenHelper(apiKey string, transport util.TransportOptions) (*AccessToken, error) {
 	parts := strings.Split(apiKey, "|")
 	if len(parts) != 2 {
 		return nil, errors.New("invalid baidu apikey")
@@ -305,7 +306,11 @@ func getBaiduAccessTokenHelper(apiKey string) (*AccessToken, error) {
 	}
 	req.Header.Add("Content-Type", "application/json")
 	req.Header.Add("Accept", "application/json")
-	res, err := util.ImpatientHTTPClient.Do(req)
+	client, err := util.GetImpatientChannelHTTPClient(transport)
+	if err != nil {
+		return nil, err
+	}
+	res, err := client.Do(req)
 	if err != nil {
 		return nil, err
 	}
@@ -11,6 +11,8 @@ import (
 	"github.com/songquanpeng/one-api/relay/util"
 	"io"
 	"net/http"
+	"net/url"
+	"strings"
 )
 
 type Adaptor struct {
@@ -64,3 +66,37 @@ func (a *Adaptor) GetModelList() []string {
 func (a *Adaptor) GetChannelName() string {
 	return "google gemini"
 }
+
+func (a *Adaptor) FetchModelList(meta *util.RelayMeta) ([]string, error) {
+	// https://ai.google.dev/api/rest/v1/models/list
+	version := helper.AssignOrDefault(meta.APIVersion, "v1")
+	headers := map[string]string{"x-goog-api-key": meta.APIKey}
+	models := make([]string, 0)
+	pageToken := ""
+	for {
+		var response ModelListResponse
+		query := url.Values{}
+		query.Set("pageSize", "1000")
+		if pageToken != "" {
+			query.Set("pageToken", pageToken)
+		}
+		requestURL := fmt.Sprintf("%s/%s/models?%s", meta.BaseURL, version, query.Encode())
+		err := channelhelper.GetJSON(meta, requestURL, headers, &response)
+		if err != nil {
+			return nil, err
+		}
+		for _, model_ := range response.Models {
+			for _, method := range model_.SupportedGenerationMethods {
+				if method == "generateContent" {
+					models = append(models, strings.TrimPrefix(model_.Name, "models/"))
+					break
+				}
+			}
+		}
+		if response.NextPageToken == "" {
+			break
+		}
+		pageToken = response.NextPageToken
+	}
+	return models, nil
+}
@@ -18,3 +18,8 @@ type Adaptor interface {
 	GetModelList() []string
 	GetChannelName() string
 }
+
+// ModelFetcher is implemented by adaptors able to list the models served by the upstream
+type ModelFetcher interface {
+	FetchModelList(meta *util.RelayMeta) ([]string, error)
+}
@@ -192,7 +192,11 @@ func DoMidjourneyHttpRequest(c *gin.Context, timeout time.Duration, fullRequestU
 		req.Header.Set("mj-api-secret", auth)
 	}
 	defer cancel()
-	resp, err := util.GetHttpClient().Do(req)
+	client, err := util.GetChannelHTTPClient(util.GetTransportOptions(c))
+	if err != nil {
+		return MidjourneyErrorWithStatusCodeWrapper(common.MjErrorUnknown, "get_http_client_failed", http.StatusInternalServerError), nullBytes, err
+	}
+	resp, err := client.Do(req)
 	if err != nil {
 		logger.SysError("do request failed: " + err.Error())
 		return MidjourneyErrorWithStatusCodeWrapper(common.MjErrorUnknown, "do_request_failed", http.StatusInternalServerError), nullBytes, err
@@ -73,3 +73,18 @@ func (a *Adaptor) GetModelList() []string {
 func (a *Adaptor) GetChannelName() string {
 	return "ollama"
 }
+
+func (a *Adaptor) FetchModelList(meta *util.RelayMeta) ([]string, error) {
+	// https://github.com/ollama/ollama/blob/main/docs/api.md#list-local-models
+	var response ModelListResponse
+	headers := map[string]string{"Authorization": "Bearer " + meta.APIKey}
+	err := channel.GetJSON(meta, fmt.Sprintf("%s/api/tags", meta.BaseURL), headers, &response)
+	if err != nil {
+		return nil, err
+	}
+	models := make([]string, 0, len(response.Models))
+	for _, model_ := range response.Models {
+		models = append(models, model_.Name)
+	}
+	return models, nil
+}
@@ -45,3 +45,9 @@ type EmbeddingResponse struct {
 	Error     string    `json:"error,omitempty"`
 	Embedding []float64 `json:"embedding,omitempty"`
 }
+
+type ModelListResponse struct {
+	Models []struct {
+		Name string `json:"name"`
+	} `json:"models"`
+}
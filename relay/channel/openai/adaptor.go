@@ -89,3 +89,21 @@ func (a *Adaptor) GetChannelName() string {
 	channelName, _ := GetCompatibleChannelMeta(a.ChannelType)
 	return channelName
 }
+
+func (a *Adaptor) FetchModelList(meta *util.RelayMeta) ([]string, error) {
+	if meta.ChannelType == common.ChannelTypeAzure {
+		// Azure serves deployments instead of models, they can't be discovered with the API key
+		return nil, errors.New("model discovery is not supported for Azure")
+	}
+	var response ModelListResponse
+	headers := map[string]string{"Authorization": "Bearer " + meta.APIKey}
+	err := channel.GetJSON(meta, util.GetFullRequestURL(meta.BaseURL, "/v1/models", meta.ChannelType), headers, &response)
+	if err != nil {
+		return nil, err
+	}
+	models := make([]string, 0, len(response.Data))
+	for _, model_ := range response.Data {
+		models = append(models, model_.Id)
+	}
+	return models, nil
+}
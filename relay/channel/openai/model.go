@@ -138,3 +138,11 @@ type CompletionsStreamResponse struct {
 		FinishReason string `json:"finish_reason"`
 	} `json:"choices"`
 }
+
+type ModelListResponse struct {
+	Object string `json:"object"`
+	Data   []struct {
+		Id      string `json:"id"`
+		OwnedBy string `json:"owned_by"`
+	} `json:"data"`
+}
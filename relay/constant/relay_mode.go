@@ -1,6 +1,9 @@
 package constant
 
-import "strings"
+import (
+	"sort"
+	"strings"
+)
 
 const (
 	RelayModeUnknown = iota
@@ -89,3 +92,43 @@ func Path2RelayModeMidjourney(path string) int {
 	}
 	return relayMode
 }
+
+// relayModeNames name the relay modes for the endpoint scopes of tokens
+var relayModeNames = map[int]string{
+	RelayModeChatCompletions:                "chat.completions",
+	RelayModeCompletions:                    "completions",
+	RelayModeEmbeddings:                     "embeddings",
+	RelayModeModerations:                    "moderations",
+	RelayModeImagesGenerations:              "images.generations",
+	RelayModeEdits:                          "edits",
+	RelayModeAudioSpeech:                    "audio.speech",
+	RelayModeAudioTranscription:             "audio.transcriptions",
+	RelayModeAudioTranslation:               "audio.translations",
+	RelayModeMidjourneyImagine:              "mj.imagine",
+	RelayModeMidjourneyDescribe:             "mj.describe",
+	RelayModeMidjourneyBlend:                "mj.blend",
+	RelayModeMidjourneyChange:               "mj.change",
+	RelayModeMidjourneySimpleChange:         "mj.simple-change",
+	RelayModeMidjourneyNotify:               "mj.notify",
+	RelayModeMidjourneyTaskFetch:            "mj.fetch",
+	RelayModeMidjourneyTaskImageSeed:        "mj.image-seed",
+	RelayModeMidjourneyTaskFetchByCondition: "mj.list-by-condition",
+	RelayModeMidjourneyAction:               "mj.action",
+	RelayModeMidjourneyModal:                "mj.modal",
+	RelayModeMidjourneyShorten:              "mj.shorten",
+	RelayModeSwapFace:                       "mj.swap-face",
+}
+
+// RelayModeName returns the name of the relay mode, empty for RelayModeUnknown
+func RelayModeName(relayMode int) string {
+	return relayModeNames[relayMode]
+}
+
+func RelayModeNames() []string {
+	names := make([]string, 0, len(relayModeNames))
+	for _, name := range relayModeNames {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
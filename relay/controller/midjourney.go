@@ -120,6 +120,7 @@ func RelaySwapFace(c *gin.Context) *midjourney.MidjourneyResponseWithStatusCode
 	startTime := time.Now().UnixNano() / int64(time.Millisecond)
 	tokenId := c.GetInt("token_id")
 	userId := c.GetInt("id")
+	organizationId := c.GetInt("organization_id")
 	consumeQuota := true
 	group := c.GetString("group")
 	channelId := c.GetInt("channel_id")
@@ -144,7 +145,7 @@ func RelaySwapFace(c *gin.Context) *midjourney.MidjourneyResponseWithStatusCode
 	}
 	groupRatio := common.GetGroupRatio(group)
 	ratio := modelPrice * groupRatio
-	userQuota, err := model.CacheGetUserQuota(ctx, userId)
+	userQuota, err := model.CacheGetBillingQuota(ctx, userId, organizationId)
 	if err != nil {
 		return &midjourney.MidjourneyResponseWithStatusCode{
 			StatusCode: http.StatusBadRequest,
@@ -189,7 +190,7 @@ func RelaySwapFace(c *gin.Context) *midjourney.MidjourneyResponseWithStatusCode
 			if quota != 0 {
 				tokenName := c.GetString("token_name")
 				logContent := fmt.Sprintf("模型固定价格 %.2f，分组倍率 %.2f，操作 %s", modelPrice, groupRatio, common.MjActionSwapFace)
-				model.RecordConsumeLog(ctx, userId, channelId, 0, 0, modelName, tokenName, quota, logContent, 0)
+				model.RecordConsumeLog(ctx, userId, organizationId, channelId, 0, 0, modelName, tokenName, quota, logContent, 0)
 				model.UpdateUserUsedQuotaAndRequestCount(userId, quota)
 				channelId := c.GetInt("channel_id")
 				model.UpdateChannelUsedQuota(channelId, quota)
@@ -198,23 +199,24 @@ func RelaySwapFace(c *gin.Context) *midjourney.MidjourneyResponseWithStatusCode
 	}(c.Request.Context())
 	midjResponse := &mjResp.Response
 	midjourneyTask := &model.Midjourney{
-		UserId:      userId,
-		Code:        midjResponse.Code,
-		Action:      common.MjActionSwapFace,
-		MjId:        midjResponse.Result,
-		Prompt:      "InsightFace",
-		PromptEn:    "",
-		Description: midjResponse.Description,
-		State:       "",
-		SubmitTime:  startTime,
-		StartTime:   time.Now().UnixNano() / int64(time.Millisecond),
-		FinishTime:  0,
-		ImageUrl:    "",
-		Status:      "",
-		Progress:    "0%",
-		FailReason:  "",
-		ChannelId:   c.GetInt("channel_id"),
-		Quota:       quota,
+		UserId:         userId,
+		OrganizationId: organizationId,
+		Code:           midjResponse.Code,
+		Action:         common.MjActionSwapFace,
+		MjId:           midjResponse.Result,
+		Prompt:         "InsightFace",
+		PromptEn:       "",
+		Description:    midjResponse.Description,
+		State:          "",
+		SubmitTime:     startTime,
+		StartTime:      time.Now().UnixNano() / int64(time.Millisecond),
+		FinishTime:     0,
+		ImageUrl:       "",
+		Status:         "",
+		Progress:       "0%",
+		FailReason:     "",
+		ChannelId:      c.GetInt("channel_id"),
+		Quota:          quota,
 	}
 
 	if mjResp.Response.Code != 1 && mjResp.Response.Code != 21 && mjResp.Response.Code != 22 {
@@ -254,7 +256,11 @@ func RelayMidjourneyTaskImageSeed(c *gin.Context) *midjourney.MidjourneyResponse
 		return midjourney.MidjourneyErrorWithStatusCodeWrapper(common.MjRequestError, "该任务所属渠道已被禁用", http.StatusInternalServerError)
 	}
 	c.Set("channel_id", originTask.ChannelId)
-	c.Request.Header.Set("Authorization", fmt.Sprintf("Bearer %s", channel.Key))
+	key, err := channel.DecryptKey()
+	if err != nil {
+		return midjourney.MidjourneyErrorWithStatusCodeWrapper(common.MjRequestError, "get_channel_info_failed", http.StatusInternalServerError)
+	}
+	c.Request.Header.Set("Authorization", fmt.Sprintf("Bearer %s", key))
 
 	requestURL := c.Request.URL.String()
 	fullRequestURL := fmt.Sprintf("%s%s", channel.GetBaseURL(), requestURL)
@@ -370,6 +376,7 @@ func RelayMidjourneySubmit(c *gin.Context, relayMode int) *midjourney.Midjourney
 	tokenId := c.GetInt("token_id")
 	//channelType := c.GetInt("channel")
 	userId := c.GetInt("id")
+	organizationId := c.GetInt("organization_id")
 	group := c.GetString("group")
 	channelId := c.GetInt("channel_id")
 	consumeQuota := true
@@ -443,7 +450,11 @@ func RelayMidjourneySubmit(c *gin.Context, relayMode int) *midjourney.Midjourney
 			}
 			c.Set("base_url", channel.GetBaseURL())
 			c.Set("channel_id", originTask.ChannelId)
-			c.Request.Header.Set("Authorization", fmt.Sprintf("Bearer %s", channel.Key))
+			key, err := channel.DecryptKey()
+			if err != nil {
+				return midjourney.MidjourneyErrorWithStatusCodeWrapper(common.MjRequestError, "get_channel_info_failed", http.StatusBadRequest)
+			}
+			c.Request.Header.Set("Authorization", fmt.Sprintf("Bearer %s", key))
 			log.Printf("检测到此操作为放大、变换、重绘，获取原channel信息: %s,%s", strconv.Itoa(originTask.ChannelId), channel.GetBaseURL())
 		}
 		midjRequest.Prompt = originTask.Prompt
@@ -501,7 +512,7 @@ func RelayMidjourneySubmit(c *gin.Context, relayMode int) *midjourney.Midjourney
 	ctx := c.Request.Context()
 	groupRatio := common.GetGroupRatio(group)
 	ratio := modelPrice * groupRatio
-	userQuota, err := model.CacheGetUserQuota(ctx, userId)
+	userQuota, err := model.CacheGetBillingQuota(ctx, userId, organizationId)
 	if err != nil {
 		return &midjourney.MidjourneyResponseWithStatusCode{
 			StatusCode: http.StatusBadRequest,
@@ -546,7 +557,7 @@ func RelayMidjourneySubmit(c *gin.Context, relayMode int) *midjourney.Midjourney
 			if quota != 0 {
 				tokenName := c.GetString("token_name")
 				logContent := fmt.Sprintf("模型固定价格 %.2f，分组倍率 %.2f，操作 %s", modelPrice, groupRatio, midjRequest.Action)
-				model.RecordConsumeLog(ctx, userId, channelId, 0, 0, modelName, tokenName, quota, logContent, 0)
+				model.RecordConsumeLog(ctx, userId, organizationId, channelId, 0, 0, modelName, tokenName, quota, logContent, 0)
 				model.UpdateUserUsedQuotaAndRequestCount(userId, quota)
 				channelId := c.GetInt("channel_id")
 				model.UpdateChannelUsedQuota(channelId, quota)
@@ -562,25 +573,26 @@ func RelayMidjourneySubmit(c *gin.Context, relayMode int) *midjourney.Midjourney
 	// 24-prompt包含敏感词 {"code":24,"description":"可能包含敏感词","properties":{"promptEn":"nude body","bannedWord":"nude"}}
 	// other: 提交错误，description为错误描述
 	midjourneyTask := &model.Midjourney{
-		UserId:      userId,
-		Code:        midjResponse.Code,
-		Action:      midjRequest.Action,
-		MjId:        midjResponse.Result,
-		Prompt:      midjRequest.Prompt,
-		PromptEn:    "",
-		Description: midjResponse.Description,
-		State:       "",
-		SubmitTime:  time.Now().UnixNano() / int64(time.Millisecond),
-		StartTime:   0,
-		FinishTime:  0,
-		ImageUrl:    "",
-		Status:      "",
-		Progress:    "0%",
-		FailReason:  "",
-		ChannelId:   c.GetInt("channel_id"),
-		Quota:       quota,
-		Type:        MidjourneyType,
-		Username:    username,
+		UserId:         userId,
+		OrganizationId: organizationId,
+		Code:           midjResponse.Code,
+		Action:         midjRequest.Action,
+		MjId:           midjResponse.Result,
+		Prompt:         midjRequest.Prompt,
+		PromptEn:       "",
+		Description:    midjResponse.Description,
+		State:          "",
+		SubmitTime:     time.Now().UnixNano() / int64(time.Millisecond),
+		StartTime:      0,
+		FinishTime:     0,
+		ImageUrl:       "",
+		Status:         "",
+		Progress:       "0%",
+		FailReason:     "",
+		ChannelId:      c.GetInt("channel_id"),
+		Quota:          quota,
+		Type:           MidjourneyType,
+		Username:       username,
 	}
 
 	if midjResponse.Code != 1 && midjResponse.Code != 21 && midjResponse.Code != 22 {
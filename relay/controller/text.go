@@ -106,6 +106,10 @@ func RelayTextHelper(c *gin.Context) *model.ErrorWithStatusCode {
 		return respErr
 	}
 
+	if usage != nil {
+		// counted by the tokens per minute limit
+		c.Set("relay_total_tokens", usage.PromptTokens+usage.CompletionTokens)
+	}
 	rowDuration := time.Since(startTime).Seconds() // 计算总耗时
 	duration := math.Round(rowDuration*1000) / 1000
 	// post-consume quota
@@ -14,6 +14,7 @@ type RelayMeta struct {
 	TokenId         int
 	TokenName       string
 	UserId          int
+	OrganizationId  int
 	Group           string
 	ModelMapping    map[string]string
 	BaseURL         string
@@ -26,6 +27,7 @@ type RelayMeta struct {
 	ActualModelName string
 	RequestURLPath  string
 	PromptTokens    int // only for DoResponse
+	Transport       TransportOptions
 }
 
 func GetRelayMeta(c *gin.Context) *RelayMeta {
@@ -36,6 +38,7 @@ func GetRelayMeta(c *gin.Context) *RelayMeta {
 		TokenId:        c.GetInt("token_id"),
 		TokenName:      c.GetString("token_name"),
 		UserId:         c.GetInt("id"),
+		OrganizationId: c.GetInt("organization_id"),
 		Group:          c.GetString("group"),
 		ModelMapping:   c.GetStringMapString("model_mapping"),
 		BaseURL:        c.GetString("base_url"),
@@ -43,6 +46,7 @@ func GetRelayMeta(c *gin.Context) *RelayMeta {
 		APIKey:         strings.TrimPrefix(c.Request.Header.Get("Authorization"), "Bearer "),
 		Config:         nil,
 		RequestURLPath: c.Request.URL.String(),
+		Transport:      GetTransportOptions(c),
 	}
 	if meta.ChannelType == common.ChannelTypeAzure {
 		meta.APIVersion = GetAzureAPIVersion(c)
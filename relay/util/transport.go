@@ -0,0 +1,156 @@
+package util
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"github.com/songquanpeng/one-api/common/config"
+	"github.com/songquanpeng/one-api/common/ctxkey"
+	"github.com/songquanpeng/one-api/model"
+)
+
+// TransportOptions are the per channel settings of the connection to the upstream
+type TransportOptions struct {
+	Proxy         string
+	CACert        string // PEM encoded CA bundle trusted in addition to the system roots
+	ClientCert    string // PEM encoded client certificate for mutual TLS
+	ClientKey     string
+	TLSServerName string
+}
+
+// NewTransportOptions reads the transport options from the config of a channel
+func NewTransportOptions(cfg map[string]string) TransportOptions {
+	return TransportOptions{
+		Proxy:         cfg[model.ChannelConfigProxy],
+		CACert:        cfg[model.ChannelConfigTLSCACert],
+		ClientCert:    cfg[model.ChannelConfigTLSClientCert],
+		ClientKey:     cfg[model.ChannelConfigTLSClientKey],
+		TLSServerName: cfg[model.ChannelConfigTLSServerName],
+	}
+}
+
+// GetTransportOptions reads the transport options of the channel selected for the request
+func GetTransportOptions(c *gin.Context) TransportOptions {
+	return TransportOptions{
+		Proxy:         c.GetString(ctxkey.ConfigProxy),
+		CACert:        c.GetString(ctxkey.ConfigTLSCACert),
+		ClientCert:    c.GetString(ctxkey.ConfigTLSClientCert),
+		ClientKey:     c.GetString(ctxkey.ConfigTLSClientKey),
+		TLSServerName: c.GetString(ctxkey.ConfigTLSServerName),
+	}
+}
+
+func (options TransportOptions) isDefault() bool {
+	return options == TransportOptions{}
+}
+
+var channelHTTPClients = make(map[TransportOptions]*http.Client)
+var channelHTTPClientsLock sync.Mutex
+
+// ParseProxyURL checks the proxy of a channel, http, https, socks5 and socks5h proxies are supported
+func ParseProxyURL(proxy string) (*url.URL, error) {
+	proxyURL, err := url.Parse(proxy)
+	if err != nil {
+		return nil, fmt.Errorf("invalid proxy: %w", err)
+	}
+	switch proxyURL.Scheme {
+	case "http", "https", "socks5", "socks5h":
+	default:
+		return nil, fmt.Errorf("unsupported proxy scheme: %q", proxyURL.Scheme)
+	}
+	if proxyURL.Host == "" {
+		return nil, fmt.Errorf("proxy %q has no host", proxy)
+	}
+	return proxyURL, nil
+}
+
+// NewTransport builds the transport described by the options, it is also used to validate them
+func NewTransport(options TransportOptions) (*http.Transport, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if options.Proxy != "" {
+		proxyURL, err := ParseProxyURL(options.Proxy)
+		if err != nil {
+			return nil, err
+		}
+		transport.Proxy = http.ProxyURL(proxyURL)
+	}
+	if options.CACert == "" && options.ClientCert == "" && options.ClientKey == "" && options.TLSServerName == "" {
+		return transport, nil
+	}
+	tlsConfig := &tls.Config{ServerName: options.TLSServerName}
+	if options.CACert != "" {
+		rootCAs, err := x509.SystemCertPool()
+		if err != nil {
+			rootCAs = x509.NewCertPool()
+		}
+		if !rootCAs.AppendCertsFromPEM([]byte(options.CACert)) {
+			return nil, errors.New("no valid certificate found in the CA bundle")
+		}
+		tlsConfig.RootCAs = rootCAs
+	}
+	if options.ClientCert != "" || options.ClientKey != "" {
+		certificate, err := tls.X509KeyPair([]byte(options.ClientCert), []byte(options.ClientKey))
+		if err != nil {
+			return nil, fmt.Errorf("invalid client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{certificate}
+	}
+	transport.TLSClientConfig = tlsConfig
+	return transport, nil
+}
+
+// GetChannelHTTPClient returns the client for the transport options, or HTTPClient when they are all empty.
+// Clients are cached per options so that connections to the upstream are reused.
+func GetChannelHTTPClient(options TransportOptions) (*http.Client, error) {
+	if options.isDefault() {
+		return HTTPClient, nil
+	}
+	channelHTTPClientsLock.Lock()
+	defer channelHTTPClientsLock.Unlock()
+	if client, ok := channelHTTPClients[options]; ok {
+		return client, nil
+	}
+	transport, err := NewTransport(options)
+	if err != nil {
+		return nil, err
+	}
+	client := &http.Client{Transport: transport}
+	if config.RelayTimeout != 0 {
+		client.Timeout = time.Duration(config.RelayTimeout) * time.Second
+	}
+	channelHTTPClients[options] = client
+	return client, nil
+}
+
+// GetImpatientChannelHTTPClient is like ImpatientHTTPClient but goes through the proxy and TLS settings of the channel
+func GetImpatientChannelHTTPClient(options TransportOptions) (*http.Client, error) {
+	if options.isDefault() {
+		return ImpatientHTTPClient, nil
+	}
+	client, err := GetChannelHTTPClient(options)
+	if err != nil {
+		return nil, err
+	}
+	return &http.Client{Transport: client.Transport, Timeout: ImpatientHTTPClient.Timeout}, nil
+}
+
+// NewWebsocketDialer builds a websocket dialer going through the proxy and TLS settings of the options
+func NewWebsocketDialer(options TransportOptions, handshakeTimeout time.Duration) (*websocket.Dialer, error) {
+	transport, err := NewTransport(options)
+	if err != nil {
+		return nil, err
+	}
+	return &websocket.Dialer{
+		Proxy:            transport.Proxy,
+		TLSClientConfig:  transport.TLSClientConfig,
+		HandshakeTimeout: handshakeTimeout,
+	}, nil
+}
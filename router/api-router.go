@@ -3,6 +3,7 @@ package router
 import (
 	"github.com/songquanpeng/one-api/controller"
 	"github.com/songquanpeng/one-api/middleware"
+	"github.com/songquanpeng/one-api/model"
 
 	"github.com/gin-contrib/gzip"
 	"github.com/gin-gonic/gin"
@@ -13,6 +14,7 @@ func SetApiRouter(router *gin.Engine) {
 	apiRouter := router.Group("/api")
 	apiRouter.Use(gzip.Gzip(gzip.DefaultCompression))
 	apiRouter.Use(middleware.GlobalAPIRateLimit())
+	apiRouter.Use(middleware.AuditLog())
 	{
 		apiRouter.GET("/status", controller.GetStatus)
 		apiRouter.GET("/models", middleware.UserAuth(), controller.DashboardListModels)
@@ -27,6 +29,8 @@ func SetApiRouter(router *gin.Engine) {
 		apiRouter.GET("/oauth/github/callback", middleware.CriticalRateLimit(), controller.GithubOAuthCallback)
 		apiRouter.GET("/oauth/google", middleware.CriticalRateLimit(), controller.GoogleOAuth)
 		apiRouter.GET("/oauth/google/callback", middleware.CriticalRateLimit(), controller.GoogleOAuthCallback)
+		apiRouter.GET("/oauth/oidc", middleware.CriticalRateLimit(), controller.OidcOAuth)
+		apiRouter.GET("/oauth/oidc/callback", middleware.CriticalRateLimit(), controller.OidcOAuthCallback)
 		apiRouter.GET("/oauth/wechat", middleware.CriticalRateLimit(), controller.WeChatAuth)
 		apiRouter.GET("/oauth/wechat/bind", middleware.CriticalRateLimit(), middleware.UserAuth(), controller.WeChatBind)
 		apiRouter.GET("/oauth/email/bind", middleware.CriticalRateLimit(), middleware.UserAuth(), controller.EmailBind)
@@ -35,6 +39,9 @@ func SetApiRouter(router *gin.Engine) {
 		{
 			userRoute.POST("/register", middleware.CriticalRateLimit(), middleware.TurnstileCheck(), controller.Register)
 			userRoute.POST("/login", middleware.CriticalRateLimit(), controller.Login)
+			userRoute.POST("/login/2fa", middleware.CriticalRateLimit(), controller.TwoFactorLogin)
+			userRoute.GET("/login/2fa/setup", middleware.CriticalRateLimit(), controller.TwoFactorLoginSetup)
+			userRoute.POST("/login/ldap", middleware.CriticalRateLimit(), controller.LdapLogin)
 			userRoute.GET("/logout", controller.Logout)
 
 			selfRoute := userRoute.Group("/")
@@ -46,43 +53,72 @@ func SetApiRouter(router *gin.Engine) {
 				selfRoute.GET("/token", controller.GenerateAccessToken)
 				selfRoute.GET("/aff", controller.GetAffCode)
 				selfRoute.POST("/topup", controller.TopUp)
+				selfRoute.GET("/sub", controller.GetSubUsers)
+				selfRoute.POST("/sub", controller.CreateSubUser)
+				selfRoute.PUT("/sub", controller.UpdateSubUser)
+				selfRoute.POST("/sub/:id/quota", controller.TransferSubUserQuota)
+				selfRoute.GET("/sub/log", controller.GetSubUserLogs)
+				selfRoute.GET("/2fa", controller.GetTwoFactorStatus)
+				selfRoute.POST("/2fa/setup", controller.SetupTwoFactor)
+				selfRoute.POST("/2fa/enable", middleware.CriticalRateLimit(), controller.EnableTwoFactor)
+				selfRoute.POST("/2fa/disable", middleware.CriticalRateLimit(), controller.DisableTwoFactor)
+				selfRoute.POST("/2fa/recovery", middleware.CriticalRateLimit(), controller.RegenerateRecoveryCodes)
+				selfRoute.GET("/session", controller.GetSelfSessions)
+				selfRoute.DELETE("/session", controller.RevokeOtherSelfSessions)
+				selfRoute.DELETE("/session/:id", controller.RevokeSelfSession)
 			}
 
 			adminRoute := userRoute.Group("/")
-			adminRoute.Use(middleware.AdminAuth())
 			{
-				adminRoute.GET("/", controller.GetAllUsers)
-				adminRoute.GET("/search", controller.SearchUsers)
-				adminRoute.GET("/:id", controller.GetUser)
-				adminRoute.POST("/", controller.CreateUser)
-				adminRoute.POST("/manage", controller.ManageUser)
-				adminRoute.PUT("/", controller.UpdateUser)
-				adminRoute.POST("/batchdelete", controller.BatchDelteUser)
-				adminRoute.DELETE("/:id", controller.DeleteUser)
+				adminRoute.GET("/", middleware.PermissionAuth(model.PermissionUserRead), controller.GetAllUsers)
+				adminRoute.GET("/search", middleware.PermissionAuth(model.PermissionUserRead), controller.SearchUsers)
+				adminRoute.GET("/:id", middleware.PermissionAuth(model.PermissionUserRead), controller.GetUser)
+				adminRoute.POST("/", middleware.PermissionAuth(model.PermissionUserManage), controller.CreateUser)
+				adminRoute.POST("/manage", middleware.PermissionAuth(model.PermissionUserManage), controller.ManageUser)
+				adminRoute.PUT("/", middleware.PermissionAuth(model.PermissionUserManage), controller.UpdateUser)
+				adminRoute.POST("/batchdelete", middleware.PermissionAuth(model.PermissionUserManage), controller.BatchDelteUser)
+				adminRoute.DELETE("/:id", middleware.PermissionAuth(model.PermissionUserManage), controller.DeleteUser)
+				adminRoute.DELETE("/:id/2fa", middleware.PermissionAuth(model.PermissionUserManage), controller.ResetUserTwoFactor)
+				adminRoute.GET("/:id/session", middleware.PermissionAuth(model.PermissionUserManage), controller.GetUserSessions)
+				adminRoute.DELETE("/:id/session", middleware.PermissionAuth(model.PermissionUserManage), controller.RevokeAllUserSessions)
+				adminRoute.DELETE("/:id/session/:session_id", middleware.PermissionAuth(model.PermissionUserManage), controller.RevokeUserSession)
+				adminRoute.DELETE("/:id/access_token", middleware.PermissionAuth(model.PermissionUserManage), controller.RevokeUserAccessToken)
 			}
 		}
 		optionRoute := apiRouter.Group("/option")
-		optionRoute.Use(middleware.RootAuth())
 		{
-			optionRoute.GET("/", controller.GetOptions)
-			optionRoute.PUT("/", controller.UpdateOption)
+			optionRoute.GET("/", middleware.PermissionAuth(model.PermissionOptionRead), controller.GetOptions)
+			optionRoute.PUT("/", middleware.PermissionAuth(model.PermissionOptionWrite), controller.UpdateOption)
+			optionRoute.GET("/managed", middleware.PermissionAuth(model.PermissionOptionRead), controller.GetManagedConfigStatus)
+			optionRoute.GET("/managed/drift", middleware.PermissionAuth(model.PermissionOptionRead), controller.GetManagedConfigDrift)
+			optionRoute.POST("/managed/reconcile", middleware.PermissionAuth(model.PermissionOptionWrite), controller.ReconcileManagedConfig)
 		}
 		channelRoute := apiRouter.Group("/channel")
-		channelRoute.Use(middleware.AdminAuth())
 		{
-			channelRoute.GET("/", controller.GetAllChannels)
-			channelRoute.GET("/search", controller.SearchChannels)
-			channelRoute.GET("/models", controller.ListModels)
-			channelRoute.GET("/:id", controller.GetChannel)
-			channelRoute.GET("/test", controller.TestChannels)
-			channelRoute.GET("/test/:id", controller.TestChannel)
-			channelRoute.GET("/update_balance", controller.UpdateAllChannelsBalance)
-			channelRoute.GET("/update_balance/:id", controller.UpdateChannelBalance)
-			channelRoute.POST("/", controller.AddChannel)
-			channelRoute.PUT("/", controller.UpdateChannel)
-			channelRoute.POST("/batchdelete", controller.BatchDelteChannel)
-			channelRoute.DELETE("/disabled", controller.DeleteDisabledChannel)
-			channelRoute.DELETE("/:id", controller.DeleteChannel)
+			channelRoute.GET("/", middleware.PermissionAuth(model.PermissionChannelRead), controller.GetAllChannels)
+			channelRoute.GET("/search", middleware.PermissionAuth(model.PermissionChannelRead), controller.SearchChannels)
+			channelRoute.GET("/models", middleware.PermissionAuth(model.PermissionChannelRead), controller.ListModels)
+			channelRoute.GET("/tags", middleware.PermissionAuth(model.PermissionChannelRead), controller.GetChannelTags)
+			channelRoute.GET("/tag", middleware.PermissionAuth(model.PermissionChannelRead), controller.GetChannelsByTag)
+			channelRoute.PUT("/tag", middleware.PermissionAuth(model.PermissionChannelWrite), controller.UpdateChannelsByTag)
+			channelRoute.POST("/export", middleware.PermissionAuth(model.PermissionChannelWrite), controller.ExportChannelsHandler)
+			channelRoute.POST("/import", middleware.PermissionAuth(model.PermissionChannelWrite), controller.ImportChannelsHandler)
+			channelRoute.GET("/:id", middleware.PermissionAuth(model.PermissionChannelRead), controller.GetChannel)
+			channelRoute.GET("/test", middleware.PermissionAuth(model.PermissionChannelWrite), controller.TestChannels)
+			channelRoute.GET("/test/:id", middleware.PermissionAuth(model.PermissionChannelWrite), controller.TestChannel)
+			channelRoute.POST("/test/:id/full", middleware.PermissionAuth(model.PermissionChannelWrite), controller.TestChannelFully)
+			channelRoute.GET("/test/:id/history", middleware.PermissionAuth(model.PermissionChannelWrite), controller.GetChannelTestHistory)
+			channelRoute.GET("/update_balance", middleware.PermissionAuth(model.PermissionChannelWrite), controller.UpdateAllChannelsBalance)
+			channelRoute.GET("/update_balance/:id", middleware.PermissionAuth(model.PermissionChannelWrite), controller.UpdateChannelBalance)
+			channelRoute.GET("/sync_models", middleware.PermissionAuth(model.PermissionChannelWrite), controller.SyncAllChannelsModels)
+			channelRoute.POST("/sync_models", middleware.PermissionAuth(model.PermissionChannelWrite), controller.SyncAllChannelsModels)
+			channelRoute.GET("/sync_models/:id", middleware.PermissionAuth(model.PermissionChannelWrite), controller.SyncChannelModels)
+			channelRoute.POST("/sync_models/:id", middleware.PermissionAuth(model.PermissionChannelWrite), controller.SyncChannelModels)
+			channelRoute.POST("/", middleware.PermissionAuth(model.PermissionChannelWrite), controller.AddChannel)
+			channelRoute.PUT("/", middleware.PermissionAuth(model.PermissionChannelWrite), controller.UpdateChannel)
+			channelRoute.POST("/batchdelete", middleware.PermissionAuth(model.PermissionChannelWrite), controller.BatchDelteChannel)
+			channelRoute.DELETE("/disabled", middleware.PermissionAuth(model.PermissionChannelWrite), controller.DeleteDisabledChannel)
+			channelRoute.DELETE("/:id", middleware.PermissionAuth(model.PermissionChannelWrite), controller.DeleteChannel)
 		}
 		tokenRoute := apiRouter.Group("/token")
 		tokenRoute.Use(middleware.UserAuth())
@@ -92,35 +128,66 @@ func SetApiRouter(router *gin.Engine) {
 			tokenRoute.GET("/:id", controller.GetToken)
 			tokenRoute.POST("/", controller.AddToken)
 			tokenRoute.PUT("/", controller.UpdateToken)
+			tokenRoute.POST("/:id/rotate", controller.RotateToken)
 			tokenRoute.POST("/batchdelete", controller.BatchDeleteToken)
 			tokenRoute.DELETE("/:id", controller.DeleteToken)
 		}
+		organizationRoute := apiRouter.Group("/organization")
+		organizationRoute.Use(middleware.UserAuth())
+		{
+			organizationRoute.GET("/", controller.GetUserOrganizations)
+			organizationRoute.GET("/all", middleware.PermissionAuth(model.PermissionOrganizationManage), controller.GetAllOrganizations)
+			organizationRoute.GET("/:id", controller.GetOrganization)
+			organizationRoute.POST("/", controller.AddOrganization)
+			organizationRoute.PUT("/", controller.UpdateOrganization)
+			organizationRoute.DELETE("/:id", controller.DeleteOrganization)
+			organizationRoute.GET("/:id/member", controller.GetOrganizationMembers)
+			organizationRoute.POST("/:id/member", controller.AddOrganizationMember)
+			organizationRoute.PUT("/:id/member", controller.UpdateOrganizationMember)
+			organizationRoute.DELETE("/:id/member/:user_id", controller.RemoveOrganizationMember)
+			organizationRoute.GET("/:id/log", controller.GetOrganizationLogs)
+		}
 		redemptionRoute := apiRouter.Group("/redemption")
-		redemptionRoute.Use(middleware.AdminAuth())
 		{
-			redemptionRoute.GET("/", controller.GetAllRedemptions)
-			redemptionRoute.GET("/search", controller.SearchRedemptions)
-			redemptionRoute.GET("/:id", controller.GetRedemption)
-			redemptionRoute.POST("/", controller.AddRedemption)
-			redemptionRoute.PUT("/", controller.UpdateRedemption)
-			redemptionRoute.POST("/batchdelete", controller.BatchDeleteRedemption)
-			redemptionRoute.DELETE("/:id", controller.DeleteRedemption)
+			redemptionRoute.GET("/", middleware.PermissionAuth(model.PermissionRedemptionRead), controller.GetAllRedemptions)
+			redemptionRoute.GET("/search", middleware.PermissionAuth(model.PermissionRedemptionRead), controller.SearchRedemptions)
+			redemptionRoute.GET("/:id", middleware.PermissionAuth(model.PermissionRedemptionRead), controller.GetRedemption)
+			redemptionRoute.POST("/", middleware.PermissionAuth(model.PermissionRedemptionCreate), controller.AddRedemption)
+			redemptionRoute.PUT("/", middleware.PermissionAuth(model.PermissionRedemptionWrite), controller.UpdateRedemption)
+			redemptionRoute.POST("/batchdelete", middleware.PermissionAuth(model.PermissionRedemptionWrite), controller.BatchDeleteRedemption)
+			redemptionRoute.DELETE("/:id", middleware.PermissionAuth(model.PermissionRedemptionWrite), controller.DeleteRedemption)
+		}
+		roleRoute := apiRouter.Group("/role")
+		roleRoute.Use(middleware.PermissionAuth(model.PermissionRoleManage))
+		{
+			roleRoute.GET("/", controller.GetAllRoles)
+			roleRoute.GET("/permissions", controller.GetAllPermissions)
+			roleRoute.POST("/", controller.AddRole)
+			roleRoute.PUT("/", controller.UpdateRole)
+			roleRoute.DELETE("/:id", controller.DeleteRole)
+			roleRoute.POST("/assign", controller.AssignUserRole)
+		}
+		auditRoute := apiRouter.Group("/audit")
+		auditRoute.Use(middleware.PermissionAuth(model.PermissionAuditRead))
+		{
+			auditRoute.GET("/", controller.GetAuditLogs)
+			auditRoute.GET("/export", controller.ExportAuditLogs)
 		}
 		logRoute := apiRouter.Group("/log")
-		logRoute.GET("/", middleware.AdminAuth(), controller.GetAllLogs)
-		logRoute.DELETE("/", middleware.AdminAuth(), controller.DeleteHistoryLogs)
-		logRoute.GET("/stat", middleware.AdminAuth(), controller.GetLogsStat)
+		logRoute.GET("/", middleware.PermissionAuth(model.PermissionLogRead), controller.GetAllLogs)
+		logRoute.DELETE("/", middleware.PermissionAuth(model.PermissionLogDelete), controller.DeleteHistoryLogs)
+		logRoute.GET("/stat", middleware.PermissionAuth(model.PermissionLogRead), controller.GetLogsStat)
 		logRoute.GET("/self/stat", middleware.UserAuth(), controller.GetLogsSelfStat)
-		logRoute.GET("/search", middleware.AdminAuth(), controller.SearchAllLogs)
+		logRoute.GET("/search", middleware.PermissionAuth(model.PermissionLogRead), controller.SearchAllLogs)
 		logRoute.GET("/self", middleware.UserAuth(), controller.GetUserLogs)
 		logRoute.GET("/self/search", middleware.UserAuth(), controller.SearchUserLogs)
 		groupRoute := apiRouter.Group("/group")
-		groupRoute.Use(middleware.AdminAuth())
+		groupRoute.Use(middleware.PermissionAuth(model.PermissionGroupRead))
 		{
 			groupRoute.GET("/", controller.GetGroups)
 		}
 		menuRoute := apiRouter.Group("/menus")
-		menuRoute.GET("/", controller.Getmenus)
+		menuRoute.GET("/", middleware.UserAuth(), controller.Getmenus)
 	}
 	cryptoaiRoute := apiRouter.Group("/")
 	// cryptoaiRoute.GET("/pay/crypt/get_qrcode", middleware.UserAuth(), middleware.GlobalAPIRateLimit(), controller.GetQrcode)
@@ -129,18 +196,18 @@ func SetApiRouter(router *gin.Engine) {
 	cryptoaiRoute.GET("/crypt/callback", middleware.CryptCallbackAuth(), controller.CryptCallback)
 
 	orderRoute := apiRouter.Group("/order")
-	orderRoute.GET("/", middleware.AdminAuth(), controller.GetAllOrders)
+	orderRoute.GET("/", middleware.PermissionAuth(model.PermissionOrderRead), controller.GetAllOrders)
 	orderRoute.GET("/self", middleware.UserAuth(), controller.GetUserOrders)
 
 	dashboardRoute := apiRouter.Group("/dashboard1")
-	dashboardRoute.GET("/", middleware.AdminAuth(), controller.GetAdminDashboard)
-	dashboardRoute.GET("/graph", middleware.AdminAuth(), controller.GetAllGraph)
+	dashboardRoute.GET("/", middleware.PermissionAuth(model.PermissionDashboardRead), controller.GetAdminDashboard)
+	dashboardRoute.GET("/graph", middleware.PermissionAuth(model.PermissionDashboardRead), controller.GetAllGraph)
 	dashboardRoute.GET("/self", middleware.UserAuth(), controller.GetUserDashboard)
 	dashboardRoute.GET("/graph/self", middleware.UserAuth(), controller.GetUserGraph)
 
 	mjRoute := apiRouter.Group("/mj")
 	mjRoute.GET("/self", middleware.UserAuth(), controller.GetUserMidjourney)
-	mjRoute.GET("/", middleware.AdminAuth(), controller.GetAllMidjourney)
+	mjRoute.GET("/", middleware.PermissionAuth(model.PermissionMidjourneyRead), controller.GetAllMidjourney)
 
 	chargeRoute := apiRouter.Group("/charge")
 	chargeRoute.GET("/get_config", middleware.UserAuth(), middleware.GlobalWebRateLimit(), controller.GetChargeConfigs)
@@ -8,7 +8,7 @@ import LoginForm from './components/LoginForm';
 import NotFound from './pages/NotFound';
 import Setting from './pages/Setting';
 import EditUser from './pages/User/EditUser';
-import { getLogo, getSystemName } from './helpers';
+import { getLogo, getSystemName, loadMenus } from './helpers';
 import PasswordResetForm from './components/PasswordResetForm';
 import GitHubOAuth from './components/GitHubOAuth';
 import PasswordResetConfirm from './components/PasswordResetConfirm';
@@ -54,6 +54,14 @@ function App() {
     }
   }, []);
 
+  // the pages and permissions are refreshed on each login, they change with the role of the user
+  useEffect(() => {
+    if (!userState.user) return;
+    loadMenus().then((menus) => {
+      if (menus) userDispatch({ type: 'menus', payload: menus });
+    });
+  }, [userState.user]);
+
   return (
     <Layout>
       <Layout.Content>
@@ -54,7 +54,19 @@ const ChannelsTable = () => {
     },
     {
       title: '名称',
-      dataIndex: 'name'
+      dataIndex: 'name',
+      render: (text, record, index) => {
+        return (
+          <div>
+            {text}{' '}
+            {record.managed && (
+              <Tooltip content={'本渠道由配置文件管理，只读'}>
+                <Tag size="small">配置文件</Tag>
+              </Tooltip>
+            )}
+          </div>
+        );
+      }
     },
     // {
     //   title: '分组',
@@ -135,6 +147,7 @@ const ChannelsTable = () => {
             <InputNumber
               style={{ width: 70 }}
               name="priority"
+              disabled={record.managed}
               onBlur={e => {
                 manageChannel(record.id, 'priority', record, e.target.value);
               }}
@@ -188,6 +201,7 @@ const ChannelsTable = () => {
             content="此修改将不可逆"
             okType={'danger'}
             position={'left'}
+            disabled={record.managed}
             onConfirm={() => {
               manageChannel(record.id, 'delete', record).then(
                 () => {
@@ -196,11 +210,11 @@ const ChannelsTable = () => {
               );
             }}
           >
-            <Button theme="light" type="danger" style={{ marginRight: 1 }}>删除</Button>
+            <Button theme="light" type="danger" style={{ marginRight: 1 }} disabled={record.managed}>删除</Button>
           </Popconfirm>
           {
             record.status === 1 ?
-              <Button theme="light" type="warning" style={{ marginRight: 1 }} onClick={
+              <Button theme="light" type="warning" style={{ marginRight: 1 }} disabled={record.managed} onClick={
                 async () => {
                   manageChannel(
                     record.id,
@@ -209,7 +223,7 @@ const ChannelsTable = () => {
                   );
                 }
               }>禁用</Button> :
-              <Button theme="light" type="secondary" style={{ marginRight: 1 }} onClick={
+              <Button theme="light" type="secondary" style={{ marginRight: 1 }} disabled={record.managed} onClick={
                 async () => {
                   manageChannel(
                     record.id,
@@ -219,7 +233,7 @@ const ChannelsTable = () => {
                 }
               }>启用</Button>
           }
-          <Button theme="light" type="tertiary" style={{ marginRight: 1 }} onClick={
+          <Button theme="light" type="tertiary" style={{ marginRight: 1 }} disabled={record.managed} onClick={
             () => {
               setEditingChannel(record);
               setShowEdit(true);
@@ -481,7 +495,7 @@ const ChannelsTable = () => {
     const res = await API.get(`/api/channel/update_balance`);
     const { success, message } = res.data;
     if (success) {
-      showInfo('已更新完毕所有已启用渠道余额！');
+      showInfo('已开始更新所有已启用渠道余额，请稍后刷新页面查看结果。');
     } else {
       showError(message);
     }
@@ -48,6 +48,7 @@ const HeaderBar = () => {
     showSuccess('注销成功!');
     userDispatch({ type: 'logout' });
     localStorage.removeItem('user');
+    localStorage.removeItem('menus');
     navigate('/login');
   }
 
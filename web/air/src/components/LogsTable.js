@@ -1,5 +1,5 @@
 import React, { useEffect, useState } from 'react';
-import { API, copy, isAdmin, showError, showSuccess, timestamp2string } from '../helpers';
+import { API, copy, hasPermission, showError, showSuccess, timestamp2string } from '../helpers';
 
 import { Avatar, Button, Form, Layout, Modal, Select, Space, Spin, Table, Tag } from '@douyinfe/semi-ui';
 import { ITEMS_PER_PAGE } from '../constants';
@@ -58,7 +58,7 @@ const LogsTable = () => {
   }, {
     title: '渠道',
     dataIndex: 'channel',
-    className: isAdmin() ? 'tableShow' : 'tableHiddle',
+    className: hasPermission('log.read') ? 'tableShow' : 'tableHiddle',
     render: (text, record, index) => {
       return (isAdminUser ? record.type === 0 || record.type === 2 ? <div>
         {<Tag color={colors[parseInt(text) % colors.length]} size="large"> {text} </Tag>}
@@ -67,7 +67,7 @@ const LogsTable = () => {
   }, {
     title: '用户',
     dataIndex: 'username',
-    className: isAdmin() ? 'tableShow' : 'tableHiddle',
+    className: hasPermission('log.read') ? 'tableShow' : 'tableHiddle',
     render: (text, record, index) => {
       return (isAdminUser ? <div>
         <Avatar size="small" color={stringToColor(text)} style={{ marginRight: 4 }}
@@ -147,7 +147,7 @@ const LogsTable = () => {
   const [searchKeyword, setSearchKeyword] = useState('');
   const [searching, setSearching] = useState(false);
   const [logType, setLogType] = useState(0);
-  const isAdminUser = isAdmin();
+  const isAdminUser = hasPermission('log.read');
   let now = new Date();
   // 初始化start_timestamp为前一天
   const [inputs, setInputs] = useState({
@@ -0,0 +1,15 @@
+import React from 'react';
+import { Message } from 'semantic-ui-react';
+
+// ManagedConfigNotice lists the settings owned by the config file, they can only be changed by editing it
+const ManagedConfigNotice = ({ managed }) => {
+  const items = [...managed.options, ...managed.entries];
+  if (items.length === 0) return <></>;
+  return (
+    <Message info>
+      以下设置由配置文件管理，在此处为只读，请通过修改配置文件更改：{items.join('，')}
+    </Message>
+  );
+};
+
+export default ManagedConfigNotice;
@@ -1,5 +1,5 @@
 import React, { useEffect, useState } from 'react';
-import { API, copy, isAdmin, showError, showSuccess, timestamp2string } from '../helpers';
+import { API, copy, hasPermission, showError, showSuccess, timestamp2string } from '../helpers';
 
 import { Banner, Button, Form, ImagePreview, Layout, Modal, Progress, Table, Tag, Typography } from '@douyinfe/semi-ui';
 import { ITEMS_PER_PAGE } from '../constants';
@@ -116,7 +116,7 @@ const LogsTable = () => {
     {
       title: '渠道',
       dataIndex: 'channel_id',
-      className: isAdmin() ? 'tableShow' : 'tableHiddle',
+      className: hasPermission('midjourney.read') ? 'tableShow' : 'tableHiddle',
       render: (text, record, index) => {
         return (
 
@@ -154,7 +154,7 @@ const LogsTable = () => {
     {
       title: '提交结果',
       dataIndex: 'code',
-      className: isAdmin() ? 'tableShow' : 'tableHiddle',
+      className: hasPermission('midjourney.read') ? 'tableShow' : 'tableHiddle',
       render: (text, record, index) => {
         return (
           <div>
@@ -166,7 +166,7 @@ const LogsTable = () => {
     {
       title: '任务状态',
       dataIndex: 'status',
-      className: isAdmin() ? 'tableShow' : 'tableHiddle',
+      className: hasPermission('midjourney.read') ? 'tableShow' : 'tableHiddle',
       render: (text, record, index) => {
         return (
           <div>
@@ -287,7 +287,7 @@ const LogsTable = () => {
   const [activePage, setActivePage] = useState(1);
   const [logCount, setLogCount] = useState(ITEMS_PER_PAGE);
   const [logType, setLogType] = useState(0);
-  const isAdminUser = isAdmin();
+  const isAdminUser = hasPermission('midjourney.read');
   const [isModalOpenurl, setIsModalOpenurl] = useState(false);
   const [showBanner, setShowBanner] = useState(false);
 
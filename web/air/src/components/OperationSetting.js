@@ -1,6 +1,7 @@
 import React, { useEffect, useState } from 'react';
 import { Divider, Form, Grid, Header } from 'semantic-ui-react';
-import { API, showError, showSuccess, timestamp2string, verifyJSON } from '../helpers';
+import { API, loadManagedConfig, showError, showSuccess, showWarning, timestamp2string, verifyJSON } from '../helpers';
+import ManagedConfigNotice from './ManagedConfigNotice';
 
 const OperationSetting = () => {
   let now = new Date();
@@ -50,11 +51,22 @@ const OperationSetting = () => {
     }
   };
 
+  const [managed, setManaged] = useState({ options: [], entries: [] });
+
+  // options owned by the config file cannot be changed here
+  const isManaged = (key) => {
+    if (!managed.options.includes(key)) return false;
+    showWarning(`${key} 由配置文件管理，请通过修改配置文件更改`);
+    return true;
+  };
+
   useEffect(() => {
     getOptions().then();
+    loadManagedConfig().then(setManaged);
   }, []);
 
   const updateOption = async (key, value) => {
+    if (isManaged(key)) return;
     setLoading(true);
     if (key.endsWith('Enabled')) {
       value = inputs[key] === 'true' ? 'false' : 'true';
@@ -73,6 +85,7 @@ const OperationSetting = () => {
   };
 
   const handleInputChange = async (e, { name, value }) => {
+    if (isManaged(name)) return;
     if (name.endsWith('Enabled')) {
       await updateOption(name, value);
     } else {
@@ -159,6 +172,7 @@ const OperationSetting = () => {
     <Grid columns={1}>
       <Grid.Column>
         <Form loading={loading}>
+          <ManagedConfigNotice managed={managed} />
           <Header as='h3'>
             通用设置
           </Header>
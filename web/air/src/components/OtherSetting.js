@@ -1,6 +1,7 @@
 import React, { useEffect, useState } from 'react';
 import { Button, Divider, Form, Grid, Header, Message, Modal } from 'semantic-ui-react';
-import { API, showError, showSuccess } from '../helpers';
+import { API, loadManagedConfig, showError, showSuccess, showWarning } from '../helpers';
+import ManagedConfigNotice from './ManagedConfigNotice';
 import { marked } from 'marked';
 import { Link } from 'react-router-dom';
 
@@ -37,11 +38,22 @@ const OtherSetting = () => {
     }
   };
 
+  const [managed, setManaged] = useState({ options: [], entries: [] });
+
+  // options owned by the config file cannot be changed here
+  const isManaged = (key) => {
+    if (!managed.options.includes(key)) return false;
+    showWarning(`${key} 由配置文件管理，请通过修改配置文件更改`);
+    return true;
+  };
+
   useEffect(() => {
     getOptions().then();
+    loadManagedConfig().then(setManaged);
   }, []);
 
   const updateOption = async (key, value) => {
+    if (isManaged(key)) return;
     setLoading(true);
     const res = await API.put('/api/option/', {
       key,
@@ -57,6 +69,7 @@ const OtherSetting = () => {
   };
 
   const handleInputChange = async (e, { name, value }) => {
+    if (isManaged(name)) return;
     setInputs((inputs) => ({ ...inputs, [name]: value }));
   };
 
@@ -113,6 +126,7 @@ const OtherSetting = () => {
     <Grid columns={1}>
       <Grid.Column>
         <Form loading={loading}>
+          <ManagedConfigNotice managed={managed} />
           <Header as='h3'>通用设置</Header>
           <Form.Button onClick={checkUpdate}>检查更新</Form.Button>
           <Form.Group widths='equal'>
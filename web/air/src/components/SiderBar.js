@@ -3,7 +3,7 @@ import { Link, useNavigate } from 'react-router-dom';
 import { UserContext } from '../context/User';
 import { StatusContext } from '../context/Status';
 
-import { API, getLogo, getSystemName, isAdmin, isMobile, showError } from '../helpers';
+import { API, getLogo, getSystemName, hasPage, isMobile, showError } from '../helpers';
 import '../index.css';
 
 import {
@@ -46,7 +46,7 @@ const SiderBar = () => {
       itemKey: 'channel',
       to: '/channel',
       icon: <IconLayers />,
-      className: isAdmin() ? 'semi-navigation-item-normal' : 'tableHiddle'
+      className: hasPage('channel') ? 'semi-navigation-item-normal' : 'tableHiddle'
     },
     {
       text: '聊天',
@@ -66,7 +66,7 @@ const SiderBar = () => {
       itemKey: 'redemption',
       to: '/redemption',
       icon: <IconGift />,
-      className: isAdmin() ? 'semi-navigation-item-normal' : 'tableHiddle'
+      className: hasPage('redemption') ? 'semi-navigation-item-normal' : 'tableHiddle'
     },
     {
       text: '充值',
@@ -79,7 +79,7 @@ const SiderBar = () => {
       itemKey: 'user',
       to: '/user',
       icon: <IconUser />,
-      className: isAdmin() ? 'semi-navigation-item-normal' : 'tableHiddle'
+      className: hasPage('user') ? 'semi-navigation-item-normal' : 'tableHiddle'
     },
     {
       text: '日志',
@@ -113,7 +113,7 @@ const SiderBar = () => {
     //     to: '/about',
     //     icon: <IconAt/>
     // }
-  ], [localStorage.getItem('enable_data_export'), localStorage.getItem('enable_drawing'), localStorage.getItem('chat_link'), isAdmin()]);
+  ], [localStorage.getItem('enable_data_export'), localStorage.getItem('enable_drawing'), localStorage.getItem('chat_link'), userState.menus]);
 
   const loadStatus = async () => {
     const res = await API.get('/api/status');
@@ -1,6 +1,7 @@
 import React, { useEffect, useState } from 'react';
 import { Button, Divider, Form, Grid, Header, Modal, Message } from 'semantic-ui-react';
-import { API, removeTrailingSlash, showError } from '../helpers';
+import { API, loadManagedConfig, removeTrailingSlash, showError, showWarning } from '../helpers';
+import ManagedConfigNotice from './ManagedConfigNotice';
 
 const SystemSetting = () => {
   let [inputs, setInputs] = useState({
@@ -59,11 +60,22 @@ const SystemSetting = () => {
     }
   };
 
+  const [managed, setManaged] = useState({ options: [], entries: [] });
+
+  // options owned by the config file cannot be changed here
+  const isManaged = (key) => {
+    if (!managed.options.includes(key)) return false;
+    showWarning(`${key} 由配置文件管理，请通过修改配置文件更改`);
+    return true;
+  };
+
   useEffect(() => {
     getOptions().then();
+    loadManagedConfig().then(setManaged);
   }, []);
 
   const updateOption = async (key, value) => {
+    if (isManaged(key)) return;
     setLoading(true);
     switch (key) {
       case 'PasswordLoginEnabled':
@@ -98,6 +110,7 @@ const SystemSetting = () => {
   };
 
   const handleInputChange = async (e, { name, value }) => {
+    if (isManaged(name)) return;
     if (name === 'PasswordLoginEnabled' && inputs[name] === 'true') {
       // block disabling password login
       setShowPasswordWarningModal(true);
@@ -244,6 +257,7 @@ const SystemSetting = () => {
     <Grid columns={1}>
       <Grid.Column>
         <Form loading={loading}>
+          <ManagedConfigNotice managed={managed} />
           <Header as='h3'>通用设置</Header>
           <Form.Group widths='equal'>
             <Form.Input
@@ -1,5 +1,5 @@
 import React, { useEffect, useState } from 'react';
-import { API, copy, showError, showSuccess, timestamp2string } from '../helpers';
+import { API, copy, showError, showSuccess, showWarning, timestamp2string } from '../helpers';
 
 import { ITEMS_PER_PAGE } from '../constants';
 import { renderQuota } from '../helpers/render';
@@ -19,6 +19,9 @@ const OPEN_LINK_OPTIONS = [
   { key: 'opencat', text: 'OpenCat', value: 'opencat' }
 ];
 
+// the key is only returned when the token is created or rotated
+const KEY_LOST_MESSAGE = '令牌密钥只在创建或轮换时显示一次，如已遗失请轮换令牌获取新的密钥。';
+
 function renderTimestamp(timestamp) {
   return (
     <>
@@ -66,7 +69,14 @@ const TokensTable = () => {
   const columns = [
     {
       title: '名称',
-      dataIndex: 'name'
+      dataIndex: 'name',
+      render: (text, record, index) => {
+        return (
+          <div>
+            {text} <Tag size="small">sk-{record.key_prefix}…</Tag>
+          </div>
+        );
+      }
     },
     {
       title: '状态',
@@ -132,7 +142,7 @@ const TokensTable = () => {
         <div>
           <Popover
             content={
-              'sk-' + record.key
+              record.key ? 'sk-' + record.key : KEY_LOST_MESSAGE
             }
             style={{ padding: 20 }}
             position="top"
@@ -141,6 +151,10 @@ const TokensTable = () => {
           </Popover>
           <Button theme="light" type="secondary" style={{ marginRight: 1 }}
                   onClick={async (text) => {
+                    if (!record.key) {
+                      showWarning(KEY_LOST_MESSAGE);
+                      return;
+                    }
                     await copyText('sk-' + record.key);
                   }}
           >复制</Button>
@@ -193,13 +207,23 @@ const TokensTable = () => {
             onConfirm={() => {
               manageToken(record.id, 'delete', record).then(
                 () => {
-                  removeRecord(record.key);
+                  removeRecord(record.id);
                 }
               );
             }}
           >
             <Button theme="light" type="danger" style={{ marginRight: 1 }}>删除</Button>
           </Popconfirm>
+          <Popconfirm
+            title="确定是否要轮换此令牌？"
+            content="将生成新密钥，旧密钥在宽限期后失效"
+            position={'left'}
+            onConfirm={() => {
+              manageToken(record.id, 'rotate', record);
+            }}
+          >
+            <Button theme="light" type="tertiary" style={{ marginRight: 1 }}>轮换</Button>
+          </Popconfirm>
           {
             record.status === 1 ?
               <Button theme="light" type="warning" style={{ marginRight: 1 }} onClick={
@@ -302,6 +326,10 @@ const TokensTable = () => {
   };
 
   const onCopy = async (type, key) => {
+    if (!key) {
+      showWarning(KEY_LOST_MESSAGE);
+      return;
+    }
     let status = localStorage.getItem('status');
     let serverAddress = '';
     if (status) {
@@ -354,6 +382,10 @@ const TokensTable = () => {
   };
 
   const onOpenLink = async (type, key) => {
+    if (!key) {
+      showWarning(KEY_LOST_MESSAGE);
+      return;
+    }
     let status = localStorage.getItem('status');
     let serverAddress = '';
     if (status) {
@@ -401,10 +433,10 @@ const TokensTable = () => {
       });
   }, [pageSize, orderBy]);
 
-  const removeRecord = key => {
+  const removeRecord = id => {
     let newDataSource = [...tokens];
-    if (key != null) {
-      let idx = newDataSource.findIndex(data => data.key === key);
+    if (id != null) {
+      let idx = newDataSource.findIndex(data => data.id === id);
 
       if (idx > -1) {
         newDataSource.splice(idx, 1);
@@ -429,6 +461,9 @@ const TokensTable = () => {
         data.status = 2;
         res = await API.put('/api/token/?status_only=true', data);
         break;
+      case 'rotate':
+        res = await API.post(`/api/token/${id}/rotate`);
+        break;
     }
     const { success, message } = res.data;
     if (success) {
@@ -438,6 +473,10 @@ const TokensTable = () => {
       // let realIdx = (activePage - 1) * ITEMS_PER_PAGE + idx;
       if (action === 'delete') {
 
+      } else if (action === 'rotate') {
+        // the new key is kept in the list until the page is reloaded
+        newTokens = newTokens.map(item => (item.id === id ? token : item));
+        await copyText('sk-' + token.key);
       } else {
         record.status = token.status;
         // newTokens[realIdx].status = token.status;
@@ -564,7 +603,7 @@ const TokensTable = () => {
                 onClick={searchTokens} style={{ marginRight: 8 }}>查询</Button>
       </Form>
 
-      <Table style={{ marginTop: 20 }} columns={columns} dataSource={pageData} pagination={{
+      <Table style={{ marginTop: 20 }} columns={columns} dataSource={pageData} rowKey="id" pagination={{
         currentPage: activePage,
         pageSize: pageSize,
         total: tokenCount,
@@ -594,7 +633,13 @@ const TokensTable = () => {
           }
           let keys = '';
           for (let i = 0; i < selectedKeys.length; i++) {
-            keys += selectedKeys[i].name + '    sk-' + selectedKeys[i].key + '\n';
+            if (selectedKeys[i].key) {
+              keys += selectedKeys[i].name + '    sk-' + selectedKeys[i].key + '\n';
+            }
+          }
+          if (keys === '') {
+            showWarning(KEY_LOST_MESSAGE);
+            return;
           }
           await copyText(keys);
         }
@@ -294,7 +294,7 @@ const EditChannel = (props) => {
                 footer={
                     <div style={{display: 'flex', justifyContent: 'flex-end'}}>
                         <Space>
-                            <Button theme='solid' size={'large'} onClick={submit}>提交</Button>
+                            <Button theme='solid' size={'large'} disabled={inputs.managed} onClick={submit}>提交</Button>
                             <Button theme='solid' size={'large'} type={'tertiary'} onClick={handleCancel}>取消</Button>
                         </Space>
                     </div>
@@ -304,6 +304,11 @@ const EditChannel = (props) => {
                 width={isMobile() ? '100%' : 600}
             >
                 <Spin spinning={loading}>
+                    {
+                        inputs.managed && (
+                            <Banner type={"warning"} description={'本渠道由配置文件管理，只读，请通过修改配置文件更改。'}/>
+                        )
+                    }
                     <div style={{marginTop: 10}}>
                         <Typography.Text strong>类型：</Typography.Text>
                     </div>
@@ -1,7 +1,7 @@
 import React, {useEffect, useRef, useState} from 'react';
 import {Button, Col, Form, Layout, Row, Spin} from "@douyinfe/semi-ui";
 import VChart from '@visactor/vchart';
-import {API, isAdmin, showError, timestamp2string, timestamp2string1} from "../../helpers";
+import {API, hasPermission, showError, timestamp2string, timestamp2string1} from "../../helpers";
 import {
     getQuotaWithUnit, modelColorMap,
     renderNumber,
@@ -23,7 +23,7 @@ const Detail = (props) => {
         data_export_default_time: ''
     });
     const {username, model_name, start_timestamp, end_timestamp, channel} = inputs;
-    const isAdminUser = isAdmin();
+    const isAdminUser = hasPermission('dashboard.read');
     const initialized = useRef(false)
     const [modelDataChart, setModelDataChart] = useState(null);
     const [modelDataPieChart, setModelDataPieChart] = useState(null);
@@ -1,12 +1,15 @@
-import React from 'react';
+import React, { useContext } from 'react';
 import SystemSetting from '../../components/SystemSetting';
-import {isRoot} from '../../helpers';
+import {hasPage} from '../../helpers';
+import { UserContext } from '../../context/User';
 import OtherSetting from '../../components/OtherSetting';
 import PersonalSetting from '../../components/PersonalSetting';
 import OperationSetting from '../../components/OperationSetting';
 import {Layout, TabPane, Tabs} from "@douyinfe/semi-ui";
 
 const Setting = () => {
+    // re-rendered once the pages of the user are loaded
+    useContext(UserContext);
     let panes = [
         {
             tab: '个人设置',
@@ -15,12 +18,14 @@ const Setting = () => {
         }
     ];
 
-    if (isRoot()) {
+    if (hasPage('operateSettings')) {
         panes.push({
             tab: '运营设置',
             content: <OperationSetting/>,
             itemKey: '2'
         });
+    }
+    if (hasPage('systemSettings')) {
         panes.push({
             tab: '系统设置',
             content: <SystemSetting/>,
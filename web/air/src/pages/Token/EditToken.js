@@ -1,6 +1,6 @@
 import React, { useEffect, useState } from 'react';
 import { useNavigate } from 'react-router-dom';
-import { API, isMobile, showError, showSuccess, timestamp2string } from '../../helpers';
+import { API, copy, isMobile, showError, showSuccess, showWarning, timestamp2string } from '../../helpers';
 import { renderQuotaWithPrompt } from '../../helpers/render';
 import {
     AutoComplete,
@@ -159,6 +159,7 @@ const EditToken = (props) => {
     } else {
       // 处理新增多个令牌的情况
       let successCount = 0; // 记录成功创建的令牌数量
+      let keys = ''; // the keys are only returned once, at creation
       for (let i = 0; i < tokenCount; i++) {
         let localInputs = { ...inputs };
         if (i !== 0) {
@@ -178,10 +179,11 @@ const EditToken = (props) => {
         }
         // localInputs.model_limits = localInputs.model_limits.join(',');
         let res = await API.post(`/api/token/`, localInputs);
-        const { success, message } = res.data;
+        const { success, message, data } = res.data;
 
         if (success) {
           successCount++;
+          keys += data.name + '    sk-' + data.key + '\n';
         } else {
           showError(message);
           break; // 如果创建失败，终止循环
@@ -189,7 +191,11 @@ const EditToken = (props) => {
       }
 
       if (successCount > 0) {
-        showSuccess(`${successCount}个令牌创建成功，请在列表页面点击复制获取令牌！`);
+        if (await copy(keys)) {
+          showSuccess(`${successCount}个令牌创建成功，密钥只显示这一次，已复制到剪贴板`);
+        } else {
+          showWarning(`${successCount}个令牌创建成功，密钥只显示这一次，请妥善保存：\n${keys}`);
+        }
         props.refresh();
         props.handleClose();
       }
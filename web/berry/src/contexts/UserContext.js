@@ -1,13 +1,15 @@
 // contexts/User/index.jsx
 import React, { useEffect, useCallback, createContext, useState } from 'react';
-import { LOGIN } from 'store/actions';
-import { useDispatch } from 'react-redux';
+import { LOGIN, SET_MENUS } from 'store/actions';
+import { useDispatch, useSelector } from 'react-redux';
+import { loadMenus } from 'utils/common';
 
 export const UserContext = createContext();
 
 // eslint-disable-next-line
 const UserProvider = ({ children }) => {
   const dispatch = useDispatch();
+  const user = useSelector((state) => state.account.user);
   const [isUserLoaded, setIsUserLoaded] = useState(false);
 
   const loadUser = useCallback(() => {
@@ -23,6 +25,14 @@ const UserProvider = ({ children }) => {
     loadUser();
   }, [loadUser]);
 
+  // the pages and permissions are refreshed on each login, they change with the role of the user
+  useEffect(() => {
+    if (!user) return;
+    loadMenus().then((menus) => {
+      if (menus) dispatch({ type: SET_MENUS, payload: menus });
+    });
+  }, [user, dispatch]);
+
   return <UserContext.Provider value={{ loadUser, isUserLoaded }}> {children} </UserContext.Provider>;
 };
 
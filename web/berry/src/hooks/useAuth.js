@@ -1,11 +1,9 @@
-import { isAdmin } from 'utils/common';
+import { hasPage } from 'utils/common';
 import { useNavigate } from 'react-router-dom';
 const navigate = useNavigate();
 
-const useAuth = () => {
-  const userIsAdmin = isAdmin();
-
-  if (!userIsAdmin) {
+const useAuth = (page) => {
+  if (!hasPage(page)) {
     navigate('/panel/404');
   }
 };
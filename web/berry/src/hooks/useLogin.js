@@ -68,6 +68,7 @@ const useLogin = () => {
   const logout = async () => {
     await API.get('/api/user/logout');
     localStorage.removeItem('user');
+    localStorage.removeItem('menus');
     dispatch({ type: LOGIN, payload: null });
     navigate('/');
   };
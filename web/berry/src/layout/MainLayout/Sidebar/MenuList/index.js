@@ -4,11 +4,13 @@ import { Typography } from '@mui/material';
 // project imports
 import NavGroup from './NavGroup';
 import menuItem from 'menu-items';
-import { isAdmin } from 'utils/common';
+import { hasPage } from 'utils/common';
+import { useSelector } from 'react-redux';
 
 // ==============================|| SIDEBAR MENU LIST ||============================== //
 const MenuList = () => {
-  const userIsAdmin = isAdmin();
+  // re-rendered once the pages of the user are loaded
+  useSelector((state) => state.account.menus);
 
   return (
     <>
@@ -21,7 +23,7 @@ const MenuList = () => {
           );
         }
 
-        const filteredChildren = item.children.filter((child) => !child.isAdmin || userIsAdmin);
+        const filteredChildren = item.children.filter((child) => !child.page || hasPage(child.page));
 
         if (filteredChildren.length === 0) {
           return null;
@@ -26,8 +26,7 @@ const panel = {
       type: 'item',
       url: '/panel/dashboard',
       icon: icons.IconDashboard,
-      breadcrumbs: false,
-      isAdmin: false
+      breadcrumbs: false
     },
     {
       id: 'channel',
@@ -36,7 +35,7 @@ const panel = {
       url: '/panel/channel',
       icon: icons.IconSitemap,
       breadcrumbs: false,
-      isAdmin: true
+      page: 'channel'
     },
     {
       id: 'token',
@@ -61,7 +60,7 @@ const panel = {
       url: '/panel/redemption',
       icon: icons.IconCoin,
       breadcrumbs: false,
-      isAdmin: true
+      page: 'redemption'
     },
     {
       id: 'topup',
@@ -78,7 +77,7 @@ const panel = {
       url: '/panel/user',
       icon: icons.IconUser,
       breadcrumbs: false,
-      isAdmin: true
+      page: 'user'
     },
     {
       id: 'profile',
@@ -86,8 +85,7 @@ const panel = {
       type: 'item',
       url: '/panel/profile',
       icon: icons.IconUserScan,
-      breadcrumbs: false,
-      isAdmin: false
+      breadcrumbs: false
     },
     {
       id: 'setting',
@@ -96,7 +94,7 @@ const panel = {
       url: '/panel/setting',
       icon: icons.IconAdjustments,
       breadcrumbs: false,
-      isAdmin: true
+      page: 'systemSettings'
     }
   ]
 };
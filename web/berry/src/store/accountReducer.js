@@ -1,7 +1,8 @@
 import * as actionTypes from './actions';
 
 export const initialState = {
-  user: undefined
+  user: undefined,
+  menus: undefined
 };
 
 const accountReducer = (state = initialState, action) => {
@@ -14,7 +15,13 @@ const accountReducer = (state = initialState, action) => {
     case actionTypes.LOGOUT:
       return {
         ...state,
-        user: undefined
+        user: undefined,
+        menus: undefined
+      };
+    case actionTypes.SET_MENUS:
+      return {
+        ...state,
+        menus: action.payload
       };
     default:
       return state;
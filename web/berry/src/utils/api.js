@@ -13,6 +13,7 @@ API.interceptors.response.use(
   (error) => {
     if (error.response?.status === 401) {
       localStorage.removeItem('user');
+      localStorage.removeItem('menus');
       store.dispatch({ type: LOGIN, payload: null });
       window.location.href = config.basename + 'login';
     }
@@ -91,11 +91,40 @@ export async function onGitHubOAuthClicked(github_client_id, openInNewTab = fals
   }
 }
 
-export function isAdmin() {
-  let user = localStorage.getItem('user');
-  if (!user) return false;
-  user = JSON.parse(user);
-  return user.role >= 10;
+// loadMenus fetches the pages and the permissions granted to the logged in user
+export async function loadMenus() {
+  const res = await API.get('/api/menus/');
+  const { success, menus, permissions } = res.data;
+  if (!success) return null;
+  const data = { pages: [...menus.Name, ...menus.Settings], permissions };
+  localStorage.setItem('menus', JSON.stringify(data));
+  return data;
+}
+
+function getMenus() {
+  let menus = localStorage.getItem('menus');
+  if (!menus) return { pages: [], permissions: [] };
+  return JSON.parse(menus);
+}
+
+export function hasPermission(permission) {
+  return getMenus().permissions.includes(permission);
+}
+
+export function hasPage(page) {
+  return getMenus().pages.includes(page);
+}
+
+// loadManagedConfig fetches what the config file owns, it is read-only in the settings
+export async function loadManagedConfig() {
+  const res = await API.get('/api/option/managed');
+  const { success, data } = res.data;
+  if (!success || !data.enabled) return { options: [], entries: [] };
+  const entries = [];
+  [['GroupRatio', data.group_ratios], ['ModelRatio', data.model_ratios], ['ModelPrice', data.model_prices]].forEach(
+    ([key, names]) => (names || []).forEach((name) => entries.push(`${key}.${name}`))
+  );
+  return { options: data.options || [], entries };
 }
 
 export function timestamp2string(timestamp) {
@@ -121,6 +121,13 @@ export default function ChannelTableRow({
 
         <TableCell>
           <NameLabel name={item.name} models={item.models} />
+          {item.managed && (
+            <Tooltip title="本渠道由配置文件管理，只读" placement="top">
+              <Label color="info" variant="outlined">
+                配置文件
+              </Label>
+            </Tooltip>
+          )}
         </TableCell>
 
         <TableCell>
@@ -159,6 +166,7 @@ export default function ChannelTableRow({
               id={`switch-${item.id}`}
               checked={statusSwitch === 1}
               onChange={handleStatus}
+              disabled={item.managed}
             />
           </Tooltip>
         </TableCell>
@@ -189,12 +197,14 @@ export default function ChannelTableRow({
               id={`priority-${item.id}`}
               type="text"
               value={priorityValve}
+              disabled={item.managed}
               onChange={(e) => setPriority(e.target.value)}
               sx={{ textAlign: "center" }}
               endAdornment={
                 <InputAdornment position="end">
                   <IconButton
                     onClick={handlePriority}
+                    disabled={item.managed}
                     sx={{ color: "rgb(99, 115, 129)" }}
                     size="small"
                   >
@@ -227,6 +237,7 @@ export default function ChannelTableRow({
         }}
       >
         <MenuItem
+          disabled={item.managed}
           onClick={() => {
             handleCloseMenu();
             handleOpenModal();
@@ -236,7 +247,7 @@ export default function ChannelTableRow({
           <IconEdit style={{ marginRight: "16px" }} />
           编辑
         </MenuItem>
-        <MenuItem onClick={handleDeleteOpen} sx={{ color: "error.main" }}>
+        <MenuItem onClick={handleDeleteOpen} disabled={item.managed} sx={{ color: "error.main" }}>
           <IconTrash style={{ marginRight: "16px" }} />
           删除
         </MenuItem>
@@ -159,7 +159,7 @@ export default function ChannelPage() {
     const res = await API.get(`/api/channel/update_balance`);
     const { success, message } = res.data;
     if (success) {
-      showInfo('已更新完毕所有已启用渠道余额！');
+      showInfo('已开始更新所有已启用渠道余额，请稍后刷新页面查看结果。');
     } else {
       showError(message);
     }
@@ -15,7 +15,7 @@ import LogTableRow from './component/TableRow';
 import LogTableHead from './component/TableHead';
 import TableToolBar from './component/TableToolBar';
 import { API } from 'utils/api';
-import { isAdmin } from 'utils/common';
+import { hasPermission } from 'utils/common';
 import { ITEMS_PER_PAGE } from 'constants';
 import { IconRefresh, IconSearch } from '@tabler/icons-react';
 
@@ -35,7 +35,7 @@ export default function Log() {
   const [searching, setSearching] = useState(false);
   const [searchKeyword, setSearchKeyword] = useState(originalKeyword);
   const [initPage, setInitPage] = useState(true);
-  const userIsAdmin = isAdmin();
+  const userIsAdmin = hasPermission('log.read');
 
   const loadLogs = async (startIdx) => {
     setSearching(true);
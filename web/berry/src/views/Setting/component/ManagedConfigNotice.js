@@ -0,0 +1,15 @@
+import PropTypes from 'prop-types';
+import Alert from '@mui/material/Alert';
+
+// ManagedConfigNotice lists the settings owned by the config file, they can only be changed by editing it
+const ManagedConfigNotice = ({ managed }) => {
+  const items = [...managed.options, ...managed.entries];
+  if (items.length === 0) return null;
+  return <Alert severity="info">以下设置由配置文件管理，在此处为只读，请通过修改配置文件更改：{items.join('，')}</Alert>;
+};
+
+ManagedConfigNotice.propTypes = {
+  managed: PropTypes.object
+};
+
+export default ManagedConfigNotice;
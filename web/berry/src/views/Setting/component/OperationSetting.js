@@ -10,8 +10,9 @@ import {
   FormControlLabel,
   TextField,
 } from "@mui/material";
-import { showSuccess, showError, verifyJSON } from "utils/common";
+import { showSuccess, showError, verifyJSON, loadManagedConfig, showWarning } from "utils/common";
 import { API } from "utils/api";
+import ManagedConfigNotice from "./ManagedConfigNotice";
 import { AdapterDayjs } from "@mui/x-date-pickers/AdapterDayjs";
 import { LocalizationProvider } from "@mui/x-date-pickers/LocalizationProvider";
 import { DateTimePicker } from "@mui/x-date-pickers/DateTimePicker";
@@ -68,11 +69,22 @@ const OperationSetting = () => {
     }
   };
 
+  const [managed, setManaged] = useState({ options: [], entries: [] });
+
+  // options owned by the config file cannot be changed here
+  const isManaged = (key) => {
+    if (!managed.options.includes(key)) return false;
+    showWarning(`${key} 由配置文件管理，请通过修改配置文件更改`);
+    return true;
+  };
+
   useEffect(() => {
     getOptions().then();
+    loadManagedConfig().then(setManaged);
   }, []);
 
   const updateOption = async (key, value) => {
+    if (isManaged(key)) return;
     setLoading(true);
     if (key.endsWith("Enabled")) {
       value = inputs[key] === "true" ? "false" : "true";
@@ -92,6 +104,7 @@ const OperationSetting = () => {
 
   const handleInputChange = async (event) => {
     let { name, value } = event.target;
+    if (isManaged(name)) return;
 
     if (name.endsWith("Enabled")) {
       await updateOption(name, value);
@@ -192,6 +205,7 @@ const OperationSetting = () => {
 
   return (
     <Stack spacing={2}>
+      <ManagedConfigNotice managed={managed} />
       <SubCard title="通用设置">
         <Stack justifyContent="flex-start" alignItems="flex-start" spacing={2}>
           <Stack
@@ -15,8 +15,9 @@ import {
     Divider, Link
 } from '@mui/material';
 import Grid from '@mui/material/Unstable_Grid2';
-import { showError, showSuccess } from 'utils/common'; //,
+import { showError, showSuccess, loadManagedConfig, showWarning } from 'utils/common'; //,
 import { API } from 'utils/api';
+import ManagedConfigNotice from './ManagedConfigNotice';
 import { marked } from 'marked';
 
 const OtherSetting = () => {
@@ -52,11 +53,22 @@ const OtherSetting = () => {
     }
   };
 
+  const [managed, setManaged] = useState({ options: [], entries: [] });
+
+  // options owned by the config file cannot be changed here
+  const isManaged = (key) => {
+    if (!managed.options.includes(key)) return false;
+    showWarning(`${key} 由配置文件管理，请通过修改配置文件更改`);
+    return true;
+  };
+
   useEffect(() => {
     getOptions().then();
+    loadManagedConfig().then(setManaged);
   }, []);
 
   const updateOption = async (key, value) => {
+    if (isManaged(key)) return;
     setLoading(true);
     const res = await API.put('/api/option/', {
       key,
@@ -74,6 +86,7 @@ const OtherSetting = () => {
 
   const handleInputChange = async (event) => {
     let { name, value } = event.target;
+    if (isManaged(name)) return;
     setInputs((inputs) => ({ ...inputs, [name]: value }));
   };
 
@@ -126,6 +139,7 @@ const OtherSetting = () => {
   return (
     <>
       <Stack spacing={2}>
+        <ManagedConfigNotice managed={managed} />
         <SubCard title="通用设置">
           <Grid container spacing={{ xs: 3, sm: 2, md: 4 }}>
             <Grid xs={12}>
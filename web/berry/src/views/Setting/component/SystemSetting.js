@@ -18,8 +18,9 @@ import {
   TextField
 } from '@mui/material';
 import Grid from '@mui/material/Unstable_Grid2';
-import { showError, showSuccess, removeTrailingSlash } from 'utils/common'; //,
+import { showError, showSuccess, removeTrailingSlash, loadManagedConfig, showWarning } from 'utils/common'; //,
 import { API } from 'utils/api';
+import ManagedConfigNotice from './ManagedConfigNotice';
 import { createFilterOptions } from '@mui/material/Autocomplete';
 
 const filter = createFilterOptions();
@@ -75,11 +76,22 @@ const SystemSetting = () => {
     }
   };
 
+  const [managed, setManaged] = useState({ options: [], entries: [] });
+
+  // options owned by the config file cannot be changed here
+  const isManaged = (key) => {
+    if (!managed.options.includes(key)) return false;
+    showWarning(`${key} 由配置文件管理，请通过修改配置文件更改`);
+    return true;
+  };
+
   useEffect(() => {
     getOptions().then();
+    loadManagedConfig().then(setManaged);
   }, []);
 
   const updateOption = async (key, value) => {
+    if (isManaged(key)) return;
     setLoading(true);
     switch (key) {
       case 'PasswordLoginEnabled':
@@ -117,6 +129,7 @@ const SystemSetting = () => {
 
   const handleInputChange = async (event) => {
     let { name, value } = event.target;
+    if (isManaged(name)) return;
 
     if (name === 'PasswordLoginEnabled' && inputs[name] === 'true') {
       // block disabling password login
@@ -202,6 +215,7 @@ const SystemSetting = () => {
   return (
     <>
       <Stack spacing={2}>
+        <ManagedConfigNotice managed={managed} />
         <SubCard title="通用设置">
           <Grid container spacing={{ xs: 3, sm: 2, md: 4 }}>
             <Grid xs={12}>
@@ -23,7 +23,7 @@ import {
 import { AdapterDayjs } from "@mui/x-date-pickers/AdapterDayjs";
 import { LocalizationProvider } from "@mui/x-date-pickers/LocalizationProvider";
 import { DateTimePicker } from "@mui/x-date-pickers/DateTimePicker";
-import { renderQuotaWithPrompt, showSuccess, showError } from "utils/common";
+import { renderQuotaWithPrompt, showSuccess, showError, showWarning } from "utils/common";
 import { API } from "utils/api";
 require("dayjs/locale/zh-cn");
 
@@ -57,12 +57,15 @@ const EditModal = ({ open, tokenId, onCancel, onOk }) => {
     } else {
       res = await API.post(`/api/token/`, values);
     }
-    const { success, message } = res.data;
+    const { success, message, data } = res.data;
     if (success) {
       if (values.is_edit) {
         showSuccess("令牌更新成功！");
       } else {
-        showSuccess("令牌创建成功，请在列表页面点击复制获取令牌！");
+        // the key is only returned once, at creation
+        const key = `sk-${data.key}`;
+        navigator.clipboard.writeText(key);
+        showWarning(`令牌创建成功，密钥只显示这一次，已复制到剪贴板：${key}`);
       }
       setSubmitting(false);
       setStatus({ success: true });
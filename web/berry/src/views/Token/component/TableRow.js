@@ -16,13 +16,14 @@ import {
   Button,
   Tooltip,
   Stack,
-  ButtonGroup
+  ButtonGroup,
+  Typography
 } from '@mui/material';
 
 import TableSwitch from 'ui-component/Switch';
-import { renderQuota, showSuccess, timestamp2string } from 'utils/common';
+import { renderQuota, showSuccess, showWarning, timestamp2string } from 'utils/common';
 
-import { IconDotsVertical, IconEdit, IconTrash, IconCaretDownFilled } from '@tabler/icons-react';
+import { IconDotsVertical, IconEdit, IconTrash, IconCaretDownFilled, IconRefresh } from '@tabler/icons-react';
 
 const COPY_OPTIONS = [
   {
@@ -35,6 +36,9 @@ const COPY_OPTIONS = [
   { key: 'opencat', text: 'OpenCat', url: 'opencat://team/join?domain={serverAddress}&token=sk-{key}', encode: true }
 ];
 
+// the key is only returned when the token is created or rotated
+const KEY_LOST_MESSAGE = '令牌密钥只在创建或轮换时显示一次，如已遗失请轮换令牌获取新的密钥。';
+
 function replacePlaceholders(text, key, serverAddress) {
   return text.replace('{key}', key).replace('{serverAddress}', serverAddress);
 }
@@ -99,6 +103,11 @@ export default function TokensTableRow({ item, manageToken, handleOpenModal, set
     await manageToken(item.id, 'delete', '');
   };
 
+  const handleRotate = async () => {
+    handleCloseMenu();
+    await manageToken(item.id, 'rotate', '');
+  };
+
   const actionItems = createMenu([
     {
       text: '编辑',
@@ -110,6 +119,12 @@ export default function TokensTableRow({ item, manageToken, handleOpenModal, set
       },
       color: undefined
     },
+    {
+      text: '轮换',
+      icon: <IconRefresh style={{ marginRight: '16px' }} />,
+      onClick: handleRotate,
+      color: undefined
+    },
     {
       text: '删除',
       icon: <IconTrash style={{ marginRight: '16px' }} />,
@@ -119,6 +134,11 @@ export default function TokensTableRow({ item, manageToken, handleOpenModal, set
   ]);
 
   const handleCopy = (option, type) => {
+    if (!item.key) {
+      showWarning(KEY_LOST_MESSAGE);
+      handleCloseMenu();
+      return;
+    }
     let serverAddress = '';
     if (siteInfo?.server_address) {
       serverAddress = siteInfo.server_address;
@@ -168,7 +188,12 @@ export default function TokensTableRow({ item, manageToken, handleOpenModal, set
   return (
     <>
       <TableRow tabIndex={item.id}>
-        <TableCell>{item.name}</TableCell>
+        <TableCell>
+          {item.name}
+          <Typography variant="caption" display="block" color="text.secondary">
+            sk-{item.key_prefix}…
+          </Typography>
+        </TableCell>
 
         <TableCell>
           <Tooltip
@@ -211,6 +236,10 @@ export default function TokensTableRow({ item, manageToken, handleOpenModal, set
               <Button
                 color="primary"
                 onClick={() => {
+                  if (!item.key) {
+                    showWarning(KEY_LOST_MESSAGE);
+                    return;
+                  }
                   navigator.clipboard.writeText(`sk-${item.key}`);
                   showSuccess('已复制到剪贴板！');
                 }}
@@ -1,5 +1,5 @@
 import { useState, useEffect } from 'react';
-import { showError, showSuccess } from 'utils/common';
+import { showError, showSuccess, showWarning } from 'utils/common';
 
 import Table from '@mui/material/Table';
 import TableBody from '@mui/material/TableBody';
@@ -103,12 +103,21 @@ export default function Token() {
           status: value
         });
         break;
+      case 'rotate':
+        res = await API.post(url + `${id}/rotate`);
+        break;
     }
     const { success, message } = res.data;
     if (success) {
       showSuccess('操作成功完成！');
       if (action === 'delete') {
         await handleRefresh();
+      } else if (action === 'rotate') {
+        // the new key is kept in the list until the page is reloaded
+        const token = res.data.data;
+        setTokens(tokens.map((item) => (item.id === id ? token : item)));
+        navigator.clipboard.writeText(`sk-${token.key}`);
+        showWarning(`新密钥只显示这一次，已复制到剪贴板：sk-${token.key}`);
       }
     } else {
       showError(message);
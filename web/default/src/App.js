@@ -9,9 +9,11 @@ import NotFound from './pages/NotFound';
 import Setting from './pages/Setting';
 import EditUser from './pages/User/EditUser';
 import AddUser from './pages/User/AddUser';
-import { API, getLogo, getSystemName, showError, showNotice } from './helpers';
+import { API, getLogo, getSystemName, loadMenus, showError, showNotice } from './helpers';
 import PasswordResetForm from './components/PasswordResetForm';
 import GitHubOAuth from './components/GitHubOAuth';
+import OidcOAuth from './components/OidcOAuth';
+import TwoFactorLogin from './components/TwoFactorLogin';
 import PasswordResetConfirm from './components/PasswordResetConfirm';
 import { UserContext } from './context/User';
 import { StatusContext } from './context/Status';
@@ -23,6 +25,7 @@ import Redemption from './pages/Redemption';
 import EditRedemption from './pages/Redemption/EditRedemption';
 import TopUp from './pages/TopUp';
 import Log from './pages/Log';
+import Audit from './pages/Audit';
 import Chat from './pages/Chat';
 
 const Home = lazy(() => import('./pages/Home'));
@@ -85,6 +88,14 @@ function App() {
     }
   }, []);
 
+  // the pages and permissions are refreshed on each login, they change with the role of the user
+  useEffect(() => {
+    if (!userState.user) return;
+    loadMenus().then((menus) => {
+      if (menus) userDispatch({ type: 'menus', payload: menus });
+    });
+  }, [userState.user]);
+
   return (
     <Routes>
       <Route
@@ -215,6 +226,14 @@ function App() {
           </Suspense>
         }
       />
+      <Route
+        path='/login/2fa'
+        element={
+          <Suspense fallback={<Loading></Loading>}>
+            <TwoFactorLogin />
+          </Suspense>
+        }
+      />
       <Route
         path='/register'
         element={
@@ -231,6 +250,14 @@ function App() {
           </Suspense>
         }
       />
+      <Route
+        path='/oauth/oidc'
+        element={
+          <Suspense fallback={<Loading></Loading>}>
+            <OidcOAuth />
+          </Suspense>
+        }
+      />
       <Route
         path='/oauth/github'
         element={
@@ -267,6 +294,14 @@ function App() {
           </PrivateRoute>
         }
       />
+      <Route
+        path='/audit'
+        element={
+          <PrivateRoute>
+            <Audit />
+          </PrivateRoute>
+        }
+      />
       <Route
         path='/about'
         element={
@@ -0,0 +1,181 @@
+import React, { useEffect, useState } from 'react';
+import { Button, Form, Label, Modal, Pagination, Table } from 'semantic-ui-react';
+import { API, downloadTextAsFile, showError, timestamp2string } from '../helpers';
+
+import { ITEMS_PER_PAGE } from '../constants';
+
+const SUCCESS_OPTIONS = [
+  { key: 'all', text: '全部', value: '' },
+  { key: 'true', text: '成功', value: 'true' },
+  { key: 'false', text: '失败', value: 'false' }
+];
+
+function renderJSON(text) {
+  if (!text) return '无';
+  try {
+    return JSON.stringify(JSON.parse(text), null, 2);
+  } catch (e) {
+    return text;
+  }
+}
+
+const AuditLogsTable = () => {
+  const [auditLogs, setAuditLogs] = useState([]);
+  const [total, setTotal] = useState(0);
+  const [loading, setLoading] = useState(true);
+  const [activePage, setActivePage] = useState(1);
+  const [detail, setDetail] = useState(null);
+  const [inputs, setInputs] = useState({
+    actor_name: '',
+    action: '',
+    target_type: '',
+    target_id: '',
+    ip: '',
+    request_id: '',
+    success: '',
+    start_timestamp: '',
+    end_timestamp: ''
+  });
+
+  const handleInputChange = (e, { name, value }) => {
+    setInputs((inputs) => ({ ...inputs, [name]: value }));
+  };
+
+  const buildQuery = () => {
+    const params = new URLSearchParams();
+    for (const [key, value] of Object.entries(inputs)) {
+      if (value === '') continue;
+      if (key === 'start_timestamp' || key === 'end_timestamp') {
+        params.set(key, Date.parse(value) / 1000);
+      } else {
+        params.set(key, value);
+      }
+    }
+    return params.toString();
+  };
+
+  const loadAuditLogs = async (page) => {
+    setLoading(true);
+    const res = await API.get(
+      `/api/audit/?page=${page}&pagesize=${ITEMS_PER_PAGE}&${buildQuery()}`
+    );
+    const { success, message, data } = res.data;
+    if (success) {
+      setAuditLogs(data.list);
+      setTotal(data.total);
+      setActivePage(page);
+    } else {
+      showError(message);
+    }
+    setLoading(false);
+  };
+
+  const exportAuditLogs = async (format) => {
+    const res = await API.get(`/api/audit/export?format=${format}&${buildQuery()}`, {
+      responseType: 'text',
+      transformResponse: (data) => data
+    });
+    downloadTextAsFile(res.data, `audit.${format}`);
+  };
+
+  useEffect(() => {
+    loadAuditLogs(1).then();
+  }, []);
+
+  return (
+    <>
+      <Form>
+        <Form.Group widths='equal'>
+          <Form.Input fluid label='操作人' name='actor_name' value={inputs.actor_name} onChange={handleInputChange} />
+          <Form.Input fluid label='操作' name='action' value={inputs.action} placeholder='如 channel. 或 user.update' onChange={handleInputChange} />
+          <Form.Input fluid label='对象类型' name='target_type' value={inputs.target_type} placeholder='如 user、channel、option' onChange={handleInputChange} />
+          <Form.Input fluid label='对象 ID' name='target_id' value={inputs.target_id} onChange={handleInputChange} />
+        </Form.Group>
+        <Form.Group widths='equal'>
+          <Form.Input fluid label='IP' name='ip' value={inputs.ip} onChange={handleInputChange} />
+          <Form.Input fluid label='请求 ID' name='request_id' value={inputs.request_id} onChange={handleInputChange} />
+          <Form.Select fluid label='结果' name='success' options={SUCCESS_OPTIONS} value={inputs.success} onChange={handleInputChange} />
+          <Form.Input fluid label='起始时间' type='datetime-local' name='start_timestamp' value={inputs.start_timestamp} onChange={handleInputChange} />
+          <Form.Input fluid label='结束时间' type='datetime-local' name='end_timestamp' value={inputs.end_timestamp} onChange={handleInputChange} />
+        </Form.Group>
+        <Form.Group>
+          <Form.Button onClick={() => loadAuditLogs(1)} loading={loading}>查询</Form.Button>
+          <Form.Button onClick={() => exportAuditLogs('csv')}>导出 CSV</Form.Button>
+          <Form.Button onClick={() => exportAuditLogs('json')}>导出 JSON</Form.Button>
+        </Form.Group>
+      </Form>
+      <Table basic compact size='small'>
+        <Table.Header>
+          <Table.Row>
+            <Table.HeaderCell>时间</Table.HeaderCell>
+            <Table.HeaderCell>操作人</Table.HeaderCell>
+            <Table.HeaderCell>操作</Table.HeaderCell>
+            <Table.HeaderCell>对象</Table.HeaderCell>
+            <Table.HeaderCell>结果</Table.HeaderCell>
+            <Table.HeaderCell>IP</Table.HeaderCell>
+            <Table.HeaderCell></Table.HeaderCell>
+          </Table.Row>
+        </Table.Header>
+        <Table.Body>
+          {auditLogs.map((auditLog) => (
+            <Table.Row key={auditLog.id}>
+              <Table.Cell>{timestamp2string(auditLog.created_at)}</Table.Cell>
+              <Table.Cell>{auditLog.actor_id ? `${auditLog.actor_name} (#${auditLog.actor_id})` : '匿名'}</Table.Cell>
+              <Table.Cell>{auditLog.action}</Table.Cell>
+              <Table.Cell>
+                {auditLog.target_type}
+                {auditLog.target_id && ` #${auditLog.target_id}`}
+              </Table.Cell>
+              <Table.Cell>
+                {auditLog.success ? (
+                  <Label basic color='green'>成功</Label>
+                ) : (
+                  <Label basic color='red' title={auditLog.message}>失败</Label>
+                )}
+              </Table.Cell>
+              <Table.Cell>{auditLog.ip}</Table.Cell>
+              <Table.Cell>
+                <Button size='mini' onClick={() => setDetail(auditLog)}>详情</Button>
+              </Table.Cell>
+            </Table.Row>
+          ))}
+        </Table.Body>
+        <Table.Footer>
+          <Table.Row>
+            <Table.HeaderCell colSpan='7'>
+              <Pagination
+                floated='right'
+                activePage={activePage}
+                onPageChange={(e, { activePage }) => loadAuditLogs(activePage)}
+                size='small'
+                siblingRange={1}
+                totalPages={Math.ceil(total / ITEMS_PER_PAGE)}
+              />
+            </Table.HeaderCell>
+          </Table.Row>
+        </Table.Footer>
+      </Table>
+      <Modal onClose={() => setDetail(null)} open={detail !== null}>
+        <Modal.Header>审计记录 #{detail && detail.id}</Modal.Header>
+        <Modal.Content scrolling>
+          {detail && (
+            <>
+              <p>
+                {detail.method} {detail.path}，请求 ID：<code>{detail.request_id}</code>
+              </p>
+              {detail.message && <p>返回信息：{detail.message}</p>}
+              <h4>变更</h4>
+              <pre>{renderJSON(detail.diff)}</pre>
+              <h4>变更前</h4>
+              <pre>{renderJSON(detail.before)}</pre>
+              <h4>变更后 / 请求内容</h4>
+              <pre>{renderJSON(detail.after)}</pre>
+            </>
+          )}
+        </Modal.Content>
+      </Modal>
+    </>
+  );
+};
+
+export default AuditLogsTable;
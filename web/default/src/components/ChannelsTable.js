@@ -290,7 +290,7 @@ const ChannelsTable = () => {
     const res = await API.get(`/api/channel/update_balance`);
     const { success, message } = res.data;
     if (success) {
-      showInfo('已更新完毕所有已启用渠道余额！');
+      showInfo('已开始更新所有已启用渠道余额，请稍后刷新页面查看结果。');
     } else {
       showError(message);
     }
@@ -424,7 +424,16 @@ const ChannelsTable = () => {
               return (
                 <Table.Row key={channel.id}>
                   <Table.Cell>{channel.id}</Table.Cell>
-                  <Table.Cell>{channel.name ? channel.name : '无'}</Table.Cell>
+                  <Table.Cell>
+                    {channel.name ? channel.name : '无'}
+                    {channel.managed && (
+                      <Popup
+                        trigger={<Label basic size='tiny'>配置文件</Label>}
+                        content='本渠道由配置文件管理，只读'
+                        basic
+                      />
+                    )}
+                  </Table.Cell>
                   <Table.Cell>{renderGroup(channel.group)}</Table.Cell>
                   <Table.Cell>{renderType(channel.type)}</Table.Cell>
                   <Table.Cell>{renderStatus(channel.status)}</Table.Cell>
@@ -449,7 +458,7 @@ const ChannelsTable = () => {
                   </Table.Cell>
                   <Table.Cell>
                     <Popup
-                      trigger={<Input type='number' defaultValue={channel.priority} onBlur={(event) => {
+                      trigger={<Input type='number' defaultValue={channel.priority} disabled={channel.managed} onBlur={(event) => {
                         manageChannel(
                           channel.id,
                           'priority',
@@ -486,7 +495,7 @@ const ChannelsTable = () => {
                       {/*</Button>*/}
                       <Popup
                         trigger={
-                          <Button size='small' negative>
+                          <Button size='small' negative disabled={channel.managed}>
                             删除
                           </Button>
                         }
@@ -505,6 +514,7 @@ const ChannelsTable = () => {
                       </Popup>
                       <Button
                         size={'small'}
+                        disabled={channel.managed}
                         onClick={() => {
                           manageChannel(
                             channel.id,
@@ -518,6 +528,7 @@ const ChannelsTable = () => {
                       <Button
                         size={'small'}
                         as={Link}
+                        disabled={channel.managed}
                         to={'/channel/edit/' + channel.id}
                       >
                         编辑
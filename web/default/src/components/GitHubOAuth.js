@@ -20,6 +20,8 @@ const GitHubOAuth = () => {
       if (message === 'bind') {
         showSuccess('绑定成功！');
         navigate('/setting');
+      } else if (data.two_factor) {
+        navigate(`/login/2fa?setup=${data.setup}`);
       } else {
         userDispatch({ type: 'login', payload: data });
         localStorage.setItem('user', JSON.stringify(data));
@@ -3,7 +3,7 @@ import { Link, useNavigate } from 'react-router-dom';
 import { UserContext } from '../context/User';
 
 import { Button, Container, Dropdown, Icon, Menu, Segment } from 'semantic-ui-react';
-import { API, getLogo, getSystemName, isAdmin, isMobile, showSuccess } from '../helpers';
+import { API, getLogo, getSystemName, hasPage, isMobile, showSuccess } from '../helpers';
 import '../index.css';
 
 // Header Buttons
@@ -17,7 +17,7 @@ let headerButtons = [
     name: '渠道',
     to: '/channel',
     icon: 'sitemap',
-    admin: true
+    page: 'channel'
   },
   {
     name: '令牌',
@@ -28,7 +28,7 @@ let headerButtons = [
     name: '兑换',
     to: '/redemption',
     icon: 'dollar sign',
-    admin: true
+    page: 'redemption'
   },
   {
     name: '充值',
@@ -39,13 +39,19 @@ let headerButtons = [
     name: '用户',
     to: '/user',
     icon: 'user',
-    admin: true
+    page: 'user'
   },
   {
     name: '日志',
     to: '/log',
     icon: 'book'
   },
+  {
+    name: '审计',
+    to: '/audit',
+    icon: 'history',
+    page: 'audit'
+  },
   {
     name: '设置',
     to: '/setting',
@@ -80,6 +86,7 @@ const Header = () => {
     showSuccess('注销成功!');
     userDispatch({ type: 'logout' });
     localStorage.removeItem('user');
+    localStorage.removeItem('menus');
     navigate('/login');
   }
 
@@ -89,7 +96,7 @@ const Header = () => {
 
   const renderButtons = (isMobile) => {
     return headerButtons.map((button) => {
-      if (button.admin && !isAdmin()) return <></>;
+      if (button.page && !hasPage(button.page)) return <></>;
       if (isMobile) {
         return (
           <Menu.Item
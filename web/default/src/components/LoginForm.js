@@ -3,7 +3,7 @@ import { Button, Divider, Form, Grid, Header, Image, Message, Modal, Segment } f
 import { Link, useNavigate, useSearchParams } from 'react-router-dom';
 import { UserContext } from '../context/User';
 import { API, getLogo, showError, showSuccess, showWarning } from '../helpers';
-import { onGitHubOAuthClicked } from './utils';
+import { onGitHubOAuthClicked, onOidcClicked } from './utils';
 
 const LoginForm = () => {
   const [inputs, setInputs] = useState({
@@ -13,6 +13,7 @@ const LoginForm = () => {
   });
   const [searchParams, setSearchParams] = useSearchParams();
   const [submitted, setSubmitted] = useState(false);
+  const [ldapLogin, setLdapLogin] = useState(false);
   const { username, password } = inputs;
   const [userState, userDispatch] = useContext(UserContext);
   let navigate = useNavigate();
@@ -41,7 +42,10 @@ const LoginForm = () => {
       `/api/oauth/wechat?code=${inputs.wechat_verification_code}`
     );
     const { success, message, data } = res.data;
-    if (success) {
+    if (success && data.two_factor) {
+      setShowWeChatLoginModal(false);
+      navigate(`/login/2fa?setup=${data.setup}`);
+    } else if (success) {
       userDispatch({ type: 'login', payload: data });
       localStorage.setItem('user', JSON.stringify(data));
       navigate('/');
@@ -60,12 +64,14 @@ const LoginForm = () => {
   async function handleSubmit(e) {
     setSubmitted(true);
     if (username && password) {
-      const res = await API.post(`/api/user/login`, {
+      const res = await API.post(ldapLogin ? `/api/user/login/ldap` : `/api/user/login`, {
         username,
         password
       });
       const { success, message, data } = res.data;
-      if (success) {
+      if (success && data.two_factor) {
+        navigate(`/login/2fa?setup=${data.setup}`);
+      } else if (success) {
         userDispatch({ type: 'login', payload: data });
         localStorage.setItem('user', JSON.stringify(data));
         if (username === 'root' && password === '123456') {
@@ -109,6 +115,15 @@ const LoginForm = () => {
               value={password}
               onChange={handleChange}
             />
+            {status.ldap_login ? (
+              <Form.Checkbox
+                label='使用 LDAP 账户登录'
+                checked={ldapLogin}
+                onChange={() => setLdapLogin(!ldapLogin)}
+              />
+            ) : (
+              <></>
+            )}
             <Button color='green' fluid size='large' onClick={handleSubmit}>
               登录
             </Button>
@@ -124,7 +139,7 @@ const LoginForm = () => {
             点击注册
           </Link>
         </Message>
-        {status.github_oauth || status.wechat_login ? (
+        {status.github_oauth || status.wechat_login || status.oidc_login ? (
           <>
             <Divider horizontal>Or</Divider>
             {status.github_oauth ? (
@@ -147,6 +162,17 @@ const LoginForm = () => {
             ) : (
               <></>
             )}
+            {status.oidc_login ? (
+              <Button
+                circular
+                color='blue'
+                icon='key'
+                title='使用 OIDC 登录'
+                onClick={onOidcClicked}
+              />
+            ) : (
+              <></>
+            )}
           </>
         ) : (
           <></>
@@ -1,6 +1,6 @@
 import React, { useEffect, useState } from 'react';
 import { Button, Form, Header, Label, Pagination, Segment, Select, Table } from 'semantic-ui-react';
-import { API, isAdmin, showError, timestamp2string } from '../helpers';
+import { API, hasPermission, showError, timestamp2string } from '../helpers';
 
 import { ITEMS_PER_PAGE } from '../constants';
 import { renderQuota } from '../helpers/render';
@@ -23,7 +23,8 @@ const LOG_OPTIONS = [
   { key: '1', text: '充值', value: 1 },
   { key: '2', text: '消费', value: 2 },
   { key: '3', text: '管理', value: 3 },
-  { key: '4', text: '系统', value: 4 }
+  { key: '4', text: '系统', value: 4 },
+  { key: '5', text: '安全', value: 5 }
 ];
 
 function renderType(type) {
@@ -36,6 +37,8 @@ function renderType(type) {
       return <Label basic color='orange'> 管理 </Label>;
     case 4:
       return <Label basic color='purple'> 系统 </Label>;
+    case 5:
+      return <Label basic color='red'> 安全 </Label>;
     default:
       return <Label basic color='black'> 未知 </Label>;
   }
@@ -50,7 +53,7 @@ const LogsTable = () => {
   const [searchKeyword, setSearchKeyword] = useState('');
   const [searching, setSearching] = useState(false);
   const [logType, setLogType] = useState(0);
-  const isAdminUser = isAdmin();
+  const isAdminUser = hasPermission('log.read');
   let now = new Date();
   const [inputs, setInputs] = useState({
     username: '',
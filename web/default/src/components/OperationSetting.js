@@ -1,6 +1,7 @@
 import React, { useEffect, useState } from 'react';
 import { Divider, Form, Grid, Header } from 'semantic-ui-react';
-import { API, showError, showSuccess, timestamp2string, verifyJSON } from '../helpers';
+import { API, loadManagedConfig, showError, showSuccess, showWarning, timestamp2string, verifyJSON } from '../helpers';
+import ManagedConfigNotice from './ManagedConfigNotice';
 
 const OperationSetting = () => {
   let now = new Date();
@@ -23,6 +24,8 @@ const OperationSetting = () => {
     DisplayInCurrencyEnabled: '',
     DisplayTokenStatEnabled: '',
     ApproximateTokenEnabled: '',
+    SubUserEnabled: '',
+    SubUserGroups: '',
     RetryTimes: 0
   });
   const [originInputs, setOriginInputs] = useState({});
@@ -50,11 +53,22 @@ const OperationSetting = () => {
     }
   };
 
+  const [managed, setManaged] = useState({ options: [], entries: [] });
+
+  // options owned by the config file cannot be changed here
+  const isManaged = (key) => {
+    if (!managed.options.includes(key)) return false;
+    showWarning(`${key} 由配置文件管理，请通过修改配置文件更改`);
+    return true;
+  };
+
   useEffect(() => {
     getOptions().then();
+    loadManagedConfig().then(setManaged);
   }, []);
 
   const updateOption = async (key, value) => {
+    if (isManaged(key)) return;
     setLoading(true);
     if (key.endsWith('Enabled')) {
       value = inputs[key] === 'true' ? 'false' : 'true';
@@ -73,6 +87,7 @@ const OperationSetting = () => {
   };
 
   const handleInputChange = async (e, { name, value }) => {
+    if (isManaged(name)) return;
     if (name.endsWith('Enabled')) {
       await updateOption(name, value);
     } else {
@@ -140,6 +155,9 @@ const OperationSetting = () => {
         if (originInputs['RetryTimes'] !== inputs.RetryTimes) {
           await updateOption('RetryTimes', inputs.RetryTimes);
         }
+        if (originInputs['SubUserGroups'] !== inputs.SubUserGroups) {
+          await updateOption('SubUserGroups', inputs.SubUserGroups);
+        }
         break;
     }
   };
@@ -159,6 +177,7 @@ const OperationSetting = () => {
     <Grid columns={1}>
       <Grid.Column>
         <Form loading={loading}>
+          <ManagedConfigNotice managed={managed} />
           <Header as='h3'>
             通用设置
           </Header>
@@ -222,6 +241,22 @@ const OperationSetting = () => {
               name='ApproximateTokenEnabled'
               onChange={handleInputChange}
             />
+            <Form.Checkbox
+              checked={inputs.SubUserEnabled === 'true'}
+              label='允许用户创建子用户并分配额度'
+              name='SubUserEnabled'
+              onChange={handleInputChange}
+            />
+          </Form.Group>
+          <Form.Group widths={2}>
+            <Form.Input
+              label='子用户可用分组'
+              name='SubUserGroups'
+              onChange={handleInputChange}
+              autoComplete='new-password'
+              value={inputs.SubUserGroups}
+              placeholder='以逗号分隔，除自己所在分组外，用户只能将子用户设置为这些分组，且倍率不能低于自己所在分组'
+            />
           </Form.Group>
           <Form.Button onClick={() => {
             submitConfig('general').then();
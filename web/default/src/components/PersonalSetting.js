@@ -4,7 +4,9 @@ import { Link, useNavigate } from 'react-router-dom';
 import { API, copy, showError, showInfo, showNotice, showSuccess } from '../helpers';
 import Turnstile from 'react-turnstile';
 import { UserContext } from '../context/User';
-import { onGitHubOAuthClicked } from './utils';
+import { onGitHubOAuthClicked, onOidcClicked } from './utils';
+import TwoFactorSetting from './TwoFactorSetting';
+import SessionSetting from './SessionSetting';
 
 const PersonalSetting = () => {
   const [userState, userDispatch] = useContext(UserContext);
@@ -201,6 +203,12 @@ const PersonalSetting = () => {
         />
       )}
       <Divider />
+      <Header as='h3'>两步验证</Header>
+      <TwoFactorSetting />
+      <Divider />
+      <Header as='h3'>登录会话</Header>
+      <SessionSetting />
+      <Divider />
       <Header as='h3'>账号绑定</Header>
       {
         status.wechat_login && (
@@ -247,6 +255,11 @@ const PersonalSetting = () => {
           <Button onClick={()=>{onGitHubOAuthClicked(status.github_client_id)}}>绑定 GitHub 账号</Button>
         )
       }
+      {
+        status.oidc_login && (
+          <Button onClick={onOidcClicked}>绑定 OIDC 账号</Button>
+        )
+      }
       <Button
         onClick={() => {
           setShowEmailBindModal(true);
@@ -0,0 +1,82 @@
+import React, { useEffect, useState } from 'react';
+import { Button, Label, Table } from 'semantic-ui-react';
+import { API, showError, showSuccess, timestamp2string } from '../helpers';
+
+const SessionSetting = () => {
+  const [sessions, setSessions] = useState([]);
+
+  const loadSessions = async () => {
+    const res = await API.get('/api/user/session');
+    const { success, message, data } = res.data;
+    if (success) {
+      setSessions(data);
+    } else {
+      showError(message);
+    }
+  };
+
+  useEffect(() => {
+    loadSessions().then();
+  }, []);
+
+  const revokeSession = async (id) => {
+    const res = await API.delete(`/api/user/session/${id}`);
+    const { success, message } = res.data;
+    if (success) {
+      showSuccess('已注销该会话');
+      await loadSessions();
+    } else {
+      showError(message);
+    }
+  };
+
+  const revokeOtherSessions = async () => {
+    const res = await API.delete('/api/user/session');
+    const { success, message, data } = res.data;
+    if (success) {
+      showSuccess(`已注销 ${data} 个其他会话`);
+      await loadSessions();
+    } else {
+      showError(message);
+    }
+  };
+
+  return (
+    <>
+      <Table basic compact size='small'>
+        <Table.Header>
+          <Table.Row>
+            <Table.HeaderCell>设备</Table.HeaderCell>
+            <Table.HeaderCell>IP</Table.HeaderCell>
+            <Table.HeaderCell>登录时间</Table.HeaderCell>
+            <Table.HeaderCell>最后活跃</Table.HeaderCell>
+            <Table.HeaderCell></Table.HeaderCell>
+          </Table.Row>
+        </Table.Header>
+        <Table.Body>
+          {sessions.map((session) => (
+            <Table.Row key={session.id}>
+              <Table.Cell title={session.user_agent}>
+                {session.device}{' '}
+                {session.current && <Label size='mini' color='green'>当前</Label>}
+              </Table.Cell>
+              <Table.Cell>{session.ip}</Table.Cell>
+              <Table.Cell>{timestamp2string(session.created_time)}</Table.Cell>
+              <Table.Cell>{timestamp2string(session.last_seen_time)}</Table.Cell>
+              <Table.Cell>
+                {!session.current && (
+                  <Button size='mini' negative onClick={() => revokeSession(session.id)}>
+                    注销
+                  </Button>
+                )}
+              </Table.Cell>
+            </Table.Row>
+          ))}
+        </Table.Body>
+      </Table>
+      <Button onClick={revokeOtherSessions}>注销其他所有会话</Button>
+    </>
+  );
+};
+
+export default SessionSetting;
@@ -1,6 +1,7 @@
 import React, { useEffect, useState } from 'react';
 import { Button, Divider, Form, Grid, Header, Modal, Message } from 'semantic-ui-react';
-import { API, removeTrailingSlash, showError } from '../helpers';
+import { API, loadManagedConfig, removeTrailingSlash, showError, showWarning } from '../helpers';
+import ManagedConfigNotice from './ManagedConfigNotice';
 
 const SystemSetting = () => {
   let [inputs, setInputs] = useState({
@@ -10,6 +11,29 @@ const SystemSetting = () => {
     GitHubOAuthEnabled: '',
     GitHubClientId: '',
     GitHubClientSecret: '',
+    OidcEnabled: '',
+    OidcDiscoveryUrl: '',
+    OidcClientId: '',
+    OidcClientSecret: '',
+    OidcRedirectUri: '',
+    OidcScopes: '',
+    OidcUsernameClaim: '',
+    OidcEmailClaim: '',
+    OidcGroupsClaim: '',
+    OidcRoleMapping: '',
+    OidcGroupMapping: '',
+    LdapEnabled: '',
+    LdapUrl: '',
+    LdapStartTlsEnabled: '',
+    LdapGroupShortNameEnabled: '',
+    LdapUserDn: '',
+    LdapBaseDn: '',
+    LdapUserFilter: '',
+    LdapEmailAttribute: '',
+    LdapDisplayNameAttribute: '',
+    LdapGroupAttribute: '',
+    LdapRoleMapping: '',
+    LdapGroupMapping: '',
     Notice: '',
     SMTPServer: '',
     SMTPPort: '',
@@ -59,17 +83,32 @@ const SystemSetting = () => {
     }
   };
 
+  const [managed, setManaged] = useState({ options: [], entries: [] });
+
+  // options owned by the config file cannot be changed here
+  const isManaged = (key) => {
+    if (!managed.options.includes(key)) return false;
+    showWarning(`${key} 由配置文件管理，请通过修改配置文件更改`);
+    return true;
+  };
+
   useEffect(() => {
     getOptions().then();
+    loadManagedConfig().then(setManaged);
   }, []);
 
   const updateOption = async (key, value) => {
+    if (isManaged(key)) return;
     setLoading(true);
     switch (key) {
       case 'PasswordLoginEnabled':
       case 'PasswordRegisterEnabled':
       case 'EmailVerificationEnabled':
       case 'GitHubOAuthEnabled':
+      case 'OidcEnabled':
+      case 'LdapEnabled':
+      case 'LdapStartTlsEnabled':
+      case 'LdapGroupShortNameEnabled':
       case 'WeChatAuthEnabled':
       case 'TurnstileCheckEnabled':
       case 'EmailDomainRestrictionEnabled':
@@ -98,6 +137,7 @@ const SystemSetting = () => {
   };
 
   const handleInputChange = async (e, { name, value }) => {
+    if (isManaged(name)) return;
     if (name === 'PasswordLoginEnabled' && inputs[name] === 'true') {
       // block disabling password login
       setShowPasswordWarningModal(true);
@@ -109,6 +149,11 @@ const SystemSetting = () => {
       name === 'ServerAddress' ||
       name === 'GitHubClientId' ||
       name === 'GitHubClientSecret' ||
+      (name.startsWith('Oidc') && name !== 'OidcEnabled') ||
+      (name.startsWith('Ldap') &&
+        name !== 'LdapEnabled' &&
+        name !== 'LdapStartTlsEnabled' &&
+        name !== 'LdapGroupShortNameEnabled') ||
       name === 'WeChatServerAddress' ||
       name === 'WeChatServerToken' ||
       name === 'WeChatAccountQRCodeImageURL' ||
@@ -212,6 +257,50 @@ const SystemSetting = () => {
     }
   };
 
+  const submitOidc = async () => {
+    const keys = [
+      'OidcDiscoveryUrl',
+      'OidcClientId',
+      'OidcRedirectUri',
+      'OidcScopes',
+      'OidcUsernameClaim',
+      'OidcEmailClaim',
+      'OidcGroupsClaim',
+      'OidcRoleMapping',
+      'OidcGroupMapping',
+    ];
+    for (const key of keys) {
+      if (originInputs[key] !== inputs[key]) {
+        await updateOption(key, inputs[key]);
+      }
+    }
+    if (
+      originInputs['OidcClientSecret'] !== inputs.OidcClientSecret &&
+      inputs.OidcClientSecret !== ''
+    ) {
+      await updateOption('OidcClientSecret', inputs.OidcClientSecret);
+    }
+  };
+
+  const submitLdap = async () => {
+    const keys = [
+      'LdapUrl',
+      'LdapUserDn',
+      'LdapBaseDn',
+      'LdapUserFilter',
+      'LdapEmailAttribute',
+      'LdapDisplayNameAttribute',
+      'LdapGroupAttribute',
+      'LdapRoleMapping',
+      'LdapGroupMapping',
+    ];
+    for (const key of keys) {
+      if (originInputs[key] !== inputs[key]) {
+        await updateOption(key, inputs[key]);
+      }
+    }
+  };
+
   const submitTurnstile = async () => {
     if (originInputs['TurnstileSiteKey'] !== inputs.TurnstileSiteKey) {
       await updateOption('TurnstileSiteKey', inputs.TurnstileSiteKey);
@@ -244,6 +333,7 @@ const SystemSetting = () => {
     <Grid columns={1}>
       <Grid.Column>
         <Form loading={loading}>
+          <ManagedConfigNotice managed={managed} />
           <Header as='h3'>通用设置</Header>
           <Form.Group widths='equal'>
             <Form.Input
@@ -310,6 +400,18 @@ const SystemSetting = () => {
               name='GitHubOAuthEnabled'
               onChange={handleInputChange}
             />
+            <Form.Checkbox
+              checked={inputs.OidcEnabled === 'true'}
+              label='允许通过 OIDC 登录 & 注册'
+              name='OidcEnabled'
+              onChange={handleInputChange}
+            />
+            <Form.Checkbox
+              checked={inputs.LdapEnabled === 'true'}
+              label='允许通过 LDAP 登录 & 注册'
+              name='LdapEnabled'
+              onChange={handleInputChange}
+            />
             <Form.Checkbox
               checked={inputs.WeChatAuthEnabled === 'true'}
               label='允许通过微信登录 & 注册'
@@ -469,6 +571,190 @@ const SystemSetting = () => {
             保存 GitHub OAuth 设置
           </Form.Button>
           <Divider />
+          <Header as='h3'>
+            配置 OpenID Connect
+            <Header.Subheader>
+              用以支持通过企业 SSO 等 OIDC 身份提供方进行登录注册
+            </Header.Subheader>
+          </Header>
+          <Message>
+            回调地址填 <code>{`${inputs.ServerAddress}/oauth/oidc`}</code>
+            ，声明名称支持使用 <code>realm_access.roles</code> 形式访问嵌套的声明；
+            角色映射与分组映射为 JSON 对象，键为分组声明中的值，值为角色名称或用户分组，例如{' '}
+            <code>{'{"one-api-admins": "admin"}'}</code>，配置角色映射后用户每次登录时都会同步角色
+          </Message>
+          <Form.Group widths={3}>
+            <Form.Input
+              label='Discovery URL'
+              name='OidcDiscoveryUrl'
+              onChange={handleInputChange}
+              value={inputs.OidcDiscoveryUrl}
+              placeholder='例如：https://sso.example.com/realms/main'
+            />
+            <Form.Input
+              label='Client ID'
+              name='OidcClientId'
+              onChange={handleInputChange}
+              autoComplete='new-password'
+              value={inputs.OidcClientId}
+            />
+            <Form.Input
+              label='Client Secret'
+              name='OidcClientSecret'
+              onChange={handleInputChange}
+              type='password'
+              autoComplete='new-password'
+              value={inputs.OidcClientSecret}
+              placeholder='敏感信息不会发送到前端显示'
+            />
+          </Form.Group>
+          <Form.Group widths={3}>
+            <Form.Input
+              label='Scopes'
+              name='OidcScopes'
+              onChange={handleInputChange}
+              value={inputs.OidcScopes}
+            />
+            <Form.Input
+              label='回调地址'
+              name='OidcRedirectUri'
+              onChange={handleInputChange}
+              value={inputs.OidcRedirectUri}
+              placeholder='留空则使用服务器地址下的 /oauth/oidc'
+            />
+          </Form.Group>
+          <Form.Group widths={3}>
+            <Form.Input
+              label='用户名声明'
+              name='OidcUsernameClaim'
+              onChange={handleInputChange}
+              value={inputs.OidcUsernameClaim}
+            />
+            <Form.Input
+              label='邮箱声明'
+              name='OidcEmailClaim'
+              onChange={handleInputChange}
+              value={inputs.OidcEmailClaim}
+            />
+            <Form.Input
+              label='分组声明'
+              name='OidcGroupsClaim'
+              onChange={handleInputChange}
+              value={inputs.OidcGroupsClaim}
+            />
+          </Form.Group>
+          <Form.Group widths='equal'>
+            <Form.TextArea
+              label='角色映射'
+              name='OidcRoleMapping'
+              onChange={handleInputChange}
+              value={inputs.OidcRoleMapping}
+              style={{ minHeight: 100, fontFamily: 'JetBrains Mono, Consolas' }}
+            />
+            <Form.TextArea
+              label='分组映射'
+              name='OidcGroupMapping'
+              onChange={handleInputChange}
+              value={inputs.OidcGroupMapping}
+              style={{ minHeight: 100, fontFamily: 'JetBrains Mono, Consolas' }}
+            />
+          </Form.Group>
+          <Form.Button onClick={submitOidc}>保存 OIDC 设置</Form.Button>
+          <Divider />
+          <Header as='h3'>
+            配置 LDAP
+            <Header.Subheader>
+              用以支持通过企业目录服务的账户进行登录注册
+            </Header.Subheader>
+          </Header>
+          <Message>
+            用户 DN 与用户过滤器中的 <code>%s</code> 会被替换为登录时输入的用户名，
+            例如 <code>uid=%s,ou=people,dc=example,dc=com</code> 或{' '}
+            <code>%s@example.com</code>；
+            角色映射与分组映射的键可以是分组的完整 DN 或其名称，例如{' '}
+            <code>{'{"one-api-admins": "admin"}'}</code>，用户每次登录时都会同步邮箱、显示名称、角色与分组
+          </Message>
+          <Form.Group widths={3}>
+            <Form.Input
+              label='LDAP 地址'
+              name='LdapUrl'
+              onChange={handleInputChange}
+              value={inputs.LdapUrl}
+              placeholder='例如：ldaps://ldap.example.com:636'
+            />
+            <Form.Input
+              label='用户 DN'
+              name='LdapUserDn'
+              onChange={handleInputChange}
+              value={inputs.LdapUserDn}
+              placeholder='例如：uid=%s,ou=people,dc=example,dc=com'
+            />
+            <Form.Input
+              label='Base DN'
+              name='LdapBaseDn'
+              onChange={handleInputChange}
+              value={inputs.LdapBaseDn}
+              placeholder='留空则直接读取用户 DN 对应的条目'
+            />
+          </Form.Group>
+          <Form.Group widths={4}>
+            <Form.Input
+              label='用户过滤器'
+              name='LdapUserFilter'
+              onChange={handleInputChange}
+              value={inputs.LdapUserFilter}
+            />
+            <Form.Input
+              label='邮箱属性'
+              name='LdapEmailAttribute'
+              onChange={handleInputChange}
+              value={inputs.LdapEmailAttribute}
+            />
+            <Form.Input
+              label='显示名称属性'
+              name='LdapDisplayNameAttribute'
+              onChange={handleInputChange}
+              value={inputs.LdapDisplayNameAttribute}
+            />
+            <Form.Input
+              label='分组属性'
+              name='LdapGroupAttribute'
+              onChange={handleInputChange}
+              value={inputs.LdapGroupAttribute}
+            />
+          </Form.Group>
+          <Form.Group inline>
+            <Form.Checkbox
+              checked={inputs.LdapStartTlsEnabled === 'true'}
+              label='使用 StartTLS'
+              name='LdapStartTlsEnabled'
+              onChange={handleInputChange}
+            />
+            <Form.Checkbox
+              checked={inputs.LdapGroupShortNameEnabled === 'true'}
+              label='映射中允许使用分组的短名称（如 admins），不同 OU 下的同名分组将无法区分'
+              name='LdapGroupShortNameEnabled'
+              onChange={handleInputChange}
+            />
+          </Form.Group>
+          <Form.Group widths='equal'>
+            <Form.TextArea
+              label='角色映射'
+              name='LdapRoleMapping'
+              onChange={handleInputChange}
+              value={inputs.LdapRoleMapping}
+              style={{ minHeight: 100, fontFamily: 'JetBrains Mono, Consolas' }}
+            />
+            <Form.TextArea
+              label='分组映射'
+              name='LdapGroupMapping'
+              onChange={handleInputChange}
+              value={inputs.LdapGroupMapping}
+              style={{ minHeight: 100, fontFamily: 'JetBrains Mono, Consolas' }}
+            />
+          </Form.Group>
+          <Form.Button onClick={submitLdap}>保存 LDAP 设置</Form.Button>
+          <Divider />
           <Header as='h3'>
             配置 WeChat Server
             <Header.Subheader>
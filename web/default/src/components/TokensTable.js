@@ -87,6 +87,10 @@ const TokensTable = () => {
   };
 
   const onCopy = async (type, key) => {
+    if (!key) {
+      showWarning('令牌密钥只在创建或轮换时显示一次，如已遗失请轮换令牌获取新的密钥。');
+      return;
+    }
     let status = localStorage.getItem('status');
     let serverAddress = '';
     if (status) {
@@ -129,6 +133,10 @@ const TokensTable = () => {
   };
 
   const onOpenLink = async (type, key) => {
+    if (!key) {
+      showWarning('令牌密钥只在创建或轮换时显示一次，如已遗失请轮换令牌获取新的密钥。');
+      return;
+    }
     let status = localStorage.getItem('status');
     let serverAddress = '';
     if (status) {
@@ -187,6 +195,9 @@ const TokensTable = () => {
         data.status = 2;
         res = await API.put('/api/token/', data);
         break;
+      case 'rotate':
+        res = await API.post(`/api/token/${id}/rotate`);
+        break;
     }
     const { success, message } = res.data;
     if (success) {
@@ -196,6 +207,10 @@ const TokensTable = () => {
       let realIdx = (activePage - 1) * ITEMS_PER_PAGE + idx;
       if (action === 'delete') {
         newTokens[realIdx].deleted = true;
+      } else if (action === 'rotate') {
+        // the new key is kept in the list until the page is reloaded
+        newTokens[realIdx] = token;
+        await onCopy('', token.key);
       } else {
         newTokens[realIdx].status = token.status;
       }
@@ -329,7 +344,10 @@ const TokensTable = () => {
             if (token.deleted) return <></>;
             return (
               <Table.Row key={token.id}>
-                <Table.Cell>{token.name ? token.name : '无'}</Table.Cell>
+                <Table.Cell>
+                  {token.name ? token.name : '无'}{' '}
+                  <Label basic size='tiny'>sk-{token.key_prefix}…</Label>
+                </Table.Cell>
                 <Table.Cell>{renderStatus(token.status)}</Table.Cell>
                 <Table.Cell>{renderQuota(token.used_quota)}</Table.Cell>
                 <Table.Cell>{token.unlimited_quota ? '无限制' : renderQuota(token.remain_quota, 2)}</Table.Cell>
@@ -401,6 +419,24 @@ const TokensTable = () => {
                         删除令牌 {token.name}
                       </Button>
                     </Popup>
+                    <Popup
+                      trigger={
+                        <Button size='small'>
+                          轮换
+                        </Button>
+                      }
+                      on='click'
+                      flowing
+                      hoverable
+                    >
+                      <Button
+                        onClick={() => {
+                          manageToken(token.id, 'rotate', idx);
+                        }}
+                      >
+                        生成新密钥，旧密钥在宽限期后失效
+                      </Button>
+                    </Popup>
                     <Button
                       size={'small'}
                       onClick={() => {
@@ -0,0 +1,115 @@
+import React, { useContext, useEffect, useState } from 'react';
+import { Button, Form, Grid, Header, Image, Message, Segment } from 'semantic-ui-react';
+import { useNavigate, useSearchParams } from 'react-router-dom';
+import { API, downloadTextAsFile, showError, showSuccess } from '../helpers';
+import { UserContext } from '../context/User';
+
+const TwoFactorLogin = () => {
+  const [searchParams] = useSearchParams();
+  const [userState, userDispatch] = useContext(UserContext);
+  const [code, setCode] = useState('');
+  const [setupInfo, setSetupInfo] = useState(null);
+  const [recoveryCodes, setRecoveryCodes] = useState(null);
+  const [loading, setLoading] = useState(false);
+  let navigate = useNavigate();
+  const setup = searchParams.get('setup') === 'true';
+
+  const loadSetupInfo = async () => {
+    const res = await API.get('/api/user/login/2fa/setup');
+    const { success, message, data } = res.data;
+    if (success) {
+      setSetupInfo(data);
+    } else {
+      showError(message);
+    }
+  };
+
+  useEffect(() => {
+    if (setup) {
+      loadSetupInfo().then();
+    }
+  }, []);
+
+  const submit = async () => {
+    if (!code) return;
+    setLoading(true);
+    const res = await API.post('/api/user/login/2fa', { code });
+    const { success, message, data, recovery_codes } = res.data;
+    setLoading(false);
+    if (success) {
+      userDispatch({ type: 'login', payload: data });
+      localStorage.setItem('user', JSON.stringify(data));
+      showSuccess('登录成功！');
+      if (recovery_codes) {
+        setRecoveryCodes(recovery_codes);
+      } else {
+        navigate('/token');
+      }
+    } else {
+      showError(message);
+    }
+  };
+
+  if (recoveryCodes) {
+    return (
+      <Grid textAlign='center' style={{ marginTop: '48px' }}>
+        <Grid.Column style={{ maxWidth: 450 }}>
+          <Segment>
+            <Header as='h3'>恢复码</Header>
+            <Message warning>
+              请妥善保存以下恢复码，每个恢复码只能使用一次，在无法使用验证器时可用于登录。恢复码只显示这一次。
+            </Message>
+            <pre>{recoveryCodes.join('\n')}</pre>
+            <Button onClick={() => downloadTextAsFile(recoveryCodes.join('\n'), 'recovery-codes.txt')}>
+              下载
+            </Button>
+            <Button color='green' onClick={() => navigate('/token')}>
+              我已保存
+            </Button>
+          </Segment>
+        </Grid.Column>
+      </Grid>
+    );
+  }
+
+  return (
+    <Grid textAlign='center' style={{ marginTop: '48px' }}>
+      <Grid.Column style={{ maxWidth: 450 }}>
+        <Header as='h2' textAlign='center'>
+          两步验证
+        </Header>
+        <Form size='large'>
+          <Segment>
+            {setup && (
+              <>
+                <Message>
+                  你所在的角色要求启用两步验证，请使用验证器应用扫描下方二维码，然后输入生成的验证码。
+                </Message>
+                {setupInfo && (
+                  <>
+                    <Image src={setupInfo.qr_code} centered size='medium' />
+                    <p>无法扫码时可手动输入密钥：<code>{setupInfo.secret}</code></p>
+                  </>
+                )}
+              </>
+            )}
+            <Form.Input
+              fluid
+              icon='lock'
+              iconPosition='left'
+              placeholder={setup ? '验证码' : '验证码或恢复码'}
+              name='code'
+              value={code}
+              onChange={(e) => setCode(e.target.value)}
+            />
+            <Button color='green' fluid size='large' onClick={submit} loading={loading}>
+              验证
+            </Button>
+          </Segment>
+        </Form>
+      </Grid.Column>
+    </Grid>
+  );
+};
+
+export default TwoFactorLogin;
@@ -0,0 +1,106 @@
+import React, { useEffect, useState } from 'react';
+import { Button, Form, Image, Message, Modal } from 'semantic-ui-react';
+import { API, downloadTextAsFile, showError, showSuccess } from '../helpers';
+
+const TwoFactorSetting = () => {
+  const [status, setStatus] = useState({ enabled: false, required: false });
+  const [setupInfo, setSetupInfo] = useState(null);
+  const [recoveryCodes, setRecoveryCodes] = useState(null);
+  const [action, setAction] = useState('');
+  const [code, setCode] = useState('');
+
+  const loadStatus = async () => {
+    const res = await API.get('/api/user/2fa');
+    const { success, message, data } = res.data;
+    if (success) {
+      setStatus(data);
+    } else {
+      showError(message);
+    }
+  };
+
+  useEffect(() => {
+    loadStatus().then();
+  }, []);
+
+  const startSetup = async () => {
+    const res = await API.post('/api/user/2fa/setup');
+    const { success, message, data } = res.data;
+    if (success) {
+      setSetupInfo(data);
+      setCode('');
+      setAction('enable');
+    } else {
+      showError(message);
+    }
+  };
+
+  const submit = async () => {
+    if (!code) return;
+    const res = await API.post(`/api/user/2fa/${action}`, { code });
+    const { success, message, data } = res.data;
+    if (success) {
+      if (action === 'disable') {
+        showSuccess('已关闭两步验证');
+      } else {
+        setRecoveryCodes(data);
+      }
+      setAction('');
+      setSetupInfo(null);
+      setCode('');
+      await loadStatus();
+    } else {
+      showError(message);
+    }
+  };
+
+  return (
+    <>
+      {status.required && !status.enabled && (
+        <Message warning>你所在的角色要求启用两步验证，下次登录时将要求完成绑定。</Message>
+      )}
+      {status.enabled ? (
+        <>
+          <Button onClick={() => setAction('recovery')}>重新生成恢复码</Button>
+          {!status.required && <Button onClick={() => setAction('disable')}>关闭两步验证</Button>}
+        </>
+      ) : (
+        <Button onClick={startSetup}>启用两步验证</Button>
+      )}
+      <Modal onClose={() => setAction('')} open={action !== ''} size='mini'>
+        <Modal.Header>两步验证</Modal.Header>
+        <Modal.Content>
+          {setupInfo && (
+            <>
+              <Message>请使用验证器应用扫描二维码，然后输入生成的验证码。</Message>
+              <Image src={setupInfo.qr_code} centered size='medium' />
+              <p>无法扫码时可手动输入密钥：<code>{setupInfo.secret}</code></p>
+            </>
+          )}
+          <Form size='large'>
+            <Form.Input
+              fluid
+              placeholder={action === 'enable' ? '验证码' : '验证码或恢复码'}
+              value={code}
+              onChange={(e) => setCode(e.target.value)}
+            />
+            <Button color='green' fluid size='large' onClick={submit}>
+              确认
+            </Button>
+          </Form>
+        </Modal.Content>
+      </Modal>
+      <Modal onClose={() => setRecoveryCodes(null)} open={recoveryCodes !== null} size='mini'>
+        <Modal.Header>恢复码</Modal.Header>
+        <Modal.Content>
+          <Message warning>请妥善保存以下恢复码，每个恢复码只能使用一次。恢复码只显示这一次。</Message>
+          <pre>{recoveryCodes && recoveryCodes.join('\n')}</pre>
+          <Button onClick={() => downloadTextAsFile(recoveryCodes.join('\n'), 'recovery-codes.txt')}>下载</Button>
+          <Button color='green' onClick={() => setRecoveryCodes(null)}>我已保存</Button>
+        </Modal.Content>
+      </Modal>
+    </>
+  );
+};
+
+export default TwoFactorSetting;
@@ -91,6 +91,16 @@ const UsersTable = () => {
     })();
   };
 
+  const revokeUserSessions = async (id) => {
+    const res = await API.delete(`/api/user/${id}/session`);
+    const { success, message, data } = res.data;
+    if (success) {
+      showSuccess(`已注销该用户的 ${data} 个会话`);
+    } else {
+      showError(message);
+    }
+  };
+
   const renderStatus = (status) => {
     switch (status) {
       case 1:
@@ -309,6 +319,21 @@ const UsersTable = () => {
                       >
                         {user.status === 1 ? '禁用' : '启用'}
                       </Button>
+                      <Popup
+                        trigger={<Button size='small'>强制下线</Button>}
+                        on='click'
+                        flowing
+                        hoverable
+                      >
+                        <Button
+                          negative
+                          onClick={() => {
+                            revokeUserSessions(user.id).then();
+                          }}
+                        >
+                          注销 {user.username} 的全部登录会话
+                        </Button>
+                      </Popup>
                       <Button
                         size={'small'}
                         as={Link}
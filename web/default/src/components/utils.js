@@ -11,6 +11,12 @@ export async function getOAuthState() {
   }
 }
 
+export async function onOidcClicked() {
+  const state = await getOAuthState();
+  if (!state) return;
+  window.location.href = `/api/oauth/oidc?state=${state}`;
+}
+
 export async function onGitHubOAuthClicked(github_client_id) {
   const state = await getOAuthState();
   if (!state) return;
@@ -8,7 +8,13 @@ export const reducer = (state, action) => {
     case 'logout':
       return {
         ...state,
-        user: undefined
+        user: undefined,
+        menus: undefined
+      };
+    case 'menus':
+      return {
+        ...state,
+        menus: action.payload
       };
 
     default:
@@ -17,5 +23,6 @@ export const reducer = (state, action) => {
 };
 
 export const initialState = {
-  user: undefined
+  user: undefined,
+  menus: undefined
 };
\ No newline at end of file
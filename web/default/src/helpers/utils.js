@@ -8,18 +8,40 @@ const HTMLToastContent = ({ htmlContent }) => {
 };
 export default HTMLToastContent;
 
-export function isAdmin() {
-  let user = localStorage.getItem('user');
-  if (!user) return false;
-  user = JSON.parse(user);
-  return user.role >= 10;
-}
-
-export function isRoot() {
-  let user = localStorage.getItem('user');
-  if (!user) return false;
-  user = JSON.parse(user);
-  return user.role >= 100;
+// loadMenus fetches the pages and the permissions granted to the logged in user
+export async function loadMenus() {
+  const res = await API.get('/api/menus/');
+  const { success, menus, permissions } = res.data;
+  if (!success) return null;
+  const data = { pages: [...menus.Name, ...menus.Settings], permissions };
+  localStorage.setItem('menus', JSON.stringify(data));
+  return data;
+}
+
+function getMenus() {
+  let menus = localStorage.getItem('menus');
+  if (!menus) return { pages: [], permissions: [] };
+  return JSON.parse(menus);
+}
+
+export function hasPermission(permission) {
+  return getMenus().permissions.includes(permission);
+}
+
+export function hasPage(page) {
+  return getMenus().pages.includes(page);
+}
+
+// loadManagedConfig fetches what the config file owns, it is read-only in the settings
+export async function loadManagedConfig() {
+  const res = await API.get('/api/option/managed');
+  const { success, data } = res.data;
+  if (!success || !data.enabled) return { options: [], entries: [] };
+  const entries = [];
+  [['GroupRatio', data.group_ratios], ['ModelRatio', data.model_ratios], ['ModelPrice', data.model_prices]].forEach(
+    ([key, names]) => (names || []).forEach((name) => entries.push(`${key}.${name}`))
+  );
+  return { options: data.options || [], entries };
 }
 
 export function getSystemName() {
@@ -0,0 +1,14 @@
+import React from 'react';
+import { Header, Segment } from 'semantic-ui-react';
+import AuditLogsTable from '../../components/AuditLogsTable';
+
+const Audit = () => (
+  <>
+    <Segment>
+      <Header as='h3'>审计日志</Header>
+      <AuditLogsTable />
+    </Segment>
+  </>
+);
+
+export default Audit;
@@ -209,6 +209,11 @@ const EditChannel = () => {
     <>
       <Segment loading={loading}>
         <Header as='h3'>{isEdit ? '更新渠道信息' : '创建新的渠道'}</Header>
+        {inputs.managed && (
+          <Message warning visible>
+            本渠道由配置文件管理，只读，请通过修改配置文件更改。
+          </Message>
+        )}
         <Form autoComplete='new-password'>
           <Form.Field>
             <Form.Select
@@ -454,7 +459,7 @@ const EditChannel = () => {
             )
           }
           <Button onClick={handleCancel}>取消</Button>
-          <Button type={isEdit ? 'button' : 'submit'} positive onClick={submit}>提交</Button>
+          <Button type={isEdit ? 'button' : 'submit'} positive disabled={inputs.managed} onClick={submit}>提交</Button>
         </Form>
       </Segment>
     </>
@@ -1,12 +1,15 @@
-import React from 'react';
+import React, { useContext } from 'react';
 import { Segment, Tab } from 'semantic-ui-react';
 import SystemSetting from '../../components/SystemSetting';
-import { isRoot } from '../../helpers';
+import { hasPage } from '../../helpers';
+import { UserContext } from '../../context/User';
 import OtherSetting from '../../components/OtherSetting';
 import PersonalSetting from '../../components/PersonalSetting';
 import OperationSetting from '../../components/OperationSetting';
 
 const Setting = () => {
+  // re-rendered once the pages of the user are loaded
+  useContext(UserContext);
   let panes = [
     {
       menuItem: '个人设置',
@@ -18,7 +21,7 @@ const Setting = () => {
     }
   ];
 
-  if (isRoot()) {
+  if (hasPage('operateSettings')) {
     panes.push({
       menuItem: '运营设置',
       render: () => (
@@ -27,6 +30,8 @@ const Setting = () => {
         </Tab.Pane>
       )
     });
+  }
+  if (hasPage('systemSettings')) {
     panes.push({
       menuItem: '系统设置',
       render: () => (
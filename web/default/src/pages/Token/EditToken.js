@@ -1,7 +1,7 @@
 import React, { useEffect, useState } from 'react';
 import { Button, Form, Header, Message, Segment } from 'semantic-ui-react';
 import { useParams, useNavigate } from 'react-router-dom';
-import { API, showError, showSuccess, timestamp2string } from '../../helpers';
+import { API, copy, showError, showSuccess, showWarning, timestamp2string } from '../../helpers';
 import { renderQuota, renderQuotaWithPrompt } from '../../helpers/render';
 
 const EditToken = () => {
@@ -13,10 +13,12 @@ const EditToken = () => {
     name: '',
     remain_quota: isEdit ? 0 : 500000,
     expired_time: -1,
-    unlimited_quota: false
+    unlimited_quota: false,
+    organization_id: 0
   };
   const [inputs, setInputs] = useState(originInputs);
-  const { name, remain_quota, expired_time, unlimited_quota } = inputs;
+  const { name, remain_quota, expired_time, unlimited_quota, organization_id } = inputs;
+  const [organizationOptions, setOrganizationOptions] = useState([]);
   const navigate = useNavigate();
   const handleInputChange = (e, { name, value }) => {
     setInputs((inputs) => ({ ...inputs, [name]: value }));
@@ -56,9 +58,25 @@ const EditToken = () => {
     }
     setLoading(false);
   };
+  const loadOrganizations = async () => {
+    let res = await API.get(`/api/organization/`);
+    const { success, data } = res.data;
+    if (success && data.length > 0) {
+      setOrganizationOptions([
+        { key: 0, text: '个人额度', value: 0 },
+        ...data.map((organization) => ({
+          key: organization.id,
+          text: organization.name,
+          value: organization.id
+        }))
+      ]);
+    }
+  };
   useEffect(() => {
     if (isEdit) {
       loadToken().then();
+    } else {
+      loadOrganizations().then();
     }
   }, []);
 
@@ -85,7 +103,13 @@ const EditToken = () => {
       if (isEdit) {
         showSuccess('令牌更新成功！');
       } else {
-        showSuccess('令牌创建成功，请在列表页面点击复制获取令牌！');
+        // the key is only returned once, at creation
+        const key = `sk-${res.data.data.key}`;
+        if (await copy(key)) {
+          showSuccess(`令牌创建成功，密钥只显示这一次，已复制到剪贴板：${key}`);
+        } else {
+          showWarning(`令牌创建成功，密钥只显示这一次，请妥善保存：${key}`);
+        }
         setInputs(originInputs);
       }
     } else {
@@ -109,6 +133,18 @@ const EditToken = () => {
               required={!isEdit}
             />
           </Form.Field>
+          {!isEdit && organizationOptions.length > 0 && (
+            <Form.Field>
+              <Form.Dropdown
+                label='计费组织'
+                name='organization_id'
+                selection
+                options={organizationOptions}
+                onChange={handleInputChange}
+                value={organization_id}
+              />
+            </Form.Field>
+          )}
           <Form.Field>
             <Form.Input
               label='过期时间'